@@ -0,0 +1,186 @@
+package tcplisten
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Metric names reported by AcceptAheadListener.
+const (
+	// MetricAcceptAheadDepth is the gauge of the number of pre-accepted
+	// connections waiting for Accept.
+	MetricAcceptAheadDepth = "tcplisten_accept_ahead_depth"
+
+	// MetricAcceptAheadExpired is the counter of pre-accepted connections
+	// closed after waiting for Accept longer than the maximum wait.
+	MetricAcceptAheadExpired = "tcplisten_accept_ahead_expired"
+)
+
+// DefaultAcceptAheadMaxWait is the default maximum time a pre-accepted
+// connection may wait for Accept.
+const DefaultAcceptAheadMaxWait = time.Second
+
+// AcceptAheadOption changes the behavior of AcceptAheadListener.
+type AcceptAheadOption func(*acceptAheadListener)
+
+// AcceptAheadMaxWait sets the maximum time a pre-accepted connection
+// may wait for Accept. Connections waiting longer are closed instead
+// of being returned, since their clients have likely given up.
+// DefaultAcceptAheadMaxWait is used by default.
+func AcceptAheadMaxWait(d time.Duration) AcceptAheadOption {
+	return func(ln *acceptAheadListener) {
+		ln.maxWait = d
+	}
+}
+
+// AcceptAheadMetrics reports the number of pre-accepted connections
+// to sink as MetricAcceptAheadDepth and the number of expired ones
+// as MetricAcceptAheadExpired.
+func AcceptAheadMetrics(sink MetricsSink) AcceptAheadOption {
+	return func(ln *acceptAheadListener) {
+		ln.sink = sink
+	}
+}
+
+// AcceptAheadListener returns a listener accepting connections from ln
+// in a goroutine ahead of Accept calls, keeping up to depth pre-accepted
+// connections, so Accept returns instantly during bursts and e.g.
+// TLS handshakes start earlier.
+//
+// Temporary accept errors are retried with the same backoff as in
+// AcceptLoop. The first non-temporary error is returned from all
+// the subsequent Accept calls. The pre-accepted connections, which
+// haven't been returned from Accept, are closed on Close.
+func AcceptAheadListener(ln net.Listener, depth int, opts ...AcceptAheadOption) net.Listener {
+	if depth < 1 {
+		depth = 1
+	}
+	al := &acceptAheadListener{
+		Listener: ln,
+		maxWait:  DefaultAcceptAheadMaxWait,
+		conns:    make(chan aheadConn, depth),
+		errs:     make(chan error, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(al)
+	}
+	go al.acceptor()
+	return al
+}
+
+type aheadConn struct {
+	net.Conn
+	accepted time.Time
+}
+
+type acceptAheadListener struct {
+	net.Listener
+	maxWait time.Duration
+	sink    MetricsSink
+
+	conns chan aheadConn
+	errs  chan error
+
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func (ln *acceptAheadListener) acceptor() {
+	defer close(ln.stopped)
+	var delay time.Duration
+	for {
+		c, err := ln.Listener.Accept()
+		if err != nil {
+			select {
+			case <-ln.done:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() && !isClosedError(err) {
+				delay = nextAcceptBackoff(delay)
+				t := time.NewTimer(delay)
+				select {
+				case <-t.C:
+				case <-ln.done:
+					t.Stop()
+					return
+				}
+				continue
+			}
+			ln.errs <- err
+			return
+		}
+		delay = 0
+		select {
+		case ln.conns <- aheadConn{Conn: c, accepted: time.Now()}:
+			ln.reportDepth()
+		case <-ln.done:
+			c.Close()
+			return
+		}
+	}
+}
+
+func (ln *acceptAheadListener) Accept() (net.Conn, error) {
+	for {
+		var ac aheadConn
+		select {
+		case ac = <-ln.conns:
+		case <-ln.done:
+			return nil, ErrListenerClosed
+		case err := <-ln.errs:
+			// Keep the error for the subsequent calls, but deliver
+			// the connections accepted before the error first.
+			ln.errs <- err
+			select {
+			case ac = <-ln.conns:
+			default:
+				return nil, err
+			}
+		}
+		ln.reportDepth()
+		if ln.maxWait > 0 && time.Since(ac.accepted) > ln.maxWait {
+			ac.Close()
+			if ln.sink != nil {
+				ln.sink.Counter(MetricAcceptAheadExpired, 1)
+			}
+			continue
+		}
+		return ac.Conn, nil
+	}
+}
+
+func (ln *acceptAheadListener) Close() error {
+	err := ErrListenerClosed
+	ln.closeOnce.Do(func() {
+		close(ln.done)
+		err = ln.Listener.Close()
+		<-ln.stopped
+	drain:
+		for {
+			select {
+			case ac := <-ln.conns:
+				ac.Close()
+			default:
+				break drain
+			}
+		}
+		ln.reportDepth()
+	})
+	return err
+}
+
+func (ln *acceptAheadListener) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(ln.Listener)
+}
+
+func (ln *acceptAheadListener) reportDepth() {
+	if ln.sink != nil {
+		ln.sink.Gauge(MetricAcceptAheadDepth, float64(len(ln.conns)))
+	}
+}
@@ -0,0 +1,84 @@
+package tcplisten
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAcceptAheadListener(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	sink := newTestSink()
+	al := AcceptAheadListener(ln, 2, AcceptAheadMaxWait(100*time.Millisecond), AcceptAheadMetrics(sink))
+	defer al.Close()
+
+	dial := func() net.Conn {
+		c, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		return c
+	}
+	waitDepth := func(depth float64) {
+		deadline := time.Now().Add(time.Second)
+		for {
+			sink.mu.Lock()
+			d := sink.gauges[MetricAcceptAheadDepth]
+			sink.mu.Unlock()
+			if d == depth {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("unexpected depth %v. Expecting %v", d, depth)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The connection waiting too long is closed instead of being returned.
+	expired := dial()
+	defer expired.Close()
+	waitDepth(1)
+	time.Sleep(150 * time.Millisecond)
+	fresh := dial()
+	defer fresh.Close()
+	waitDepth(2)
+
+	c, err := al.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	defer c.Close()
+	if c.RemoteAddr().String() != fresh.LocalAddr().String() {
+		t.Fatalf("unexpected connection from %s. Expecting %s", c.RemoteAddr(), fresh.LocalAddr())
+	}
+	expired.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = expired.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("unexpected error %v when reading from expired connection. Expecting EOF", err)
+	}
+	sink.mu.Lock()
+	n := sink.counters[MetricAcceptAheadExpired]
+	sink.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("unexpected number of expired connections %d. Expecting 1", n)
+	}
+
+	// Pre-accepted connections are closed on Close.
+	pending := dial()
+	defer pending.Close()
+	waitDepth(1)
+	if err = al.Close(); err != nil {
+		t.Fatalf("cannot close listener: %s", err)
+	}
+	pending.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = pending.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("unexpected error %v when reading from pending connection. Expecting EOF", err)
+	}
+	if _, err = al.Accept(); err != ErrListenerClosed {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrListenerClosed)
+	}
+}
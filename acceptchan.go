@@ -0,0 +1,81 @@
+package tcplisten
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// AcceptChan accepts connections from ln in a goroutine and delivers them
+// on the returned channel with the given buffer size, so multiple listeners
+// may be multiplexed with select.
+//
+// Temporary accept errors are retried with the same backoff as in
+// AcceptLoop. The first non-temporary error is delivered on the error
+// channel, which is never closed. The connection channel is closed
+// when the goroutine stops.
+//
+// The returned func stops the goroutine: it closes ln, waits for
+// the goroutine to exit and closes the connections left in the channel
+// buffer. It may be called multiple times.
+func AcceptChan(ln net.Listener, bufSize int) (<-chan net.Conn, <-chan error, func()) {
+	if bufSize < 0 {
+		bufSize = 0
+	}
+	conns := make(chan net.Conn, bufSize)
+	errs := make(chan error, 1)
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer close(conns)
+		var delay time.Duration
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				select {
+				case <-stopCh:
+					return
+				default:
+				}
+				if isClosedError(err) {
+					return
+				}
+				if ne, ok := err.(net.Error); ok && ne.Temporary() {
+					delay = nextAcceptBackoff(delay)
+					t := time.NewTimer(delay)
+					select {
+					case <-t.C:
+					case <-stopCh:
+						t.Stop()
+						return
+					}
+					continue
+				}
+				errs <- err
+				return
+			}
+			delay = 0
+			select {
+			case conns <- c:
+			case <-stopCh:
+				c.Close()
+				return
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() {
+			close(stopCh)
+			ln.Close()
+		})
+		<-done
+		for c := range conns {
+			c.Close()
+		}
+	}
+	return conns, errs, stop
+}
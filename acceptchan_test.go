@@ -0,0 +1,79 @@
+package tcplisten
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAcceptChan(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	conns, errs, stop := AcceptChan(ln, 4)
+	defer stop()
+
+	for i := 0; i < 3; i++ {
+		c, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		defer c.Close()
+		select {
+		case sc := <-conns:
+			sc.Close()
+		case err := <-errs:
+			t.Fatalf("unexpected error: %s", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("connection #%d hasn't been delivered", i)
+		}
+	}
+
+	// The connection left in the buffer is closed by stop.
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	if _, ok := <-conns; ok {
+		t.Fatalf("the connection channel hasn't been closed")
+	}
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err = c.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("the buffered connection hasn't been closed")
+	}
+	stop()
+}
+
+type failingListener struct {
+	net.Listener
+}
+
+func (ln failingListener) Accept() (net.Conn, error) {
+	return nil, errors.New("accept failed")
+}
+
+func TestAcceptChanError(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	conns, errs, stop := AcceptChan(failingListener{ln}, 0)
+	defer stop()
+
+	select {
+	case err := <-errs:
+		if err.Error() != "accept failed" {
+			t.Fatalf("unexpected error %q", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the error hasn't been delivered")
+	}
+	if _, ok := <-conns; ok {
+		t.Fatalf("the connection channel hasn't been closed")
+	}
+}
@@ -0,0 +1,165 @@
+package tcplisten
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAcceptGracePeriod is the time AcceptLoop waits for the running
+// handlers to finish after it has stopped accepting connections.
+const DefaultAcceptGracePeriod = 5 * time.Second
+
+// Backoff limits for temporary accept errors, e.g. EMFILE.
+const (
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+)
+
+// AcceptLoop is AcceptLoopGrace with DefaultAcceptGracePeriod.
+func AcceptLoop(ctx context.Context, ln net.Listener, workers int, handle func(net.Conn)) error {
+	return AcceptLoopGrace(ctx, ln, workers, DefaultAcceptGracePeriod, handle)
+}
+
+// AcceptLoopGrace accepts connections from ln in the given number
+// of goroutines and calls handle for every connection in a new goroutine.
+//
+// Multiple goroutines blocked in Accept on the same listener reduce
+// accept latency spikes, since a connection may be accepted while
+// the other goroutines are busy starting handlers.
+//
+// Temporary accept errors, e.g. EMFILE, are retried with exponential
+// backoff from 5ms to 1s. Panics in handle are logged with the standard
+// logger and the connection is closed.
+//
+// AcceptLoopGrace closes ln and stops when ctx is done, when ln is closed
+// or when Accept fails with a non-temporary error, which is returned then.
+// It waits for the running handlers up to grace before returning,
+// and returns an error if they haven't finished in time.
+func AcceptLoopGrace(ctx context.Context, ln net.Listener, workers int, grace time.Duration, handle func(net.Conn)) error {
+	if workers <= 0 {
+		workers = 1
+	}
+	var (
+		acceptors sync.WaitGroup
+		handlers  sync.WaitGroup
+		stopOnce  sync.Once
+		errOnce   sync.Once
+		loopErr   error
+	)
+	stopCh := make(chan struct{})
+	stop := func() {
+		stopOnce.Do(func() {
+			close(stopCh)
+			ln.Close()
+		})
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			stop()
+		case <-stopCh:
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		acceptors.Add(1)
+		go func() {
+			defer acceptors.Done()
+			if err := acceptConns(ln, stopCh, &handlers, handle); err != nil {
+				errOnce.Do(func() {
+					loopErr = err
+				})
+			}
+			stop()
+		}()
+	}
+	acceptors.Wait()
+
+	if grace <= 0 {
+		return loopErr
+	}
+	done := make(chan struct{})
+	go func() {
+		handlers.Wait()
+		close(done)
+	}()
+	t := time.NewTimer(grace)
+	defer t.Stop()
+	select {
+	case <-done:
+		return loopErr
+	case <-t.C:
+		if loopErr != nil {
+			return loopErr
+		}
+		return fmt.Errorf("cannot stop accept loop on %s: handlers are still running after %s", ln.Addr(), grace)
+	}
+}
+
+// acceptConns accepts connections until stopCh is closed or Accept
+// fails with a non-temporary error.
+func acceptConns(ln net.Listener, stopCh <-chan struct{}, handlers *sync.WaitGroup, handle func(net.Conn)) error {
+	var delay time.Duration
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-stopCh:
+				return nil
+			default:
+			}
+			if isClosedError(err) {
+				return nil
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				delay = nextAcceptBackoff(delay)
+				t := time.NewTimer(delay)
+				select {
+				case <-t.C:
+				case <-stopCh:
+					t.Stop()
+					return nil
+				}
+				continue
+			}
+			return err
+		}
+		delay = 0
+		handlers.Add(1)
+		go serveConn(c, handlers, handle)
+	}
+}
+
+// nextAcceptBackoff returns the delay before retrying Accept
+// after a temporary error.
+func nextAcceptBackoff(delay time.Duration) time.Duration {
+	if delay *= 2; delay == 0 {
+		delay = minAcceptBackoff
+	}
+	if delay > maxAcceptBackoff {
+		delay = maxAcceptBackoff
+	}
+	return delay
+}
+
+func serveConn(c net.Conn, handlers *sync.WaitGroup, handle func(net.Conn)) {
+	defer handlers.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			c.Close()
+			stdLogger{}.Printf("tcplisten: panic serving %s: %v\n%s", c.RemoteAddr(), r, debug.Stack())
+		}
+	}()
+	handle(c)
+}
+
+// isClosedError reports whether err is returned from Accept
+// on a closed listener.
+func isClosedError(err error) bool {
+	return err == ErrListenerClosed || strings.Contains(err.Error(), "use of closed network connection")
+}
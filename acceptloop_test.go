@@ -0,0 +1,102 @@
+package tcplisten
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAcceptLoop(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- AcceptLoop(ctx, ln, 4, func(c net.Conn) {
+			defer c.Close()
+			var b [1]byte
+			if _, err := io.ReadFull(c, b[:]); err != nil {
+				return
+			}
+			if b[0] == 'p' {
+				panic("test panic")
+			}
+			c.Write(b[:])
+		})
+	}()
+
+	for i := 0; i < 10; i++ {
+		c, err := net.Dial("tcp4", addr)
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		c.Write([]byte("x"))
+		resp, err := ioutil.ReadAll(c)
+		c.Close()
+		if err != nil || string(resp) != "x" {
+			t.Fatalf("unexpected response %q, %v. Expecting %q", resp, err, "x")
+		}
+	}
+
+	// The panicking handler must not break the loop.
+	c, err := net.Dial("tcp4", addr)
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	c.Write([]byte("p"))
+	if resp, _ := ioutil.ReadAll(c); len(resp) != 0 {
+		t.Fatalf("unexpected response %q from the panicking handler", resp)
+	}
+	c.Close()
+
+	cancel()
+	select {
+	case err = <-errCh:
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("accept loop hasn't stopped")
+	}
+	if _, err = net.Dial("tcp4", addr); err == nil {
+		t.Fatalf("expecting the listener to be closed")
+	}
+}
+
+func TestAcceptLoopGrace(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- AcceptLoopGrace(ctx, ln, 1, 50*time.Millisecond, func(c net.Conn) {
+			defer c.Close()
+			close(started)
+			<-release
+		})
+	}()
+
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	<-started
+	cancel()
+	if err = <-errCh; err == nil {
+		t.Fatalf("expecting error for the handler outliving the grace period")
+	}
+}
@@ -0,0 +1,95 @@
+package tcplisten
+
+import (
+	"net"
+	"syscall"
+	"time"
+)
+
+// MetricAcceptQueueWait is the histogram of the time in seconds
+// the connections accepted from the listener returned by
+// MeasureAcceptQueueWait have spent in the accept queue.
+const MetricAcceptQueueWait = "tcplisten_accept_queue_wait_seconds"
+
+// QueueWaitMethod is the method AcceptQueueWait has used for estimating
+// the time a connection has spent in the accept queue.
+type QueueWaitMethod int
+
+const (
+	// QueueWaitRxTimestamp is the time since the kernel received the first
+	// queued data, obtained from its software RX timestamp. It has
+	// nanosecond resolution.
+	//
+	// It requires the listener to have Config.HardwareTimestamping
+	// and the data to arrive before Accept. The wait is underestimated
+	// if the client sent the data some time after the handshake,
+	// and is exact with Config.DeferAccept, since the connection is queued
+	// when its data arrives then.
+	QueueWaitRxTimestamp QueueWaitMethod = iota + 1
+
+	// QueueWaitTCPInfo is the time since the last segment received
+	// on the connection, obtained from tcpi_last_ack_recv and
+	// tcpi_last_data_recv of TCP_INFO. It has millisecond resolution,
+	// and is further rounded to the kernel tick, i.e. 1-10ms.
+	//
+	// It is the time since the handshake for connections without data,
+	// and is underestimated if the client sent segments after
+	// the handshake.
+	QueueWaitTCPInfo
+)
+
+// String returns the name of the method.
+func (m QueueWaitMethod) String() string {
+	switch m {
+	case QueueWaitRxTimestamp:
+		return "rx-timestamp"
+	case QueueWaitTCPInfo:
+		return "tcp-info"
+	default:
+		return "unknown"
+	}
+}
+
+// MeasureAcceptQueueWait returns a listener estimating the time every
+// accepted connection has spent in the accept queue with AcceptQueueWait.
+//
+// The estimate is passed to onAccept unless it is nil, and is reported
+// to sink as MetricAcceptQueueWait if sink implements HistogramSink.
+// Connections for which the wait cannot be estimated, e.g. on platforms
+// other than Linux, are accepted without reporting anything.
+func MeasureAcceptQueueWait(ln net.Listener, sink MetricsSink, onAccept func(c net.Conn, wait time.Duration, method QueueWaitMethod)) net.Listener {
+	hs, _ := sink.(HistogramSink)
+	return &queueWaitListener{
+		Listener: ln,
+		sink:     hs,
+		onAccept: onAccept,
+	}
+}
+
+type queueWaitListener struct {
+	net.Listener
+	sink     HistogramSink
+	onAccept func(c net.Conn, wait time.Duration, method QueueWaitMethod)
+}
+
+func (ln *queueWaitListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	wait, method, err := AcceptQueueWait(c)
+	if err != nil {
+		return c, nil
+	}
+	if ln.sink != nil {
+		ln.sink.Observe(MetricAcceptQueueWait, wait.Seconds())
+	}
+	if ln.onAccept != nil {
+		ln.onAccept(c, wait, method)
+	}
+	return c, nil
+}
+
+func (ln *queueWaitListener) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(ln.Listener)
+}
@@ -0,0 +1,66 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// AcceptQueueWait estimates the time the just accepted connection c
+// has spent in the accept queue, so it must be called right after Accept.
+//
+// QueueWaitRxTimestamp is used if c has received data with timestamps.
+// QueueWaitTCPInfo is used otherwise. See their docs for the accuracy
+// of the estimates. In both cases the estimate doesn't include the time
+// the connection has spent in the SYN queue.
+func AcceptQueueWait(c net.Conn) (time.Duration, QueueWaitMethod, error) {
+	var (
+		wait   time.Duration
+		method QueueWaitMethod
+	)
+	err := withFd(c, func(fd uintptr) error {
+		now := time.Now()
+		if ts := peekRxTimestamp(int(fd)); !ts.IsZero() {
+			wait, method = now.Sub(ts), QueueWaitRxTimestamp
+			if wait < 0 {
+				wait = 0
+			}
+			return nil
+		}
+		var ti syscall.TCPInfo
+		if err := getTCPInfo(int(fd), &ti); err != nil {
+			return fmt.Errorf("cannot obtain TCP_INFO: %s", err)
+		}
+		// Both values are the time since the handshake unless the client
+		// has sent something after it, so the larger one is closer.
+		ms := ti.Last_ack_recv
+		if ti.Last_data_recv > ms {
+			ms = ti.Last_data_recv
+		}
+		wait, method = time.Duration(ms)*time.Millisecond, QueueWaitTCPInfo
+		return nil
+	})
+	return wait, method, err
+}
+
+// peekRxTimestamp returns the software RX timestamp of the first
+// queued data without consuming it. It returns zero time if no data
+// is queued or it has no timestamp.
+func peekRxTimestamp(fd int) time.Time {
+	var (
+		b   [1]byte
+		oob [128]byte
+	)
+	n, oobn, _, _, err := syscall.Recvmsg(fd, b[:], oob[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+	if err != nil || n == 0 {
+		return time.Time{}
+	}
+	ts, err := parseTimestamps(oob[:oobn])
+	if err != nil {
+		return time.Time{}
+	}
+	return ts.Software
+}
@@ -0,0 +1,85 @@
+package tcplisten
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAcceptQueueWaitTCPInfo(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	var (
+		wait   time.Duration
+		method QueueWaitMethod
+	)
+	sink := newTestSink()
+	mln := MeasureAcceptQueueWait(ln, sink, func(c net.Conn, d time.Duration, m QueueWaitMethod) {
+		wait, method = d, m
+	})
+
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	time.Sleep(100 * time.Millisecond)
+	sc, err := mln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	sc.Close()
+
+	if method != QueueWaitTCPInfo {
+		t.Fatalf("unexpected method %s. Expecting %s", method, QueueWaitTCPInfo)
+	}
+	if wait < 80*time.Millisecond || wait > 5*time.Second {
+		t.Fatalf("unexpected wait %s. Expecting around 100ms", wait)
+	}
+	if h := sink.histograms[MetricAcceptQueueWait]; len(h) != 1 || h[0] != wait.Seconds() {
+		t.Fatalf("unexpected %s histogram %v. Expecting [%v]", MetricAcceptQueueWait, h, wait.Seconds())
+	}
+}
+
+func TestAcceptQueueWaitRxTimestamp(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{HardwareTimestamping: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	// The kernel enables RX timestamps asynchronously after the first
+	// socket asks for them, so the first segments may come without them.
+	for i := 0; i < 20; i++ {
+		c, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		if _, err = c.Write([]byte("ping")); err != nil {
+			t.Fatalf("cannot write: %s", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		sc, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept: %s", err)
+		}
+		wait, method, err := AcceptQueueWait(sc)
+		sc.Close()
+		c.Close()
+		if err != nil {
+			t.Fatalf("cannot estimate accept queue wait: %s", err)
+		}
+		if method != QueueWaitRxTimestamp {
+			continue
+		}
+		if wait < 40*time.Millisecond || wait > 5*time.Second {
+			t.Fatalf("unexpected wait %s. Expecting around 50ms", wait)
+		}
+		return
+	}
+	t.Fatalf("%s hasn't been used", QueueWaitRxTimestamp)
+}
@@ -0,0 +1,19 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+	"time"
+)
+
+// AcceptQueueWait estimates the time the just accepted connection c
+// has spent in the accept queue.
+//
+// It is supported only on Linux.
+func AcceptQueueWait(c net.Conn) (time.Duration, QueueWaitMethod, error) {
+	return 0, 0, &UnsupportedError{
+		Op:     "AcceptQueueWait",
+		Reason: "the time of the handshake is exposed only by TCP_INFO on Linux",
+	}
+}
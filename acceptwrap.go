@@ -0,0 +1,253 @@
+package tcplisten
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// configListener alters the accepted connections according to Config,
+// i.e. UnmapV4, AcceptReadTimeout and KeepAliveConfig, and according to
+// WithConnConfig, CountFastOpen and TrackConns.
+//
+// All the alterations are done by a single wrapper, so a single struct
+// is allocated per accepted connection regardless of the number
+// of enabled options.
+type configListener struct {
+	net.Listener
+	unmapV4     bool
+	readTimeout time.Duration
+	keepAlive   *KeepAliveConfig
+	connConfig  *ConnConfig
+	sink        MetricsSink
+	tracked     *TrackedListener
+}
+
+// composeListener returns a configListener for adding another alteration
+// to the connections accepted from ln.
+//
+// If ln is a configListener, its copy is returned, so the connections
+// aren't wrapped once per alteration and ln itself stays unchanged.
+func composeListener(ln net.Listener) *configListener {
+	if cl, ok := ln.(*configListener); ok {
+		cp := *cl
+		return &cp
+	}
+	return &configListener{Listener: ln}
+}
+
+func (ln *configListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if ln.keepAlive != nil {
+		// The net package enables keep-alive with its own defaults
+		// on every accepted connection, so the options set on
+		// the listening socket would be overridden.
+		if err = withFd(c, func(fd uintptr) error {
+			return setKeepAlive(fd, ln.keepAlive, nil)
+		}); err != nil {
+			c.Close()
+			return nil, &connConfigError{err: err}
+		}
+	}
+	if ln.connConfig != nil {
+		if err = ln.connConfig.Apply(c); err != nil {
+			c.Close()
+			return nil, &connConfigError{err: err}
+		}
+	}
+	if ln.sink != nil {
+		countFastOpen(c, ln.sink)
+	}
+	// Only check whether the address is mapped here. The unmapped
+	// address is built by RemoteAddr, so connections whose address
+	// isn't needed don't pay for it.
+	unmapped := ln.unmapV4 && isMappedV4(c.RemoteAddr())
+	if ln.readTimeout > 0 {
+		if err = c.SetReadDeadline(time.Now().Add(ln.readTimeout)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	} else if !unmapped && ln.tracked == nil {
+		return c, nil
+	}
+
+	// The wrapper keeps the half-close methods of TCP and unix
+	// connections, so it is still allocated once per connection.
+	var cc *configConn
+	var wc net.Conn
+	if _, ok := c.(halfCloser); ok {
+		hc := &halfCloseConfigConn{}
+		cc, wc = &hc.configConn, hc
+	} else {
+		cc = &configConn{}
+		wc = cc
+	}
+	cc.Conn = c
+	cc.unmapped = unmapped
+	cc.tracked = ln.tracked
+	if ln.readTimeout <= 0 {
+		cc.deadlineDone = 1
+	}
+	if ln.tracked != nil {
+		ln.tracked.add(cc, wc)
+	}
+	return wc, nil
+}
+
+func isMappedV4(addr net.Addr) bool {
+	a, ok := addr.(*net.TCPAddr)
+	return ok && len(a.IP) == net.IPv6len && a.IP.To4() != nil
+}
+
+func (ln *configListener) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(ln.Listener)
+}
+
+type configConn struct {
+	net.Conn
+
+	// deadlineDone is set to 1 once the initial read deadline is cleared
+	// or replaced by the caller, or if there is no initial deadline.
+	deadlineDone int32
+
+	// unmapped is set if RemoteAddr must return the plain IPv4 address
+	// instead of the IPv4-mapped IPv6 one.
+	unmapped bool
+
+	tracked   *TrackedListener
+	closeOnce sync.Once
+}
+
+func (c *configConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && atomic.LoadInt32(&c.deadlineDone) == 0 && atomic.CompareAndSwapInt32(&c.deadlineDone, 0, 1) {
+		c.Conn.SetReadDeadline(time.Time{})
+	}
+	return n, err
+}
+
+func (c *configConn) SetDeadline(t time.Time) error {
+	atomic.StoreInt32(&c.deadlineDone, 1)
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *configConn) SetReadDeadline(t time.Time) error {
+	atomic.StoreInt32(&c.deadlineDone, 1)
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *configConn) RemoteAddr() net.Addr {
+	addr := c.Conn.RemoteAddr()
+	if !c.unmapped {
+		return addr
+	}
+	a := addr.(*net.TCPAddr)
+	return &net.TCPAddr{
+		IP:   a.IP.To4(),
+		Port: a.Port,
+	}
+}
+
+func (c *configConn) Close() error {
+	if c.tracked != nil {
+		c.closeOnce.Do(func() {
+			c.tracked.remove(c)
+		})
+	}
+	return c.Conn.Close()
+}
+
+func (c *configConn) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(c.Conn)
+}
+
+// NetConn returns the wrapped connection, e.g. *net.TCPConn.
+func (c *configConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// ReadFrom passes r to the wrapped connection, so io.Copy may use
+// sendfile or splice.
+func (c *configConn) ReadFrom(r io.Reader) (int64, error) {
+	if src := asConfigConn(r); src != nil && atomic.LoadInt32(&src.deadlineDone) != 0 {
+		// Copy between the wrapped connections, so the kernel
+		// may move the data.
+		r = src.Conn
+	}
+	if rf, ok := c.Conn.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(c.Conn, r)
+}
+
+// WriteTo passes w to the wrapped connection, so io.Copy may use splice.
+//
+// The first chunk is read with Read while the initial read deadline
+// is set, so the deadline is cleared as usual.
+func (c *configConn) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	if atomic.LoadInt32(&c.deadlineDone) == 0 {
+		var buf [512]byte
+		nr, err := c.Read(buf[:])
+		if nr > 0 {
+			nw, werr := w.Write(buf[:nr])
+			n += int64(nw)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	if dst := asConfigConn(w); dst != nil {
+		w = dst.Conn
+	}
+	var m int64
+	var err error
+	if wt, ok := c.Conn.(io.WriterTo); ok {
+		m, err = wt.WriteTo(w)
+	} else {
+		m, err = io.Copy(w, c.Conn)
+	}
+	return n + m, err
+}
+
+// asConfigConn returns the configConn of v or nil if v isn't one.
+func asConfigConn(v interface{}) *configConn {
+	switch c := v.(type) {
+	case *configConn:
+		return c
+	case *halfCloseConfigConn:
+		return &c.configConn
+	}
+	return nil
+}
+
+type halfCloser interface {
+	CloseRead() error
+	CloseWrite() error
+}
+
+// halfCloseConfigConn is configConn for connections supporting
+// half-close, e.g. *net.TCPConn and *net.UnixConn.
+type halfCloseConfigConn struct {
+	configConn
+}
+
+func (c *halfCloseConfigConn) CloseRead() error {
+	return c.Conn.(halfCloser).CloseRead()
+}
+
+func (c *halfCloseConfigConn) CloseWrite() error {
+	return c.Conn.(halfCloser).CloseWrite()
+}
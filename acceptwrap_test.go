@@ -0,0 +1,128 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// benchConn is a connection whose Close is a no-op, so the same
+// connection may be accepted many times.
+type benchConn struct {
+	*net.TCPConn
+}
+
+func (c *benchConn) Close() error {
+	return nil
+}
+
+// benchListener returns the same connection from every Accept,
+// so only the allocations of the wrappers are measured.
+type benchListener struct {
+	net.Listener
+	c net.Conn
+}
+
+func (ln *benchListener) Accept() (net.Conn, error) {
+	return ln.c, nil
+}
+
+type nopSink struct{}
+
+func (nopSink) Counter(name string, delta uint64) {}
+func (nopSink) Gauge(name string, value float64)  {}
+
+func BenchmarkAcceptWrapped(b *testing.B) {
+	b.Run("ipv6", func(b *testing.B) {
+		benchmarkAcceptWrapped(b, "[::1]:0", "::1")
+	})
+	b.Run("ipv4-mapped", func(b *testing.B) {
+		// The connection dialed over IPv4 is accepted by the dual-stack
+		// listener with the IPv4-mapped address, so UnmapV4 applies.
+		benchmarkAcceptWrapped(b, "[::]:0", "127.0.0.1")
+	})
+}
+
+func benchmarkAcceptWrapped(b *testing.B, addr, dialHost string) {
+	ln, err := NewListener("tcp6", addr, Config{V6Only: V6OnlyDisabled})
+	if err != nil {
+		b.Skipf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		b.Fatalf("cannot parse listener address: %s", err)
+	}
+	cc, err := net.Dial("tcp", net.JoinHostPort(dialHost, port))
+	if err != nil {
+		b.Fatalf("cannot dial listener: %s", err)
+	}
+	defer cc.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		b.Fatalf("cannot accept connection: %s", err)
+	}
+	defer c.Close()
+
+	cfg := Config{
+		UnmapV4:           true,
+		AcceptReadTimeout: time.Minute,
+	}
+	var wln net.Listener = &benchListener{
+		Listener: ln,
+		c:        &benchConn{c.(*net.TCPConn)},
+	}
+	wln = cfg.wrapListener(wln)
+	wln = WithConnConfig(wln, ConnConfig{})
+	wln = CountFastOpen(wln, nopSink{})
+	tln := TrackConns(wln)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c, err := tln.Accept()
+		if err != nil {
+			b.Fatalf("cannot accept connection: %s", err)
+		}
+		c.RemoteAddr()
+		c.Close()
+	}
+}
+
+func TestConfigConnCopy(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{AcceptReadTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	defer sc.Close()
+	if _, ok := sc.(interface{ NetConn() net.Conn }).NetConn().(*net.TCPConn); !ok {
+		t.Fatalf("the wrapped connection isn't *net.TCPConn")
+	}
+
+	req := bytes.Repeat([]byte("data"), 10000)
+	go func() {
+		c.Write(req)
+		c.(*net.TCPConn).CloseWrite()
+	}()
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, sc); err != nil {
+		t.Fatalf("cannot copy: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), req) {
+		t.Fatalf("unexpected data of %d bytes. Expecting %d bytes", buf.Len(), len(req))
+	}
+}
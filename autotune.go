@@ -0,0 +1,184 @@
+package tcplisten
+
+import (
+	"fmt"
+)
+
+// TuningDecision is a setting derived by Config.AutoTune from the machine.
+type TuningDecision struct {
+	// Setting is the name of the setting, e.g. "Backlog" or "SO_RCVBUF".
+	Setting string
+
+	// Value is the chosen value. Zero means the setting is left
+	// to the system.
+	Value int
+
+	// Reason explains why the value has been chosen.
+	Reason string
+}
+
+// String returns the decision in the form "Setting=Value (Reason)".
+func (d TuningDecision) String() string {
+	return fmt.Sprintf("%s=%d (%s)", d.Setting, d.Value, d.Reason)
+}
+
+const (
+	// autoBacklogPerCPU is the backlog per CPU, which allows absorbing
+	// bursts of connections while all the CPUs are busy.
+	autoBacklogPerCPU = 1024
+
+	// autoBacklogMin is the backlog chosen for the smallest machines.
+	autoBacklogMin = 128
+
+	// autoBacklogMemory is the memory per queued connection. Every queued
+	// connection ends up with socket buffers once accepted, so small
+	// machines shouldn't queue more connections than they can serve.
+	autoBacklogMemory = 256 * 1024
+)
+
+// machineProfile describes the machine for autoTune.
+type machineProfile struct {
+	// cpus is GOMAXPROCS.
+	cpus int
+
+	// memory is the memory available to the process in bytes,
+	// or zero if it is unknown.
+	memory uint64
+
+	// soMaxConn is the limit of the backlog, or zero if it is unknown.
+	soMaxConn int
+
+	// rmemMax and wmemMax are the limits of SO_RCVBUF and SO_SNDBUF,
+	// or zero if they are unknown.
+	rmemMax int
+	wmemMax int
+
+	// bufferAutotuning is true if the kernel grows socket buffers
+	// of every connection on demand.
+	bufferAutotuning bool
+
+	// reusePortBalances is true if SO_REUSEPORT distributes connections
+	// among the listeners.
+	reusePortBalances bool
+}
+
+// autoTuning is the result of autoTune.
+type autoTuning struct {
+	backlog    int
+	recvBuffer int
+	sendBuffer int
+	shards     int
+	decisions  []TuningDecision
+}
+
+func (t *autoTuning) decide(setting string, value int, format string, args ...interface{}) {
+	t.decisions = append(t.decisions, TuningDecision{
+		Setting: setting,
+		Value:   value,
+		Reason:  fmt.Sprintf(format, args...),
+	})
+}
+
+// autoTune derives the listener settings from the machine profile.
+func autoTune(p machineProfile) *autoTuning {
+	t := &autoTuning{}
+	cpus := p.cpus
+	if cpus < 1 {
+		cpus = 1
+	}
+
+	t.backlog = cpus * autoBacklogPerCPU
+	reason := fmt.Sprintf("%d per CPU for %d CPUs", autoBacklogPerCPU, cpus)
+	if p.memory > 0 {
+		if n := p.memory / autoBacklogMemory; n < uint64(t.backlog) {
+			t.backlog = int(n)
+			reason = fmt.Sprintf("one per %dKiB of %dMiB memory", autoBacklogMemory>>10, p.memory>>20)
+		}
+	}
+	if t.backlog < autoBacklogMin {
+		t.backlog = autoBacklogMin
+		reason = "minimum backlog"
+	}
+	if p.soMaxConn > 0 && t.backlog > p.soMaxConn {
+		t.backlog = p.soMaxConn
+		reason += fmt.Sprintf(", limited by somaxconn=%d", p.soMaxConn)
+	}
+	t.decide("Backlog", t.backlog, "%s", reason)
+
+	if p.bufferAutotuning {
+		t.decide("SO_RCVBUF", 0, "left to the kernel buffer autotuning")
+		t.decide("SO_SNDBUF", 0, "left to the kernel buffer autotuning")
+	} else {
+		size, reason := autoBufferSize(p.memory)
+		t.recvBuffer = limitBuffer(size, p.rmemMax)
+		t.sendBuffer = limitBuffer(size, p.wmemMax)
+		t.decide("SO_RCVBUF", t.recvBuffer, "%s%s", reason, limitReason(size, p.rmemMax, "rmem_max"))
+		t.decide("SO_SNDBUF", t.sendBuffer, "%s%s", reason, limitReason(size, p.wmemMax, "wmem_max"))
+	}
+
+	switch {
+	case !p.reusePortBalances:
+		t.decide("Shards", 0, "SO_REUSEPORT doesn't balance connections on this platform")
+	case cpus < 4:
+		t.decide("Shards", 0, "a single listener keeps up with %d CPUs", cpus)
+	default:
+		t.shards = cpus
+		t.decide("Shards", t.shards, "NewShardGroup with a listener per CPU spreads accept load over %d CPUs", cpus)
+	}
+	return t
+}
+
+// autoBufferSize returns the socket buffer size for the given memory.
+func autoBufferSize(memory uint64) (int, string) {
+	switch {
+	case memory == 0:
+		return 64 * 1024, "memory is unknown"
+	case memory >= 8<<30:
+		return 256 * 1024, fmt.Sprintf("%dMiB memory is at least 8GiB", memory>>20)
+	case memory >= 2<<30:
+		return 128 * 1024, fmt.Sprintf("%dMiB memory is at least 2GiB", memory>>20)
+	default:
+		return 64 * 1024, fmt.Sprintf("%dMiB memory is less than 2GiB", memory>>20)
+	}
+}
+
+func limitBuffer(size, limit int) int {
+	if limit > 0 && size > limit {
+		return limit
+	}
+	return size
+}
+
+func limitReason(size, limit int, name string) string {
+	if limit > 0 && size > limit {
+		return fmt.Sprintf(", limited by %s=%d", name, limit)
+	}
+	return ""
+}
+
+// applyAutoTune sets the options of cfg derived from the machine unless
+// they are set explicitly.
+func (cfg *Config) applyAutoTune(p machineProfile) *autoTuning {
+	t := autoTune(p)
+	if cfg.Backlog > 0 {
+		t.backlog = cfg.Backlog
+		t.decisions[0] = TuningDecision{
+			Setting: "Backlog",
+			Value:   cfg.Backlog,
+			Reason:  "set explicitly",
+		}
+	}
+	cfg.Backlog = t.backlog
+	for i, d := range t.decisions {
+		switch {
+		case d.Setting == "SO_RCVBUF" && cfg.ReceiveBufferSize > 0:
+			// The buffer is set by NewListener before bind.
+			t.recvBuffer = 0
+			t.decisions[i] = TuningDecision{Setting: d.Setting, Value: cfg.ReceiveBufferSize, Reason: "set explicitly"}
+		case d.Setting == "SO_SNDBUF" && cfg.SendBufferSize > 0:
+			t.sendBuffer = 0
+			t.decisions[i] = TuningDecision{Setting: d.Setting, Value: cfg.SendBufferSize, Reason: "set explicitly"}
+		}
+	}
+	return t
+}
@@ -0,0 +1,75 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	memInfoPath     = "/proc/meminfo"
+	cgroupMemoryMax = "/sys/fs/cgroup/memory.max"
+)
+
+// readMachineProfile inspects the machine for Config.AutoTune.
+// Values which cannot be read are left unknown.
+func readMachineProfile(cfg *Config) machineProfile {
+	p := machineProfile{
+		cpus:              runtime.GOMAXPROCS(0),
+		memory:            availableMemory(),
+		bufferAutotuning:  true,
+		reusePortBalances: reusePortBalances(cfg),
+	}
+	if n, err := soMaxConn(); err == nil {
+		p.soMaxConn = n
+	}
+	if n, err := readSysctlInt("net/core/rmem_max"); err == nil {
+		p.rmemMax = n
+	}
+	if n, err := readSysctlInt("net/core/wmem_max"); err == nil {
+		p.wmemMax = n
+	}
+	return p
+}
+
+// availableMemory returns MemTotal from /proc/meminfo limited
+// by the memory.max of the cgroup v2 namespace root, or zero
+// if it cannot be read.
+func availableMemory() uint64 {
+	var buf [128]byte
+	size, err := readSmallFile(memInfoPath, buf[:])
+	if err != nil {
+		return 0
+	}
+	memory := parseMemTotal(string(buf[:size]))
+
+	size, err = readSmallFile(cgroupMemoryMax, buf[:])
+	if err != nil {
+		return memory
+	}
+	// memory.max is "max" if the cgroup isn't limited.
+	limit, err := strconv.ParseUint(strings.TrimSpace(string(buf[:size])), 10, 64)
+	if err == nil && limit > 0 && (memory == 0 || limit < memory) {
+		memory = limit
+	}
+	return memory
+}
+
+// parseMemTotal returns MemTotal in bytes from the contents
+// of /proc/meminfo, or zero if it is missing.
+func parseMemTotal(s string) uint64 {
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestParseMemTotal(t *testing.T) {
+	s := "MemTotal:        8039124 kB\nMemFree:         1234567 kB\n"
+	if n := parseMemTotal(s); n != 8039124<<10 {
+		t.Fatalf("unexpected MemTotal %d. Expecting %d", n, uint64(8039124<<10))
+	}
+	if n := parseMemTotal("MemFree: 1 kB\n"); n != 0 {
+		t.Fatalf("unexpected MemTotal %d for missing field", n)
+	}
+}
+
+func TestNewListenerAutoTune(t *testing.T) {
+	res, err := NewListenerResult("tcp4", "127.0.0.1:0", Config{AutoTune: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer res.Close()
+
+	if len(res.Tuning) == 0 || res.Tuning[0].Setting != "Backlog" {
+		t.Fatalf("unexpected tuning %v", res.Tuning)
+	}
+	if res.Backlog != res.Tuning[0].Value {
+		t.Fatalf("unexpected backlog %d. Expecting %d", res.Backlog, res.Tuning[0].Value)
+	}
+	if res.hasOption("SO_RCVBUF") {
+		t.Fatalf("SO_RCVBUF mustn't be set when the kernel autotunes buffers")
+	}
+}
@@ -0,0 +1,20 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"runtime"
+)
+
+// readMachineProfile inspects the machine for Config.AutoTune.
+// The memory isn't read outside Linux, so it doesn't limit the backlog.
+func readMachineProfile(cfg *Config) machineProfile {
+	p := machineProfile{
+		cpus:              runtime.GOMAXPROCS(0),
+		reusePortBalances: reusePortBalances(cfg),
+	}
+	if n, err := soMaxConn(); err == nil {
+		p.soMaxConn = n
+	}
+	return p
+}
@@ -0,0 +1,73 @@
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestAutoTune(t *testing.T) {
+	testAutoTune := func(name string, p machineProfile, backlog, buffer, shards int) {
+		t.Helper()
+		tuning := autoTune(p)
+		if tuning.backlog != backlog || tuning.recvBuffer != buffer || tuning.sendBuffer != buffer || tuning.shards != shards {
+			t.Fatalf("%s: unexpected backlog=%d, buffers=%d/%d, shards=%d. Expecting %d, %d, %d",
+				name, tuning.backlog, tuning.recvBuffer, tuning.sendBuffer, tuning.shards, backlog, buffer, shards)
+		}
+		if len(tuning.decisions) != 4 {
+			t.Fatalf("%s: unexpected decisions %v", name, tuning.decisions)
+		}
+		for _, d := range tuning.decisions {
+			if d.Reason == "" {
+				t.Fatalf("%s: missing reason for %s", name, d.Setting)
+			}
+		}
+	}
+
+	testAutoTune("tiny container", machineProfile{
+		cpus:              1,
+		memory:            16 << 20,
+		soMaxConn:         4096,
+		bufferAutotuning:  true,
+		reusePortBalances: true,
+	}, autoBacklogMin, 0, 0)
+	testAutoTune("small vm", machineProfile{
+		cpus:              2,
+		memory:            512 << 20,
+		soMaxConn:         4096,
+		bufferAutotuning:  true,
+		reusePortBalances: true,
+	}, 2048, 0, 0)
+	testAutoTune("big linux server", machineProfile{
+		cpus:              64,
+		memory:            256 << 30,
+		soMaxConn:         4096,
+		bufferAutotuning:  true,
+		reusePortBalances: true,
+	}, 4096, 0, 64)
+	testAutoTune("bsd server", machineProfile{
+		cpus:      8,
+		memory:    16 << 30,
+		soMaxConn: 65535,
+		rmemMax:   128 * 1024,
+		wmemMax:   128 * 1024,
+	}, 8192, 128*1024, 0)
+	testAutoTune("unknown memory", machineProfile{
+		cpus: 4,
+	}, 4096, 64*1024, 0)
+}
+
+func TestAutoTuneExplicitBacklog(t *testing.T) {
+	cfg := Config{Backlog: 10}
+	tuning := cfg.applyAutoTune(machineProfile{cpus: 8})
+	if cfg.Backlog != 10 || tuning.backlog != 10 {
+		t.Fatalf("unexpected backlog %d. Expecting 10", cfg.Backlog)
+	}
+	if d := tuning.decisions[0]; d.Setting != "Backlog" || d.Reason != "set explicitly" {
+		t.Fatalf("unexpected decision %s", d)
+	}
+
+	cfg = Config{}
+	cfg.applyAutoTune(machineProfile{cpus: 8})
+	if cfg.Backlog != 8*autoBacklogPerCPU {
+		t.Fatalf("unexpected backlog %d. Expecting %d", cfg.Backlog, 8*autoBacklogPerCPU)
+	}
+}
@@ -0,0 +1,28 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setBuffers sets the derived socket buffer sizes on the listening socket,
+// so the accepted connections inherit them.
+func (t *autoTuning) setBuffers(res *ListenResult, tr tracer) error {
+	return withFd(res.Listener, func(fd uintptr) error {
+		if t.recvBuffer > 0 {
+			if err := tr.setsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, "SO_RCVBUF", t.recvBuffer); err != nil {
+				return fmt.Errorf("cannot set SO_RCVBUF to %d: %s", t.recvBuffer, err)
+			}
+			res.applied("SO_RCVBUF")
+		}
+		if t.sendBuffer > 0 {
+			if err := tr.setsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF, "SO_SNDBUF", t.sendBuffer); err != nil {
+				return fmt.Errorf("cannot set SO_SNDBUF to %d: %s", t.sendBuffer, err)
+			}
+			res.applied("SO_SNDBUF")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,37 @@
+package tcplisten
+
+// minSuggestedBacklog is the smallest backlog SuggestBacklog returns,
+// so short bursts don't overflow the accept queue of idle servers.
+const minSuggestedBacklog = 128
+
+// SuggestBacklog returns the recommended Config.Backlog for the expected
+// rate of new connections and the time the server needs for accepting
+// a connection, in milliseconds.
+//
+// The accept queue holds connections which have been established,
+// but haven't been accepted yet. By Little's law its average length is
+// connectionsPerSecond * handlerLatencyMs / 1000. The suggestion doubles
+// it for bursts and limits the result by the system-wide maximum
+// (net.core.somaxconn on Linux), since the kernel silently truncates
+// larger backlogs.
+func SuggestBacklog(connectionsPerSecond, handlerLatencyMs int) int {
+	if connectionsPerSecond < 0 {
+		connectionsPerSecond = 0
+	}
+	if handlerLatencyMs < 0 {
+		handlerLatencyMs = 0
+	}
+	n := 2 * ((int64(connectionsPerSecond)*int64(handlerLatencyMs) + 999) / 1000)
+	if n < minSuggestedBacklog {
+		n = minSuggestedBacklog
+	}
+
+	max, err := soMaxConn()
+	if err != nil || max <= 0 {
+		return int(n)
+	}
+	if n > int64(max) {
+		n = int64(max)
+	}
+	return int(n)
+}
@@ -0,0 +1,26 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestSuggestBacklog(t *testing.T) {
+	max, err := soMaxConn()
+	if err != nil {
+		t.Fatalf("cannot obtain somaxconn: %s", err)
+	}
+
+	if n := SuggestBacklog(0, 0); n != minSuggestedBacklog && n != max {
+		t.Fatalf("unexpected backlog %d for idle server", n)
+	}
+	if n := SuggestBacklog(1e9, 1e3); n != max {
+		t.Fatalf("unexpected backlog %d. Expecting somaxconn %d", n, max)
+	}
+	if max >= 2000 {
+		if n := SuggestBacklog(10000, 100); n != 2000 {
+			t.Fatalf("unexpected backlog %d. Expecting 2000", n)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package tcplisten
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// Metric names reported by SendBufferTuner.Run.
+const (
+	MetricSendBufferAdjustments = "tcplisten_sndbuf_adjustments"
+	MetricSendBufferBytes       = "tcplisten_sndbuf_bytes"
+)
+
+const (
+	// DefaultMinSendBuffer is the default lower bound of the send buffer
+	// size set by SendBufferTuner.
+	DefaultMinSendBuffer = 64 << 10
+
+	// DefaultMaxSendBuffer is the default upper bound of the send buffer
+	// size set by SendBufferTuner.
+	DefaultMaxSendBuffer = 4 << 20
+)
+
+// sendBufferHysteresis is the minimum relative difference between
+// the current and the target send buffer sizes for adjusting the buffer,
+// so the buffers don't flap with the RTT jitter.
+const sendBufferHysteresis = 0.25
+
+// SendBufferTuner sizes SO_SNDBUF of tracked connections to their
+// bandwidth-delay product, e.g. for listeners serving both same-rack
+// and cross-continent clients.
+//
+// The first adjustment of a connection sets SO_SNDBUF explicitly,
+// which disables the kernel send buffer autotuning for the connection,
+// so the tuner doesn't fight the kernel. Connections the tuner hasn't
+// adjusted yet are left to the autotuning.
+type SendBufferTuner struct {
+	// Bandwidth is the target bandwidth of a connection in bytes
+	// per second.
+	//
+	// The buffer is sized for twice the throughput measured
+	// by the kernel, so it may grow with the connection, but not
+	// for more than Bandwidth. Bandwidth is used if the kernel
+	// hasn't measured the throughput yet.
+	Bandwidth uint64
+
+	// MinBuffer and MaxBuffer bound the buffer size in bytes.
+	//
+	// DefaultMinSendBuffer and DefaultMaxSendBuffer are used by default.
+	// The kernel additionally limits the size by net.core.wmem_max.
+	MinBuffer int
+	MaxBuffer int
+
+	// Interval is the period for sampling every tracked connection.
+	//
+	// One second is used by default.
+	Interval time.Duration
+
+	// Sink receives the number of adjustments as MetricSendBufferAdjustments
+	// and, if it implements HistogramSink, the adjusted sizes
+	// as MetricSendBufferBytes.
+	Sink MetricsSink
+
+	// OnAdjust is called with the connection and its previous and new
+	// send buffer sizes after the buffer has been adjusted.
+	OnAdjust func(c net.Conn, prev, size int)
+}
+
+// sendBufferSample is the state of a connection sampled by SendBufferTuner.
+type sendBufferSample struct {
+	rtt time.Duration
+
+	// rate is the throughput measured by the kernel in bytes per second,
+	// or zero if it hasn't been measured yet.
+	rate uint64
+
+	// size is the current send buffer size.
+	size int
+}
+
+// Run samples connections from set every interval and adjusts their
+// send buffers until ctx is done.
+//
+// ErrUnsupportedOption is returned if the RTT of connections cannot
+// be obtained on the current platform.
+func (bt *SendBufferTuner) Run(ctx context.Context, set ConnSet) error {
+	if bt.Bandwidth == 0 {
+		return errors.New("cannot tune send buffers: Bandwidth must be set")
+	}
+	interval := bt.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		for _, c := range set.Conns() {
+			if err := bt.tune(c); err == ErrUnsupportedOption {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (bt *SendBufferTuner) tune(c net.Conn) error {
+	s, err := sampleSendBuffer(c)
+	if err != nil {
+		// The connection may have been closed concurrently.
+		return err
+	}
+	size := bt.targetSize(s)
+	if !needsAdjustment(s.size, size) {
+		return nil
+	}
+	if err = setSendBuffer(c, size); err != nil {
+		return err
+	}
+	if bt.Sink != nil {
+		bt.Sink.Counter(MetricSendBufferAdjustments, 1)
+		if hs, ok := bt.Sink.(HistogramSink); ok {
+			hs.Observe(MetricSendBufferBytes, float64(size))
+		}
+	}
+	if bt.OnAdjust != nil {
+		bt.OnAdjust(c, s.size, size)
+	}
+	return nil
+}
+
+// targetSize returns the send buffer size for the sampled connection.
+func (bt *SendBufferTuner) targetSize(s sendBufferSample) int {
+	rate := bt.Bandwidth
+	if s.rate > 0 && 2*s.rate < rate {
+		rate = 2 * s.rate
+	}
+	size := int(float64(rate) * s.rtt.Seconds())
+
+	minSize, maxSize := bt.MinBuffer, bt.MaxBuffer
+	if minSize <= 0 {
+		minSize = DefaultMinSendBuffer
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSendBuffer
+	}
+	if size < minSize {
+		size = minSize
+	}
+	if size > maxSize {
+		size = maxSize
+	}
+	return size
+}
+
+// needsAdjustment reports whether the buffer must be resized
+// from prev to size.
+func needsAdjustment(prev, size int) bool {
+	d := float64(size - prev)
+	if d < 0 {
+		d = -d
+	}
+	return d >= sendBufferHysteresis*float64(prev)
+}
@@ -0,0 +1,46 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// sampleSendBuffer returns the RTT, the delivery rate and the send
+// buffer size of the connection.
+func sampleSendBuffer(c net.Conn) (sendBufferSample, error) {
+	var s sendBufferSample
+	err := withFd(c, func(fd uintptr) error {
+		var ti tcpInfoExt
+		l := uint32(unsafe.Sizeof(ti))
+		if err := getsockopt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_INFO, unsafe.Pointer(&ti), &l); err != nil {
+			return fmt.Errorf("cannot obtain TCP_INFO: %s", err)
+		}
+		size, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+		if err != nil {
+			return fmt.Errorf("cannot obtain SO_SNDBUF: %s", err)
+		}
+		s = sendBufferSample{
+			rtt:  time.Duration(ti.Rtt) * time.Microsecond,
+			rate: ti.deliveryRate,
+			// Linux reports the doubled value passed to SO_SNDBUF.
+			size: size / 2,
+		}
+		return nil
+	})
+	return s, err
+}
+
+// setSendBuffer sets SO_SNDBUF of the connection.
+func setSendBuffer(c net.Conn, size int) error {
+	return withFd(c, func(fd uintptr) error {
+		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF, size); err != nil {
+			return fmt.Errorf("cannot set SO_SNDBUF to %d: %s", size, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,88 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSendBufferTuner(t *testing.T) {
+	base, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln := TrackConns(base)
+	defer ln.Close()
+
+	cc, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial listener: %s", err)
+	}
+	defer cc.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	defer c.Close()
+
+	const size = 256 << 10
+	adjusted := make(chan int, 1)
+	sink := newTestSink()
+	bt := &SendBufferTuner{
+		Bandwidth: 1 << 30,
+		MinBuffer: size,
+		MaxBuffer: size,
+		Interval:  10 * time.Millisecond,
+		Sink:      sink,
+		OnAdjust: func(c net.Conn, prev, size int) {
+			select {
+			case adjusted <- size:
+			default:
+			}
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- bt.Run(ctx, ln)
+	}()
+
+	select {
+	case n := <-adjusted:
+		if n != size {
+			t.Fatalf("unexpected adjusted size %d. Expecting %d", n, size)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for the buffer adjustment")
+	}
+	// Wait for a few more samples, which mustn't adjust the buffer again.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err = <-done; err != context.Canceled {
+		t.Fatalf("unexpected error %v. Expecting %v", err, context.Canceled)
+	}
+
+	var n int
+	err = withFd(c, func(fd uintptr) error {
+		var err error
+		n, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("cannot obtain SO_SNDBUF: %s", err)
+	}
+	if n != 2*size {
+		t.Fatalf("unexpected SO_SNDBUF %d. Expecting %d", n, 2*size)
+	}
+	if v := sink.counters[MetricSendBufferAdjustments]; v != 1 {
+		t.Fatalf("unexpected number of adjustments %d. Expecting 1", v)
+	}
+	if h := sink.histograms[MetricSendBufferBytes]; len(h) != 1 || h[0] != size {
+		t.Fatalf("unexpected adjusted sizes %v. Expecting [%d]", h, size)
+	}
+}
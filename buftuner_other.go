@@ -0,0 +1,19 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// sampleSendBuffer returns the RTT, the delivery rate and the send
+// buffer size of the connection.
+//
+// It is supported only on Linux.
+func sampleSendBuffer(c net.Conn) (sendBufferSample, error) {
+	return sendBufferSample{}, ErrUnsupportedOption
+}
+
+func setSendBuffer(c net.Conn, size int) error {
+	return ErrUnsupportedOption
+}
@@ -0,0 +1,53 @@
+package tcplisten
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSendBufferTunerTargetSize(t *testing.T) {
+	bt := &SendBufferTuner{
+		Bandwidth: 100 << 20,
+		MinBuffer: 32 << 10,
+		MaxBuffer: 8 << 20,
+	}
+	for _, tc := range []struct {
+		rtt  time.Duration
+		rate uint64
+		size int
+	}{
+		// Same-rack clients get the minimum buffer.
+		{100 * time.Microsecond, 0, 32 << 10},
+		// The configured bandwidth is used until the rate is measured.
+		{10 * time.Millisecond, 0, 1 << 20},
+		// The buffer is sized for twice the measured rate.
+		{10 * time.Millisecond, 10 << 20, 209715},
+		// The measured rate doesn't raise the buffer above Bandwidth.
+		{10 * time.Millisecond, 200 << 20, 1 << 20},
+		// Cross-continent clients are limited by MaxBuffer.
+		{150 * time.Millisecond, 0, 8 << 20},
+	} {
+		size := bt.targetSize(sendBufferSample{rtt: tc.rtt, rate: tc.rate})
+		if d := size - tc.size; d < -1 || d > 1 {
+			t.Fatalf("unexpected size %d for rtt=%s, rate=%d. Expecting %d", size, tc.rtt, tc.rate, tc.size)
+		}
+	}
+
+	bt = &SendBufferTuner{Bandwidth: 1}
+	if size := bt.targetSize(sendBufferSample{rtt: time.Second}); size != DefaultMinSendBuffer {
+		t.Fatalf("unexpected size %d. Expecting %d", size, DefaultMinSendBuffer)
+	}
+	bt = &SendBufferTuner{Bandwidth: 1 << 40}
+	if size := bt.targetSize(sendBufferSample{rtt: time.Second}); size != DefaultMaxSendBuffer {
+		t.Fatalf("unexpected size %d. Expecting %d", size, DefaultMaxSendBuffer)
+	}
+}
+
+func TestSendBufferNeedsAdjustment(t *testing.T) {
+	if needsAdjustment(100000, 110000) || needsAdjustment(100000, 90000) {
+		t.Fatalf("small changes mustn't adjust the buffer")
+	}
+	if !needsAdjustment(100000, 200000) || !needsAdjustment(100000, 50000) || !needsAdjustment(0, 1) {
+		t.Fatalf("large changes must adjust the buffer")
+	}
+}
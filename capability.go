@@ -0,0 +1,45 @@
+package tcplisten
+
+import (
+	"fmt"
+)
+
+// MissingCapabilityError is returned if an option requires a privilege
+// the process lacks, so the option would fail with EPERM.
+type MissingCapabilityError struct {
+	// Option is the name of the option, e.g. "SO_MARK".
+	Option string
+
+	// Capability is the missing privilege, e.g. "CAP_NET_ADMIN".
+	Capability string
+}
+
+func (e *MissingCapabilityError) Error() string {
+	return fmt.Sprintf("tcplisten: %s requires %s, which the process lacks", e.Option, e.Capability)
+}
+
+// capabilityCheck describes an option requiring a privilege.
+type capabilityCheck struct {
+	option     string
+	capability string
+
+	// requested reports whether cfg requests the option.
+	requested func(cfg *Config) bool
+
+	// granted reports whether the process holds the privilege.
+	granted func() bool
+}
+
+// checkCapabilities returns MissingCapabilityError for the first option
+// requested by cfg whose privilege the process lacks.
+func (cfg *Config) checkCapabilities() error {
+	for _, c := range capabilityChecks {
+		if c.requested(cfg) && !c.granted() {
+			return &MissingCapabilityError{
+				Option:     c.option,
+				Capability: c.capability,
+			}
+		}
+	}
+	return nil
+}
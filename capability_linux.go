@@ -0,0 +1,44 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const capNetAdmin = 12
+
+// capabilityChecks lists the Config options requiring capabilities,
+// which Config.Validate checks before any syscall is made. Helpers
+// requiring capabilities, e.g. BindToCgroup, check them by themselves.
+var capabilityChecks = []capabilityCheck{
+	{
+		option:     "IP_TRANSPARENT",
+		capability: "CAP_NET_ADMIN",
+		requested:  func(cfg *Config) bool { return cfg.Transparent },
+		granted:    func() bool { return hasEffectiveCap(capNetAdmin) },
+	},
+}
+
+// hasEffectiveCap reports whether the capability is in CapEff
+// of /proc/self/status.
+func hasEffectiveCap(capability uint) bool {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(line[len("CapEff:"):]), 16, 64)
+		return err == nil && caps&(1<<capability) != 0
+	}
+	return false
+}
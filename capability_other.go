@@ -0,0 +1,6 @@
+// +build !linux
+
+package tcplisten
+
+// capabilityChecks lists the Config options requiring privileges.
+var capabilityChecks []capabilityCheck
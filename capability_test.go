@@ -0,0 +1,39 @@
+package tcplisten
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateCapabilities(t *testing.T) {
+	saved := capabilityChecks
+	defer func() {
+		capabilityChecks = saved
+	}()
+	capabilityChecks = []capabilityCheck{{
+		option:     "SO_TEST",
+		capability: "CAP_TEST",
+		requested:  func(cfg *Config) bool { return cfg.NoDelay },
+		granted:    func() bool { return false },
+	}}
+
+	if err := (Config{}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err := Config{NoDelay: true}.Validate()
+	var ce *MissingCapabilityError
+	if !errors.As(err, &ce) || ce.Option != "SO_TEST" || ce.Capability != "CAP_TEST" {
+		t.Fatalf("unexpected error %v. Expecting MissingCapabilityError for SO_TEST", err)
+	}
+	if !strings.Contains(err.Error(), "SO_TEST") || !strings.Contains(err.Error(), "CAP_TEST") {
+		t.Fatalf("%q doesn't name the option and the capability", err)
+	}
+	if _, err = NewListener("tcp4", "127.0.0.1:0", Config{NoDelay: true}); !errors.As(err, &ce) {
+		t.Fatalf("unexpected error %v. Expecting MissingCapabilityError", err)
+	}
+
+	if err = (Config{ReusePort: true, ReusePortLB: true}).Validate(); err == nil {
+		t.Fatalf("expecting error for ReusePort with ReusePortLB")
+	}
+}
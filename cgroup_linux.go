@@ -0,0 +1,51 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+)
+
+const cgroup2SuperMagic = 0x63677270
+
+// BindToCgroup sets SO_MARK of the socket to the id of the cgroup v2
+// at cgroupPath, i.e. to the inode number of its directory, so the traffic
+// of the socket may be classified by the cgroup with fwmark rules
+// of iptables, nftables or tc. The mark is inherited by the connections
+// accepted from a listening socket.
+//
+// cgroup v2 has no net_cls controller, and sockets are charged to the cgroup
+// of the process creating them, which nftables may match directly with
+// `socket cgroupv2`. BindToCgroup is for sockets created in another cgroup,
+// e.g. by a supervisor passing them to containers.
+//
+// It may be called from Config.PostListen. Setting SO_MARK requires
+// CAP_NET_ADMIN.
+func BindToCgroup(fd uintptr, cgroupPath string) error {
+	if !hasEffectiveCap(capNetAdmin) {
+		return &MissingCapabilityError{
+			Option:     "SO_MARK",
+			Capability: "CAP_NET_ADMIN",
+		}
+	}
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(cgroupPath, &fs); err != nil {
+		return fmt.Errorf("cannot access cgroup %q: %s", cgroupPath, err)
+	}
+	if fs.Type != cgroup2SuperMagic {
+		return fmt.Errorf("cannot bind to %q: it isn't a cgroup v2 directory", cgroupPath)
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(cgroupPath, &st); err != nil {
+		return fmt.Errorf("cannot access cgroup %q: %s", cgroupPath, err)
+	}
+	if st.Ino > math.MaxUint32 {
+		return fmt.Errorf("cannot bind to %q: cgroup id %d doesn't fit SO_MARK", cgroupPath, st.Ino)
+	}
+	if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, int(st.Ino)); err != nil {
+		return fmt.Errorf("cannot set SO_MARK to cgroup id %d: %s", st.Ino, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package tcplisten
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// cgroup2Mount returns the mount point of cgroup v2.
+func cgroup2Mount(t *testing.T) string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		t.Skipf("cannot open mounts: %s", err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) > 2 && fields[2] == "cgroup2" {
+			return fields[1]
+		}
+	}
+	t.Skipf("cgroup v2 isn't mounted")
+	return ""
+}
+
+func TestBindToCgroup(t *testing.T) {
+	path := cgroup2Mount(t)
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		t.Fatalf("cannot stat %q: %s", path, err)
+	}
+
+	var mark int
+	cfg := Config{
+		PostListen: func(fd uintptr) error {
+			if err := BindToCgroup(fd, path); err != nil {
+				return err
+			}
+			var err error
+			mark, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK)
+			return err
+		},
+	}
+	ln, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		if strings.Contains(err.Error(), syscall.EPERM.Error()) {
+			t.Skipf("SO_MARK requires CAP_NET_ADMIN: %s", err)
+		}
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln.Close()
+	if uint64(mark) != uint64(st.Ino) {
+		t.Fatalf("unexpected SO_MARK %d. Expecting cgroup id %d", mark, st.Ino)
+	}
+
+	ln, err = NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	err = withFd(ln, func(fd uintptr) error {
+		return BindToCgroup(fd, os.TempDir())
+	})
+	if err == nil {
+		t.Fatalf("expecting error for %q", os.TempDir())
+	}
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package tcplisten
+
+// BindToCgroup sets SO_MARK of the socket to the id of the cgroup v2
+// at cgroupPath.
+//
+// It is supported only on Linux.
+func BindToCgroup(fd uintptr, cgroupPath string) error {
+	return &UnsupportedError{
+		Op:     "BindToCgroup",
+		Reason: "cgroups and SO_MARK exist only on Linux",
+	}
+}
@@ -0,0 +1,484 @@
+package tcplisten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Config provides options to enable on the returned listener.
+//
+// All the options are available on every platform. Options which cannot
+// be honored are either ignored or make NewListener fail with an error
+// wrapping ErrUnsupportedOption, as documented for each option.
+// Plan 9 has no socket options, so all of them are unsupported there.
+type Config struct {
+	// ReusePort enables SO_REUSEPORT.
+	//
+	// Linux and DragonFly distribute incoming connections among
+	// the listeners sharing the port. FreeBSD, macOS, NetBSD and OpenBSD
+	// deliver all the connections to a single listener, so use
+	// ReusePortLB on FreeBSD for load balancing.
+	//
+	// It isn't supported on Windows, where the closest SO_REUSEADDR
+	// allows any socket to bind the port in use.
+	ReusePort bool
+
+	// DisableReuseAddr disables SO_REUSEADDR, which NewListener enables
+	// by default, so the listener cannot be bound while connections
+	// to its port linger in TIME_WAIT.
+	//
+	// It may be combined with ReusePort for sharing the port without
+	// the TIME_WAIT rebind behavior of SO_REUSEADDR. It is ignored
+	// on Windows, where SO_REUSEADDR isn't set.
+	DisableReuseAddr bool
+
+	// UnlinkBeforeBind removes the socket file left at the path
+	// of a unix listener, e.g. by a crashed process, before binding,
+	// which is the unix socket counterpart of SO_REUSEADDR. The path
+	// is dialed first, and the file is removed only if the connection
+	// is refused, so the socket of a live listener is kept and binding
+	// fails then. The live listener accepts the dialed connection, which
+	// is closed without sending anything. Files other than sockets
+	// aren't removed. Binding fails if the file exists by default.
+	//
+	// The socket file is removed when the listener is closed anyway.
+	// It is ignored for TCP listeners and abstract unix socket names.
+	UnlinkBeforeBind bool
+
+	// UnlinkStale removes the socket file at the path of a unix listener
+	// if binding fails with EADDRINUSE and the file is stale, and retries
+	// binding once.
+	//
+	// The file is stale if dialing it fails with ECONNREFUSED, see
+	// UnlinkBeforeBind. Unlike UnlinkBeforeBind, the path is dialed only
+	// if it is in use, so nothing connects to a live listener when
+	// the path is free. It is ignored for TCP listeners and abstract
+	// unix socket names.
+	UnlinkStale bool
+
+	// UnixMode sets the permission bits of the socket file of a unix
+	// listener, e.g. 0660 for allowing only the owner and the group
+	// to connect. The file is created with the umask by default.
+	//
+	// The mode is set after bind and before listen, and connections
+	// are refused until listen, so no client can connect to the socket
+	// with the default mode. The socket file is removed if the mode
+	// cannot be set. It is ignored for TCP listeners and abstract
+	// unix socket names.
+	UnixMode os.FileMode
+
+	// UnixOwner changes the owner of the socket file of a unix listener
+	// the same way as UnixMode changes its mode.
+	//
+	// Changing the UID requires root privileges or CAP_CHOWN on Linux.
+	// The process may change the GID to any of its groups without them.
+	UnixOwner *UnixOwner
+
+	// ExclusiveAddrUse enables SO_EXCLUSIVEADDRUSE, which prevents
+	// other sockets from binding the port of the listener.
+	//
+	// It is supported only on Windows. It is ignored on other platforms,
+	// where the port of a listener cannot be taken over by default.
+	ExclusiveAddrUse bool
+
+	// ExclusiveReusePortGroup makes NewListener with ReusePort fail
+	// with ForeignReusePortError if sockets held by other processes
+	// already listen on the same address, e.g. the ones of a misconfigured
+	// second deployment, which would silently take over a share
+	// of incoming connections. Sockets held by the current process, e.g.
+	// the other NewShardGroup listeners, are allowed.
+	//
+	// The group is looked up via inet_diag, so it is checked only on Linux.
+	// If the check cannot be performed, a "sock_diag" trace record with
+	// the error is emitted and a message is logged before binding anyway.
+	// It is ignored on Windows.
+	ExclusiveReusePortGroup bool
+
+	// AllowForeignReusePort makes ExclusiveReusePortGroup log the sockets
+	// of other processes instead of failing.
+	AllowForeignReusePort bool
+
+	// ReusePortLB enables SO_REUSEPORT_LB, which distributes incoming
+	// connections among the listeners sharing the port.
+	//
+	// It is supported only on FreeBSD 12 and newer. NewListener fails
+	// with an error wrapping ErrUnsupportedOption on other platforms.
+	// It cannot be enabled together with ReusePort.
+	ReusePortLB bool
+
+	// DeferAccept enables TCP_DEFER_ACCEPT.
+	//
+	// It is ignored on platforms other than Linux. A hint suggesting
+	// DeferUntilData is logged on other Unix platforms then.
+	DeferAccept bool
+
+	// DeferUntilData makes Accept return connections only after they
+	// become readable, like DeferAccept does, but at the application
+	// layer, so it works on all the platforms. The listener is wrapped
+	// with EmulateDeferAccept with DefaultDeferUntilDataTimeout, so
+	// connections which send nothing during the timeout are closed.
+	// Use EmulateDeferAccept directly for another timeout.
+	//
+	// Every accepted connection is peeked in a separate goroutine
+	// until it becomes readable. Connections are returned as soon
+	// as they are accepted on Plan 9, where peeking isn't supported.
+	DeferUntilData bool
+
+	// FastOpen enables TCP_FASTOPEN.
+	//
+	// It is ignored on platforms other than Linux.
+	FastOpen bool
+
+	// NoDelay enables TCP_NODELAY.
+	//
+	// The standard library enables TCP_NODELAY on accepted connections
+	// on Windows anyway.
+	NoDelay bool
+
+	// QuickACK enables TCP_QUICKACK.
+	//
+	// Linux clears it on the accepted connections once they switch
+	// to delayed ACKs, so use SetQuickAck for keeping it enabled.
+	// It is ignored on platforms other than Linux.
+	QuickACK bool
+
+	// Backlog is the maximum number of pending TCP connections the listener
+	// may queue before passing them to Accept.
+	// See man 2 listen for details.
+	//
+	// By default system-level backlog value is used.
+	// It is ignored on Windows with Go versions older than 1.25,
+	// see NewListenerResult.
+	Backlog int
+
+	// ReceiveBufferSize and SendBufferSize set SO_RCVBUF and SO_SNDBUF
+	// of the listening socket before bind, which are inherited by
+	// the accepted connections. The receive buffer must be set before
+	// the connections are established, since the window scale offered
+	// to clients is derived from it. The system defaults are kept
+	// if they are zero.
+	//
+	// Linux doubles the values for the bookkeeping overhead, so getsockopt
+	// reports twice the size, and caps them at net.core.rmem_max
+	// and net.core.wmem_max. SO_RCVBUFFORCE and SO_SNDBUFFORCE
+	// aren't used. Setting SO_RCVBUF disables the receive buffer
+	// autotuning, so ReceiveBufferSize cannot be set together
+	// with DisableRecvAutotune.
+	ReceiveBufferSize int
+	SendBufferSize    int
+
+	// InitialRTO is the initial retransmission timeout of accepted
+	// connections. The system default is used by default.
+	//
+	// It is supported only on Windows, where it is set with
+	// SIO_TCP_INITIAL_RTO. Linux configures it per route
+	// (ip route ... rto_min), and the BSDs only system-wide,
+	// so NewListener fails with an error wrapping ErrUnsupportedOption
+	// if InitialRTO is set there.
+	InitialRTO time.Duration
+
+	// MaxPacingRate sets SO_MAX_PACING_RATE in bytes per second,
+	// which is inherited by the accepted connections.
+	// Use ConnConfig.MaxPacingRate for overriding it per connection.
+	//
+	// The rate is enforced only by the fq qdisc or by the internal
+	// pacing of BBR, and is ignored by the other qdiscs and congestion
+	// control algorithms.
+	//
+	// It is supported only on Linux. NewListener fails with an error
+	// wrapping ErrUnsupportedOption if MaxPacingRate is set elsewhere.
+	MaxPacingRate uint64
+
+	// HardwareTimestamping enables SO_TIMESTAMPING with hardware
+	// and software timestamps of received and sent data, which is
+	// inherited by the accepted connections. Use ReadTimestamped
+	// and ReadTxTimestamps for obtaining the timestamps.
+	//
+	// Hardware timestamps require a NIC driver supporting them, which is
+	// shown by `ethtool -T`, and hardware timestamping must be enabled
+	// on the interface with SIOCSHWTSTAMP, e.g. by `hwstamp_ctl -r 1 -t 1`
+	// or a PTP daemon. Only software timestamps are reported otherwise.
+	//
+	// It is supported only on Linux. NewListener fails with an error
+	// wrapping ErrUnsupportedOption if HardwareTimestamping is set elsewhere.
+	HardwareTimestamping bool
+
+	// V6Only controls IPV6_V6ONLY on tcp6 listeners.
+	//
+	// The platform default is used by default.
+	V6Only V6OnlyMode
+
+	// FlowLabel controls the IPv6 flow label of packets sent
+	// by the accepted connections.
+	//
+	// It is supported only for tcp6 listeners on Linux. NewListener fails
+	// with an error if FlowLabel is set for tcp4 listeners, and with
+	// an error wrapping ErrUnsupportedOption on other platforms.
+	FlowLabel FlowLabelMode
+
+	// Transparent allows binding and accepting connections for addresses
+	// which aren't local, e.g. for transparent proxies.
+	//
+	// It enables IP_TRANSPARENT or IPV6_TRANSPARENT on Linux, which requires
+	// CAP_NET_ADMIN and TPROXY rules, and SO_BINDANY on OpenBSD, which
+	// requires root and pf divert-to rules. NewListener fails with
+	// *MissingCapabilityError if the process lacks the privilege,
+	// and with an error wrapping ErrUnsupportedOption on other platforms.
+	Transparent bool
+
+	// ServiceClass is the traffic class of the accepted connections.
+	//
+	// It sets SO_NET_SERVICE_TYPE on macOS, and the DSCP bits of IP_TOS
+	// or IPV6_TCLASS together with SO_PRIORITY on Linux. Recent Linux
+	// kernels derive SO_PRIORITY of the accepted connections from the SYN,
+	// so there it applies only to SYN-ACKs, while the DSCP bits are
+	// inherited. NewListener fails with an error wrapping
+	// ErrUnsupportedOption if ServiceClass is set on other platforms.
+	ServiceClass ServiceClass
+
+	// UnmapV4 makes RemoteAddr of the accepted connections return
+	// plain IPv4 addresses instead of IPv4-mapped IPv6 addresses,
+	// e.g. 1.2.3.4 instead of ::ffff:1.2.3.4 for IPv4 clients of tcp6
+	// listeners.
+	UnmapV4 bool
+
+	// AcceptReadTimeout is the read deadline set on every accepted
+	// connection, which is cleared after the first successful read.
+	// It closes connections from clients which connect without sending
+	// anything, e.g. slowloris attacks.
+	//
+	// The deadline isn't cleared if the caller sets its own deadline
+	// before the first read. It is disabled by default.
+	AcceptReadTimeout time.Duration
+
+	// DisableRecvAutotune pins SO_RCVBUF of the accepted connections
+	// to the system default receive buffer size, e.g. net.ipv4.tcp_rmem
+	// on Linux, for predictable memory usage under load.
+	//
+	// Setting SO_RCVBUF explicitly disables the kernel receive buffer
+	// autotuning for the socket and the connections accepted on it,
+	// so the buffers never grow beyond the default. This limits
+	// the throughput of connections with a large bandwidth-delay product.
+	//
+	// NewListener fails with an error wrapping ErrUnsupportedOption
+	// if DisableRecvAutotune is set on Windows.
+	DisableRecvAutotune bool
+
+	// KeepAliveConfig configures TCP keep-alive of the accepted connections
+	// with the semantics of net.KeepAliveConfig: zero fields are set
+	// to the defaults of the net package and negative fields are left
+	// unchanged. Keep-alive is disabled if Enable is false.
+	// The type is defined by the package for Go versions older than 1.23,
+	// see KeepAliveConfigFromNet for using net.KeepAliveConfig.
+	//
+	// The options are set on every accepted connection, since the net
+	// package overrides keep-alive of the accepted connections anyway.
+	// The timers are system-wide on OpenBSD, so only Enable is honored
+	// there. It isn't supported on Plan 9.
+	KeepAliveConfig *KeepAliveConfig
+
+	// KeepAlive enables SO_KEEPALIVE on the listening socket, so the sockets
+	// accepted by the kernel inherit it together with KeepAliveIdle,
+	// KeepAliveInterval and KeepAliveCount. This covers the consumers
+	// accepting from the listener fd directly, e.g. after passing it
+	// to another process.
+	//
+	// The net package overrides keep-alive of the connections accepted
+	// by the returned listener, so the options are set on them too
+	// unless KeepAliveConfig is set. Zero options are left as set
+	// by the net package then, i.e. 15 seconds for the timers.
+	KeepAlive bool
+
+	// KeepAliveIdle is the time the connection must be idle before
+	// the first keep-alive probe is sent if KeepAlive is set.
+	// TCP_KEEPIDLE is set, or TCP_KEEPALIVE on macOS. It is rounded up
+	// to seconds. The system default is left if it is zero.
+	KeepAliveIdle time.Duration
+
+	// KeepAliveInterval is the time between keep-alive probes if KeepAlive
+	// is set. It is rounded up to seconds. The system default is left
+	// if it is zero.
+	KeepAliveInterval time.Duration
+
+	// KeepAliveCount is the maximum number of unanswered keep-alive probes
+	// before dropping the connection if KeepAlive is set. The system default
+	// is left if it is zero.
+	//
+	// The keep-alive timers and the count are system-wide on OpenBSD,
+	// so only SO_KEEPALIVE is set there.
+	KeepAliveCount int
+
+	// OptionOrder is the order of applying the options, for the rare cases
+	// when it matters, e.g. when an option is derived from another one
+	// by the kernel. The options are applied in the fixed order
+	// documented by Explain if it is empty.
+	//
+	// The options from the Options table are named as in OptionSpec.Name,
+	// e.g. "SO_REUSEPORT", and the rest by their Config fields,
+	// i.e. "DisableRecvAutotune", "Transparent", "ServiceClass",
+	// "ReceiveBufferSize", "SendBufferSize", "FlowLabel", "KeepAlive"
+	// and "InitialRTO". All the options to be
+	// set must be named, including SO_REUSEADDR and TCP_NODELAY set
+	// by default, or NewListener fails.
+	//
+	// It is ignored on Windows and Plan 9.
+	OptionOrder []string
+
+	// Control is called with the socket after the options from Config
+	// are applied, but before bind(2), for setting the options Config
+	// lacks, e.g. IP_FREEBIND or SO_BINDTODEVICE. It mirrors
+	// net.ListenConfig.Control: network is tcp4, tcp6 or unix,
+	// and address is the address passed to NewListener.
+	//
+	// The socket is closed and NewListener fails if Control returns
+	// an error. It isn't supported on Plan 9.
+	Control func(network, address string, fd uintptr) error
+
+	// PostListen is called with the listening socket after listen(2)
+	// succeeds, e.g. for registering the socket in an external supervisor.
+	//
+	// The socket is closed and NewListener fails if PostListen returns
+	// an error.
+	PostListen func(fd uintptr) error
+
+	// Trace is called for every syscall made while setting up
+	// the listening socket, e.g. for debugging misbehaving listeners
+	// in production.
+	//
+	// Only option calls are traced on Windows with Go versions older
+	// than 1.25, since the socket is bound by the standard library then.
+	Trace func(r TraceRecord)
+
+	// SingletonLock is the path to a lock file which must be exclusively
+	// flock'ed before binding.
+	//
+	// NewListener fails if another process holds the lock, so at most
+	// a single instance of the service may listen on the host.
+	// The lock is released when the returned listener is closed.
+	//
+	// It isn't supported on Windows.
+	SingletonLock string
+
+	// Logger is used for logging messages. The standard logger is used
+	// by default.
+	Logger Logger
+
+	// LogInspectHint enables logging of a shell command inspecting
+	// the created listener, e.g. `ss -tlnpe 'sport = :8080'`.
+	LogInspectHint bool
+
+	// Register adds the listener to the process-wide registry reported
+	// by ActiveListeners, e.g. for introspecting what the process listens
+	// on or for closing all the listeners on graceful shutdown.
+	//
+	// The registry holds the listener until it is closed.
+	Register bool
+
+	// LoopbackOnly makes NewListener fail unless the address resolves
+	// to a loopback address, i.e. 127.0.0.0/8 or ::1.
+	//
+	// It protects development servers from being exposed by accident,
+	// e.g. when listening on ":8080".
+	LoopbackOnly bool
+
+	// AutoTune derives the settings from the machine when the listener
+	// is created: the backlog from GOMAXPROCS, the memory and somaxconn,
+	// and the socket buffer sizes from the memory unless the kernel
+	// autotunes them, as Linux does. An explicit Backlog, ReceiveBufferSize
+	// and SendBufferSize are kept.
+	//
+	// Every derived value is recorded with its reason in ListenResult.Tuning,
+	// which also suggests the number of NewShardGroup shards.
+	// It is ignored on Windows and Plan 9.
+	AutoTune bool
+}
+
+// Validate checks cfg without creating a socket. It reports options
+// which conflict with each other and returns *MissingCapabilityError
+// for options requiring a privilege the process lacks.
+//
+// NewListener calls Validate before creating the socket.
+func (cfg Config) Validate() error {
+	return cfg.validate()
+}
+
+// validate checks the options which conflict with each other
+// and the privileges the options require.
+func (cfg *Config) validate() error {
+	if cfg.ReusePort && cfg.ReusePortLB {
+		return errors.New("ReusePort and ReusePortLB cannot be enabled simultaneously")
+	}
+	if cfg.DisableRecvAutotune && cfg.ReceiveBufferSize > 0 {
+		return errors.New("DisableRecvAutotune and ReceiveBufferSize cannot be set simultaneously")
+	}
+	if err := cfg.ServiceClass.validate(); err != nil {
+		return err
+	}
+	if cfg.UnixMode&^os.ModePerm != 0 {
+		return fmt.Errorf("UnixMode %s may contain only permission bits", cfg.UnixMode)
+	}
+	if err := cfg.validateOptionOrder(); err != nil {
+		return err
+	}
+	return cfg.checkCapabilities()
+}
+
+// checkLoopback returns an error if LoopbackOnly is set and ip
+// isn't a loopback address.
+func (cfg *Config) checkLoopback(ip net.IP, addr string) error {
+	if cfg.LoopbackOnly && !ip.IsLoopback() {
+		return fmt.Errorf("cannot listen on %q: %s isn't a loopback address", addr, ip)
+	}
+	return nil
+}
+
+// resolveLoopback resolves addr for the platforms without getSockaddr
+// and checks it with checkLoopback if LoopbackOnly is set.
+// It returns the resolved address to listen on, so the address isn't
+// resolved again to something else.
+func (cfg *Config) resolveLoopback(ctx context.Context, network, addr string) (string, error) {
+	if !cfg.LoopbackOnly {
+		return addr, nil
+	}
+	tcpAddr, err := resolveTCPAddr(ctx, network, addr)
+	if err != nil {
+		return "", err
+	}
+	ip := tcpAddr.IP
+	if ip == nil {
+		ip = net.IPv6unspecified
+	}
+	if err = cfg.checkLoopback(ip, addr); err != nil {
+		return "", err
+	}
+	return tcpAddr.String(), nil
+}
+
+// wrapListener wraps ln with the listeners altering the accepted
+// connections according to cfg.
+func (cfg *Config) wrapListener(ln net.Listener) net.Listener {
+	if cfg.DeferUntilData {
+		ln = EmulateDeferAccept(ln, DefaultDeferUntilDataTimeout)
+	}
+	if !cfg.UnmapV4 && cfg.AcceptReadTimeout <= 0 && cfg.KeepAliveConfig == nil && !cfg.KeepAlive {
+		return ln
+	}
+	cl := &configListener{
+		Listener:    ln,
+		unmapV4:     cfg.UnmapV4,
+		readTimeout: cfg.AcceptReadTimeout,
+	}
+	if cfg.KeepAliveConfig != nil {
+		// Copy the config, so later changes by the caller
+		// don't race with Accept.
+		ka := *cfg.KeepAliveConfig
+		cl.keepAlive = &ka
+	} else if cfg.KeepAlive {
+		cl.keepAlive = cfg.listenerKeepAlive()
+	}
+	return cl
+}
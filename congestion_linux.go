@@ -0,0 +1,24 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+const availableCongestionControlPath = "/proc/sys/net/ipv4/tcp_available_congestion_control"
+
+// AvailableCongestionControls returns the names of the TCP congestion
+// control algorithms loaded into the kernel, e.g. "reno" and "cubic".
+//
+// Algorithms built as modules are listed only after the module is loaded,
+// e.g. with `modprobe tcp_bbr`.
+func AvailableCongestionControls() ([]string, error) {
+	data, err := ioutil.ReadFile(availableCongestionControlPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read available congestion controls: %s", err)
+	}
+	return strings.Fields(string(data)), nil
+}
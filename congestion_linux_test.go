@@ -0,0 +1,21 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestAvailableCongestionControls(t *testing.T) {
+	names, err := AvailableCongestionControls()
+	if err != nil {
+		t.Skipf("cannot obtain congestion controls: %s", err)
+	}
+	// reno is always built into the kernel.
+	for _, name := range names {
+		if name == "reno" {
+			return
+		}
+	}
+	t.Fatalf("reno is missing in the available congestion controls %q", names)
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package tcplisten
+
+// AvailableCongestionControls returns the names of the TCP congestion
+// control algorithms loaded into the kernel, e.g. "reno" and "cubic".
+//
+// It is supported only on Linux.
+func AvailableCongestionControls() ([]string, error) {
+	return nil, &UnsupportedError{
+		Op:     "AvailableCongestionControls",
+		Reason: "the list of congestion controls is exposed only by Linux procfs",
+	}
+}
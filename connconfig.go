@@ -0,0 +1,86 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// ConnConfig provides options to set on accepted connections.
+type ConnConfig struct {
+	// KernelReadTimeout sets SO_RCVTIMEO on the connection's socket.
+	//
+	// The timeout doesn't affect Go I/O, since the runtime works with
+	// non-blocking sockets and relies on deadlines instead. It is intended
+	// for sockets handed off to non-Go consumers performing blocking reads,
+	// e.g. via File or fd passing.
+	KernelReadTimeout time.Duration
+
+	// KernelWriteTimeout sets SO_SNDTIMEO on the connection's socket.
+	//
+	// See KernelReadTimeout for details.
+	KernelWriteTimeout time.Duration
+
+	// MaxPacingRate sets SO_MAX_PACING_RATE in bytes per second,
+	// overriding the rate inherited from Config.MaxPacingRate.
+	//
+	// See Config.MaxPacingRate for details.
+	MaxPacingRate uint64
+
+	// Trace is called for every setsockopt made by Apply.
+	Trace func(r TraceRecord)
+}
+
+// Apply sets the options on the given connection.
+func (cc *ConnConfig) Apply(c net.Conn) error {
+	if cc.KernelReadTimeout <= 0 && cc.KernelWriteTimeout <= 0 && cc.MaxPacingRate == 0 {
+		// Avoid obtaining the file descriptor, which allocates,
+		// when there is nothing to set.
+		return nil
+	}
+	return withFd(c, func(fd uintptr) error {
+		return cc.fdSetup(fd)
+	})
+}
+
+func (cc *ConnConfig) fdSetup(fd uintptr) error {
+	tr := tracer(cc.Trace)
+	if cc.KernelReadTimeout > 0 {
+		if err := setKernelTimeout(fd, soRcvTimeo, "SO_RCVTIMEO", cc.KernelReadTimeout, tr); err != nil {
+			return fmt.Errorf("cannot set SO_RCVTIMEO: %s", err)
+		}
+	}
+	if cc.KernelWriteTimeout > 0 {
+		if err := setKernelTimeout(fd, soSndTimeo, "SO_SNDTIMEO", cc.KernelWriteTimeout, tr); err != nil {
+			return fmt.Errorf("cannot set SO_SNDTIMEO: %s", err)
+		}
+	}
+	if cc.MaxPacingRate > 0 {
+		if err := setMaxPacingRate(fd, cc.MaxPacingRate, tr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithConnConfig returns a listener applying cc to connections accepted
+// from ln.
+//
+// Connections which cannot be configured are closed, and Accept returns
+// a temporary net.Error for them.
+func WithConnConfig(ln net.Listener, cc ConnConfig) net.Listener {
+	cl := composeListener(ln)
+	cl.connConfig = &cc
+	return cl
+}
+
+type connConfigError struct {
+	err error
+}
+
+func (e *connConfigError) Error() string {
+	return "cannot configure accepted connection: " + e.err.Error()
+}
+
+func (e *connConfigError) Timeout() bool   { return false }
+func (e *connConfigError) Temporary() bool { return true }
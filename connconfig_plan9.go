@@ -0,0 +1,17 @@
+// +build plan9
+
+package tcplisten
+
+import (
+	"time"
+)
+
+const (
+	soRcvTimeo = 0
+	soSndTimeo = 0
+)
+
+func setKernelTimeout(fd uintptr, opt int, option string, d time.Duration, tr tracer) error {
+	// Plan 9 has no socket options.
+	return ErrUnsupportedOption
+}
@@ -0,0 +1,54 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestConnConfigKernelTimeouts(t *testing.T) {
+	base, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln := WithConnConfig(base, ConnConfig{
+		KernelReadTimeout:  1500 * time.Millisecond,
+		KernelWriteTimeout: 250 * time.Millisecond,
+	})
+	defer ln.Close()
+
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial listener: %s", err)
+	}
+	defer c.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	defer sc.Close()
+
+	for _, tc := range []struct {
+		opt      int
+		expected time.Duration
+	}{
+		{syscall.SO_RCVTIMEO, 1500 * time.Millisecond},
+		{syscall.SO_SNDTIMEO, 250 * time.Millisecond},
+	} {
+		var tv syscall.Timeval
+		l := uint32(unsafe.Sizeof(tv))
+		if err = withFd(sc, func(fd uintptr) error {
+			return getsockopt(int(fd), syscall.SOL_SOCKET, tc.opt, unsafe.Pointer(&tv), &l)
+		}); err != nil {
+			t.Fatalf("cannot read timeout %d: %s", tc.opt, err)
+		}
+		// The kernel stores timeouts in jiffies.
+		if d := time.Duration(tv.Nano()); d < tc.expected || d > tc.expected+10*time.Millisecond {
+			t.Fatalf("unexpected timeout %d: %s. Expecting %s", tc.opt, d, tc.expected)
+		}
+	}
+}
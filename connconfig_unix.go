@@ -0,0 +1,26 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"syscall"
+	"time"
+)
+
+const (
+	soRcvTimeo = syscall.SO_RCVTIMEO
+	soSndTimeo = syscall.SO_SNDTIMEO
+)
+
+func setKernelTimeout(fd uintptr, opt int, option string, d time.Duration, tr tracer) error {
+	tv := syscall.NsecToTimeval(d.Nanoseconds())
+	err := syscall.SetsockoptTimeval(int(fd), syscall.SOL_SOCKET, opt, &tv)
+	tr.trace(TraceRecord{
+		Call:   "setsockopt",
+		Level:  syscall.SOL_SOCKET,
+		Option: option,
+		Value:  int(d / time.Microsecond),
+		Err:    err,
+	})
+	return err
+}
@@ -0,0 +1,30 @@
+// +build windows
+
+package tcplisten
+
+import (
+	"syscall"
+	"time"
+)
+
+const (
+	soRcvTimeo = 0x1006
+	soSndTimeo = 0x1005
+)
+
+func setKernelTimeout(fd uintptr, opt int, option string, d time.Duration, tr tracer) error {
+	// Windows expects DWORD milliseconds.
+	ms := d / time.Millisecond
+	if ms == 0 {
+		ms = 1
+	}
+	err := syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, opt, int(ms))
+	tr.trace(TraceRecord{
+		Call:   "setsockopt",
+		Level:  syscall.SOL_SOCKET,
+		Option: option,
+		Value:  int(ms * 1000),
+		Err:    err,
+	})
+	return err
+}
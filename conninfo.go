@@ -0,0 +1,45 @@
+package tcplisten
+
+import (
+	"time"
+)
+
+// ConnInfo contains TCP statistics of a connection returned by GetConnInfo.
+//
+// Fields the platform or the kernel doesn't report are zero.
+type ConnInfo struct {
+	// RTT is the smoothed round-trip time.
+	RTT time.Duration
+
+	// MinRTT is the minimum round-trip time observed on the connection.
+	// It requires Linux 4.6 or newer.
+	MinRTT time.Duration
+
+	// MSS is the maximum segment size for sending.
+	MSS int
+
+	// CongestionWindow is the congestion window in bytes.
+	CongestionWindow int
+
+	// BytesSent is the number of bytes sent, including retransmissions.
+	// It requires Linux 4.19 or newer.
+	BytesSent uint64
+
+	// BytesReceived is the number of bytes received.
+	// It requires Linux 4.1 or newer.
+	BytesReceived uint64
+
+	// BytesRetransmitted is the number of bytes retransmitted.
+	// It requires Linux 4.19 or newer.
+	BytesRetransmitted uint64
+
+	// ReceiverWindowLimited is the time sending has been limited
+	// by the receive window of the peer. It requires Linux 4.10 or newer,
+	// or Windows 10 1703 or newer.
+	ReceiverWindowLimited time.Duration
+
+	// SendBufferLimited is the time sending has been limited
+	// by the send buffer. It requires Linux 4.10 or newer,
+	// or Windows 10 1703 or newer.
+	SendBufferLimited time.Duration
+}
@@ -0,0 +1,65 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// tcpInfoExt is struct tcp_info including the fields appended
+// after the ones in syscall.TCPInfo. Older kernels fill only a prefix
+// of the struct, leaving the rest zero.
+type tcpInfoExt struct {
+	syscall.TCPInfo
+
+	pacingRate    uint64
+	maxPacingRate uint64
+	bytesAcked    uint64
+	bytesReceived uint64
+	segsOut       uint32
+	segsIn        uint32
+	notsentBytes  uint32
+	minRTT        uint32
+	dataSegsIn    uint32
+	dataSegsOut   uint32
+	deliveryRate  uint64
+	busyTime      uint64
+	rwndLimited   uint64
+	sndbufLimited uint64
+	delivered     uint32
+	deliveredCE   uint32
+	bytesSent     uint64
+	bytesRetrans  uint64
+}
+
+// GetConnInfo returns TCP statistics of the connection from TCP_INFO.
+//
+// It works on any connection exposing its file descriptor
+// with SyscallConn, e.g. *net.TCPConn.
+func GetConnInfo(c net.Conn) (ConnInfo, error) {
+	var ci ConnInfo
+	err := withFd(c, func(fd uintptr) error {
+		var ti tcpInfoExt
+		l := uint32(unsafe.Sizeof(ti))
+		if err := getsockopt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_INFO, unsafe.Pointer(&ti), &l); err != nil {
+			return fmt.Errorf("cannot obtain TCP_INFO: %s", err)
+		}
+		ci = ConnInfo{
+			RTT:                   time.Duration(ti.Rtt) * time.Microsecond,
+			MinRTT:                time.Duration(ti.minRTT) * time.Microsecond,
+			MSS:                   int(ti.Snd_mss),
+			CongestionWindow:      int(ti.Snd_cwnd) * int(ti.Snd_mss),
+			BytesSent:             ti.bytesSent,
+			BytesReceived:         ti.bytesReceived,
+			BytesRetransmitted:    ti.bytesRetrans,
+			ReceiverWindowLimited: time.Duration(ti.rwndLimited) * time.Microsecond,
+			SendBufferLimited:     time.Duration(ti.sndbufLimited) * time.Microsecond,
+		}
+		return nil
+	})
+	return ci, err
+}
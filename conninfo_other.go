@@ -0,0 +1,17 @@
+// +build !linux,!windows
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// GetConnInfo returns TCP statistics of the connection.
+//
+// It is supported only on Linux and Windows.
+func GetConnInfo(c net.Conn) (ConnInfo, error) {
+	return ConnInfo{}, &UnsupportedError{
+		Op:     "GetConnInfo",
+		Reason: "TCP statistics are exposed only by TCP_INFO on Linux and SIO_TCP_INFO on Windows",
+	}
+}
@@ -0,0 +1,45 @@
+// +build linux windows
+
+package tcplisten
+
+import (
+	"net"
+	"testing"
+)
+
+func TestGetConnInfo(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	defer sc.Close()
+
+	if _, err = c.Write([]byte("ping")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err = sc.Read(buf); err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+
+	ci, err := GetConnInfo(sc)
+	if err != nil {
+		t.Fatalf("cannot obtain connection info: %s", err)
+	}
+	if ci.MSS <= 0 || ci.CongestionWindow < ci.MSS {
+		t.Fatalf("unexpected MSS %d and congestion window %d", ci.MSS, ci.CongestionWindow)
+	}
+	if ci.BytesReceived != 0 && ci.BytesReceived != 4 {
+		t.Fatalf("unexpected number of received bytes %d. Expecting 4", ci.BytesReceived)
+	}
+}
@@ -0,0 +1,119 @@
+// +build windows
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// sioTCPInfo is SIO_TCP_INFO, i.e. _WSAIORW(IOC_VENDOR, 39).
+const sioTCPInfo = 0xD8000027
+
+// tcpInfoV0 is TCP_INFO_v0 from mstcpip.h.
+type tcpInfoV0 struct {
+	State             uint32
+	Mss               uint32
+	ConnectionTimeMs  uint64
+	TimestampsEnabled uint8
+	_                 [3]byte
+	RttUs             uint32
+	MinRttUs          uint32
+	BytesInFlight     uint32
+	Cwnd              uint32
+	SndWnd            uint32
+	RcvWnd            uint32
+	RcvBuf            uint32
+	BytesOut          uint64
+	BytesIn           uint64
+	BytesReordered    uint32
+	BytesRetrans      uint32
+	FastRetrans       uint32
+	DupAcksIn         uint32
+	TimeoutEpisodes   uint32
+	SynRetrans        uint8
+	_                 [3]byte
+}
+
+// tcpInfoV1 is TCP_INFO_v1 from mstcpip.h.
+type tcpInfoV1 struct {
+	tcpInfoV0
+
+	SndLimTransRwin uint32
+	SndLimTimeRwin  uint32
+	SndLimBytesRwin uint64
+	SndLimTransCwnd uint32
+	SndLimTimeCwnd  uint32
+	SndLimBytesCwnd uint64
+	SndLimTransSnd  uint32
+	SndLimTimeSnd   uint32
+	SndLimBytesSnd  uint64
+}
+
+// tcpInfoV1Build is the first Windows build supporting TCP_INFO_v1,
+// i.e. Windows 10 1703.
+const tcpInfoV1Build = 15063
+
+var (
+	windowsBuildOnce sync.Once
+	windowsBuild     uint32
+)
+
+// getWindowsBuild returns the build number of Windows. Unlike GetVersion,
+// RtlGetNtVersionNumbers isn't affected by the application manifest.
+func getWindowsBuild() uint32 {
+	windowsBuildOnce.Do(func() {
+		proc := syscall.NewLazyDLL("ntdll.dll").NewProc("RtlGetNtVersionNumbers")
+		if proc.Find() != nil {
+			return
+		}
+		var major, minor, build uint32
+		proc.Call(uintptr(unsafe.Pointer(&major)), uintptr(unsafe.Pointer(&minor)), uintptr(unsafe.Pointer(&build)))
+		windowsBuild = build & 0xFFFF
+	})
+	return windowsBuild
+}
+
+// GetConnInfo returns TCP statistics of the connection from SIO_TCP_INFO.
+//
+// It requires Windows 10 1703 or newer. The fields reported only
+// by TCP_INFO_v1 are zero on older builds.
+//
+// It works on any connection exposing its socket with SyscallConn,
+// e.g. *net.TCPConn.
+func GetConnInfo(c net.Conn) (ConnInfo, error) {
+	var ci ConnInfo
+	err := withFd(c, func(fd uintptr) error {
+		var (
+			ti      tcpInfoV1
+			version uint32
+			size    = uint32(unsafe.Sizeof(ti.tcpInfoV0))
+			n       uint32
+		)
+		if getWindowsBuild() >= tcpInfoV1Build {
+			version, size = 1, uint32(unsafe.Sizeof(ti))
+		}
+		err := syscall.WSAIoctl(syscall.Handle(fd), sioTCPInfo, (*byte)(unsafe.Pointer(&version)), uint32(unsafe.Sizeof(version)),
+			(*byte)(unsafe.Pointer(&ti)), size, &n, nil, 0)
+		if err != nil {
+			return fmt.Errorf("cannot obtain SIO_TCP_INFO v%d: %s", version, err)
+		}
+		ci = ConnInfo{
+			RTT:                   time.Duration(ti.RttUs) * time.Microsecond,
+			MinRTT:                time.Duration(ti.MinRttUs) * time.Microsecond,
+			MSS:                   int(ti.Mss),
+			CongestionWindow:      int(ti.Cwnd),
+			BytesSent:             ti.BytesOut,
+			BytesReceived:         ti.BytesIn,
+			BytesRetransmitted:    uint64(ti.BytesRetrans),
+			ReceiverWindowLimited: time.Duration(ti.SndLimTimeRwin) * time.Millisecond,
+			SendBufferLimited:     time.Duration(ti.SndLimTimeSnd) * time.Millisecond,
+		}
+		return nil
+	})
+	return ci, err
+}
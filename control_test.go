@@ -0,0 +1,51 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestConfigControl(t *testing.T) {
+	const size = 64 << 10
+	var called string
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{
+		Control: func(network, address string, fd uintptr) error {
+			called = network + " " + address
+			return syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, size)
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	if called != "tcp4 127.0.0.1:0" {
+		t.Fatalf("unexpected Control arguments %q. Expecting %q", called, "tcp4 127.0.0.1:0")
+	}
+	var v int
+	err = withFd(ln, func(fd uintptr) error {
+		var err error
+		v, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("cannot obtain SO_RCVBUF: %s", err)
+	}
+	// Linux doubles the value.
+	if v != size && v != 2*size {
+		t.Fatalf("unexpected SO_RCVBUF %d. Expecting %d", v, size)
+	}
+
+	errHook := errors.New("hook error")
+	_, err = NewListener("tcp4", "127.0.0.1:0", Config{
+		Control: func(network, address string, fd uintptr) error {
+			return errHook
+		},
+	})
+	if !errors.Is(err, errHook) || !strings.Contains(err.Error(), "127.0.0.1:0") {
+		t.Fatalf("unexpected error %v. Expecting %v mentioning the address", err, errHook)
+	}
+}
@@ -0,0 +1,223 @@
+package tcplisten
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// NamedListener is a listener shown by DebugHandler under the given name.
+type NamedListener struct {
+	Name     string
+	Listener net.Listener
+}
+
+// ListenerState is the state of a listener reported by DebugHandler.
+type ListenerState struct {
+	// Name is the name of the listener. Listeners from ActiveListeners
+	// are named by their address.
+	Name string `json:"name"`
+
+	// Network is the network the listener has been created for.
+	// It is empty for listeners not created with Config.Register.
+	Network string `json:"network,omitempty"`
+
+	// Addr is the address the listener is bound to.
+	Addr string `json:"addr"`
+
+	// Config contains the non-zero fields of the config the listener
+	// has been created with. Hooks and loggers are omitted.
+	// It is empty for listeners not created with Config.Register.
+	Config map[string]interface{} `json:"config,omitempty"`
+
+	// Backlog is the backlog passed to listen(2).
+	// It is zero for listeners not created with Config.Register.
+	Backlog int `json:"backlog,omitempty"`
+
+	// Options contains the values of the options known to the package
+	// read back with GetOption.
+	Options map[string]int `json:"options,omitempty"`
+
+	// QueueLen and QueueMax are the current and the maximum length
+	// of the accept queue reported by AcceptQueueLen.
+	QueueLen *int `json:"queue_len,omitempty"`
+	QueueMax *int `json:"queue_max,omitempty"`
+
+	// Accepted is the number of accepted connections. It is reported
+	// for listeners returned by PublishExpvar.
+	Accepted *uint64 `json:"accepted,omitempty"`
+
+	// Tracked is the number of open connections. It is reported
+	// for listeners returned by TrackConns.
+	Tracked *int `json:"tracked,omitempty"`
+}
+
+// DebugHandler returns an http.Handler dumping the state of the given
+// listeners and of the listeners created with Config.Register,
+// e.g. for mounting under an admin mux during incidents.
+//
+// The listeners from ActiveListeners are looked up on every request,
+// so the ones created after the handler appear automatically.
+// The state is rendered as JSON, or as human-readable text
+// if the request has the "format=text" query arg.
+func DebugHandler(listeners ...NamedListener) http.Handler {
+	named := append([]NamedListener(nil), listeners...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		states := debugStates(named, ActiveListeners())
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			writeDebugText(w, states)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(states)
+	})
+}
+
+// debugStates returns the states of the named listeners followed by
+// the registered ones, which haven't been named. Listeners are matched
+// by their file descriptors, so wrapped listeners are matched too.
+func debugStates(named []NamedListener, infos []ListenerInfo) []ListenerState {
+	states := make([]ListenerState, 0, len(named)+len(infos))
+	seen := make([]bool, len(infos))
+	for _, nl := range named {
+		var info *ListenerInfo
+		if fd, ok := listenerFd(nl.Listener); ok {
+			for i := range infos {
+				if ifd, ok := listenerFd(infos[i].Listener); ok && ifd == fd {
+					info = &infos[i]
+					seen[i] = true
+				}
+			}
+		}
+		states = append(states, listenerState(nl.Name, nl.Listener, info))
+	}
+	for i := range infos {
+		if !seen[i] {
+			states = append(states, listenerState(infos[i].Addr.String(), infos[i].Listener, &infos[i]))
+		}
+	}
+	return states
+}
+
+// listenerFd returns the file descriptor of the listener if it exposes one.
+func listenerFd(ln net.Listener) (uintptr, bool) {
+	var lfd uintptr
+	err := withFd(ln, func(fd uintptr) error {
+		lfd = fd
+		return nil
+	})
+	return lfd, err == nil
+}
+
+func listenerState(name string, ln net.Listener, info *ListenerInfo) ListenerState {
+	st := ListenerState{
+		Name: name,
+		Addr: ln.Addr().String(),
+	}
+	if info != nil {
+		st.Network = info.Network
+		st.Backlog = info.Backlog
+		st.Config = configFields(&info.Config)
+	}
+	for _, spec := range Options() {
+		v, err := GetOption(ln, spec.Option)
+		if err != nil {
+			continue
+		}
+		if st.Options == nil {
+			st.Options = make(map[string]int)
+		}
+		st.Options[spec.Name] = v
+	}
+	if cur, max, err := AcceptQueueLen(ln); err == nil {
+		st.QueueLen, st.QueueMax = &cur, &max
+	}
+	if ac, ok := ln.(interface{ acceptedCount() uint64 }); ok {
+		n := ac.acceptedCount()
+		st.Accepted = &n
+	}
+	if tl, ok := ln.(*TrackedListener); ok {
+		n := tl.Len()
+		st.Tracked = &n
+	}
+	return st
+}
+
+// configFields returns the non-zero fields of cfg except for hooks
+// and loggers. Stringers, e.g. durations, are rendered as strings.
+func configFields(cfg *Config) map[string]interface{} {
+	fields := make(map[string]interface{})
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Func, reflect.Interface:
+			continue
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			if f.IsNil() {
+				continue
+			}
+		default:
+			if f.Interface() == reflect.Zero(f.Type()).Interface() {
+				continue
+			}
+		}
+		if s, ok := f.Interface().(fmt.Stringer); ok {
+			fields[v.Type().Field(i).Name] = s.String()
+		} else {
+			fields[v.Type().Field(i).Name] = f.Interface()
+		}
+	}
+	return fields
+}
+
+func writeDebugText(w http.ResponseWriter, states []ListenerState) {
+	for _, st := range states {
+		if st.Network != "" {
+			fmt.Fprintf(w, "%s (%s %s)\n", st.Name, st.Network, st.Addr)
+		} else {
+			fmt.Fprintf(w, "%s (%s)\n", st.Name, st.Addr)
+		}
+		if st.Backlog > 0 {
+			fmt.Fprintf(w, "  backlog: %d\n", st.Backlog)
+		}
+		if st.QueueLen != nil {
+			fmt.Fprintf(w, "  accept queue: %d/%d\n", *st.QueueLen, *st.QueueMax)
+		}
+		if st.Accepted != nil {
+			fmt.Fprintf(w, "  accepted: %d\n", *st.Accepted)
+		}
+		if st.Tracked != nil {
+			fmt.Fprintf(w, "  tracked: %d\n", *st.Tracked)
+		}
+		writeDebugMap(w, "config", st.Config)
+		options := make(map[string]interface{}, len(st.Options))
+		for k, v := range st.Options {
+			options[k] = v
+		}
+		writeDebugMap(w, "options", options)
+	}
+}
+
+func writeDebugMap(w http.ResponseWriter, title string, m map[string]interface{}) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, m[k]))
+	}
+	fmt.Fprintf(w, "  %s: %s\n", title, strings.Join(pairs, " "))
+}
@@ -0,0 +1,73 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	tl := TrackConns(ln)
+	defer tl.Close()
+	h := DebugHandler(NamedListener{Name: "api", Listener: tl})
+
+	// The listener registered after creating the handler must appear.
+	rln, err := NewListener("tcp4", "127.0.0.1:0", Config{Register: true, Backlog: 16})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer rln.Close()
+
+	c, err := net.Dial("tcp4", tl.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	sc, err := tl.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	defer sc.Close()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	var states []ListenerState
+	if err = json.Unmarshal(w.Body.Bytes(), &states); err != nil {
+		t.Fatalf("cannot parse response %q: %s", w.Body, err)
+	}
+	var api, registered *ListenerState
+	for i := range states {
+		switch states[i].Addr {
+		case tl.Addr().String():
+			api = &states[i]
+		case rln.Addr().String():
+			registered = &states[i]
+		}
+	}
+	if api == nil || registered == nil {
+		t.Fatalf("unexpected states %+v. Expecting %s and %s", states, tl.Addr(), rln.Addr())
+	}
+	if api.Name != "api" || api.Tracked == nil || *api.Tracked != 1 {
+		t.Fatalf("unexpected state %+v of the named listener. Expecting a single tracked connection", api)
+	}
+	if _, ok := api.Options["TCP_NODELAY"]; !ok {
+		t.Fatalf("unexpected options %v. Expecting TCP_NODELAY", api.Options)
+	}
+	if registered.Config["Backlog"] != float64(16) || registered.Config["Register"] != true {
+		t.Fatalf("unexpected state %+v of the registered listener", registered)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/?format=text", nil))
+	if !strings.Contains(w.Body.String(), "api (") || !strings.Contains(w.Body.String(), "Backlog=16") {
+		t.Fatalf("unexpected text response %q", w.Body)
+	}
+}
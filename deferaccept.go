@@ -0,0 +1,153 @@
+package tcplisten
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultDeferUntilDataTimeout is the time Config.DeferUntilData waits
+// for the first bytes of a connection before closing it.
+const DefaultDeferUntilDataTimeout = 10 * time.Second
+
+// EmulateDeferAccept returns a listener emulating DeferAccept on platforms
+// where TCP_DEFER_ACCEPT is unavailable, e.g. macOS and OpenBSD.
+//
+// Accept returns connections only after they become readable.
+// Connections which send nothing during timeout, or which are closed
+// by the peer before sending anything, are closed. Readability is detected
+// by peeking the receive queue, so no data is consumed.
+//
+// Peeking isn't supported on Plan 9, so connections are returned
+// as soon as they are accepted there.
+func EmulateDeferAccept(ln net.Listener, timeout time.Duration) net.Listener {
+	dl := &deferAcceptListener{
+		Listener: ln,
+		timeout:  timeout,
+		ch:       make(chan acceptResult),
+		failed:   make(chan struct{}),
+		done:     make(chan struct{}),
+		pending:  make(map[net.Conn]struct{}),
+	}
+	go dl.acceptLoop()
+	return dl
+}
+
+type deferAcceptListener struct {
+	net.Listener
+	timeout time.Duration
+
+	ch chan acceptResult
+
+	// failed is closed after the accept loop stops on err.
+	failed chan struct{}
+	err    error
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// pending holds the connections waiting for data, so Close
+	// can close them.
+	mu      sync.Mutex
+	pending map[net.Conn]struct{}
+}
+
+func (ln *deferAcceptListener) acceptLoop() {
+	for {
+		c, err := ln.Listener.Accept()
+		if err != nil {
+			// Errors are passed to Accept callers, and the loop
+			// stops on the first non-temporary one.
+			select {
+			case ln.ch <- acceptResult{nil, err}:
+			case <-ln.done:
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			ln.err = err
+			close(ln.failed)
+			return
+		}
+		go ln.wait(c)
+	}
+}
+
+// wait passes c to Accept once it becomes readable.
+func (ln *deferAcceptListener) wait(c net.Conn) {
+	ln.mu.Lock()
+	select {
+	case <-ln.done:
+		ln.mu.Unlock()
+		c.Close()
+		return
+	default:
+	}
+	ln.pending[c] = struct{}{}
+	ln.mu.Unlock()
+
+	ok := waitReadable(c, ln.timeout)
+	ln.mu.Lock()
+	delete(ln.pending, c)
+	ln.mu.Unlock()
+	if !ok {
+		c.Close()
+		return
+	}
+	select {
+	case ln.ch <- acceptResult{c, nil}:
+	case <-ln.done:
+		c.Close()
+	}
+}
+
+// waitReadable reports whether c has data to read within timeout.
+func waitReadable(c net.Conn, timeout time.Duration) bool {
+	if err := c.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false
+	}
+	defer c.SetReadDeadline(time.Time{})
+
+	var b [1]byte
+	n, err := peek(c, b[:])
+	if err == ErrUnsupportedOption {
+		return true
+	}
+	return err == nil && n > 0
+}
+
+// Accept waits for and returns the next connection with data to read.
+//
+// After the underlying listener fails, Accept keeps returning its error.
+func (ln *deferAcceptListener) Accept() (net.Conn, error) {
+	select {
+	case r := <-ln.ch:
+		return r.c, r.err
+	case <-ln.failed:
+		return nil, ln.err
+	case <-ln.done:
+		return nil, ErrListenerClosed
+	}
+}
+
+// Close closes the underlying listener and the connections waiting
+// for data.
+func (ln *deferAcceptListener) Close() error {
+	var err error
+	ln.closeOnce.Do(func() {
+		ln.mu.Lock()
+		close(ln.done)
+		for c := range ln.pending {
+			c.Close()
+		}
+		ln.mu.Unlock()
+		err = ln.Listener.Close()
+	})
+	return err
+}
+
+func (ln *deferAcceptListener) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(ln.Listener)
+}
@@ -0,0 +1,154 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEmulateDeferAccept(t *testing.T) {
+	tln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln := EmulateDeferAccept(tln, 200*time.Millisecond)
+	defer ln.Close()
+
+	silent, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer silent.Close()
+
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	time.Sleep(50 * time.Millisecond)
+	if _, err = c.Write([]byte("hello")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	c.(*net.TCPConn).CloseWrite()
+
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	defer sc.Close()
+	if sc.RemoteAddr().String() != c.LocalAddr().String() {
+		t.Fatalf("unexpected connection from %s. Expecting %s", sc.RemoteAddr(), c.LocalAddr())
+	}
+	data, err := ioutil.ReadAll(sc)
+	if err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("unexpected data %q. Expecting %q", data, "hello")
+	}
+
+	// The silent connection must be closed after the timeout.
+	silent.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err = silent.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("expecting the silent connection to be closed")
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatalf("the silent connection hasn't been closed")
+	}
+
+	ln.Close()
+	if _, err = ln.Accept(); err != ErrListenerClosed {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrListenerClosed)
+	}
+}
+
+func TestConfigDeferUntilData(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{DeferUntilData: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	silent, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer silent.Close()
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	time.Sleep(50 * time.Millisecond)
+	if _, err = c.Write([]byte("hello")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	defer sc.Close()
+	if sc.RemoteAddr().String() != c.LocalAddr().String() {
+		t.Fatalf("unexpected connection from %s. Expecting %s", sc.RemoteAddr(), c.LocalAddr())
+	}
+}
+
+func TestEmulateDeferAcceptClose(t *testing.T) {
+	tln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln := EmulateDeferAccept(tln, time.Hour)
+
+	silent, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer silent.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// Close must close the connection waiting for data.
+	ln.Close()
+	silent.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err = silent.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("expecting the waiting connection to be closed")
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatalf("the waiting connection hasn't been closed")
+	}
+}
+
+func TestEmulateDeferAcceptListenerError(t *testing.T) {
+	tln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln := EmulateDeferAccept(tln, time.Second)
+	defer ln.Close()
+
+	// Closing the underlying listener must stop the accept loop, and
+	// Accept must keep returning the error instead of blocking.
+	tln.Close()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err1 := ln.Accept()
+		_, err2 := ln.Accept()
+		if err1 == nil || err2 == nil {
+			errCh <- nil
+			return
+		}
+		errCh <- err2
+	}()
+	select {
+	case err = <-errCh:
+		if err == nil || err == ErrListenerClosed {
+			t.Fatalf("unexpected error %v. Expecting the error of the underlying listener", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Accept blocks after the underlying listener is closed")
+	}
+}
@@ -0,0 +1,158 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	netlinkSockDiag   = 0x4
+	sockDiagByFamily  = 0x14
+	tcpListenState    = 0xa
+	inetDiagReqV2Size = 56
+	inetDiagMsgSize   = 72
+)
+
+// nativeEndian is the byte order used by netlink.
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// inetDiagMsg is a parsed struct inet_diag_msg with its attributes.
+type inetDiagMsg struct {
+	family uint8
+	state  uint8
+	sport  int
+	src    net.IP
+	rqueue uint32
+	wqueue uint32
+	uid    uint32
+	inode  uint32
+	attrs  map[uint16][]byte
+}
+
+// inetDiagListeners dumps TCP sockets in LISTEN state of the given family
+// via NETLINK_SOCK_DIAG. ext is the idiag_ext mask of requested attributes.
+func inetDiagListeners(family int, ext uint8) ([]inetDiagMsg, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, netlinkSockDiag)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create sock_diag netlink socket: %s", err)
+	}
+	defer syscall.Close(fd)
+
+	req := make([]byte, syscall.NLMSG_HDRLEN+inetDiagReqV2Size)
+	nativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	nativeEndian.PutUint16(req[4:6], sockDiagByFamily)
+	nativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	nativeEndian.PutUint32(req[8:12], 1)
+	body := req[syscall.NLMSG_HDRLEN:]
+	body[0] = uint8(family)
+	body[1] = syscall.IPPROTO_TCP
+	body[2] = ext
+	nativeEndian.PutUint32(body[4:8], 1<<tcpListenState)
+
+	if err = syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("cannot send sock_diag request: %s", err)
+	}
+
+	var msgs []inetDiagMsg
+	buf := make([]byte, 32*1024)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read sock_diag response: %s", err)
+		}
+		nms, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse sock_diag response: %s", err)
+		}
+		for _, nm := range nms {
+			switch nm.Header.Type {
+			case syscall.NLMSG_DONE:
+				return msgs, nil
+			case syscall.NLMSG_ERROR:
+				if len(nm.Data) >= 4 {
+					if errno := int32(nativeEndian.Uint32(nm.Data[:4])); errno != 0 {
+						return nil, fmt.Errorf("sock_diag request failed: %s", syscall.Errno(-errno))
+					}
+				}
+				return msgs, nil
+			case sockDiagByFamily:
+				m, err := parseInetDiagMsg(nm.Data)
+				if err != nil {
+					return nil, err
+				}
+				msgs = append(msgs, m)
+			}
+		}
+	}
+}
+
+func parseInetDiagMsg(b []byte) (inetDiagMsg, error) {
+	if len(b) < inetDiagMsgSize {
+		return inetDiagMsg{}, fmt.Errorf("truncated inet_diag_msg: %d bytes", len(b))
+	}
+	m := inetDiagMsg{
+		family: b[0],
+		state:  b[1],
+		sport:  int(binary.BigEndian.Uint16(b[4:6])),
+		rqueue: nativeEndian.Uint32(b[56:60]),
+		wqueue: nativeEndian.Uint32(b[60:64]),
+		uid:    nativeEndian.Uint32(b[64:68]),
+		inode:  nativeEndian.Uint32(b[68:72]),
+	}
+	if m.family == syscall.AF_INET {
+		m.src = net.IP(append([]byte(nil), b[8:12]...))
+	} else {
+		m.src = net.IP(append([]byte(nil), b[8:24]...))
+	}
+
+	attrs := b[inetDiagMsgSize:]
+	for len(attrs) >= syscall.SizeofRtAttr {
+		l := int(nativeEndian.Uint16(attrs[0:2]))
+		if l < syscall.SizeofRtAttr || l > len(attrs) {
+			break
+		}
+		if m.attrs == nil {
+			m.attrs = make(map[uint16][]byte)
+		}
+		m.attrs[nativeEndian.Uint16(attrs[2:4])] = attrs[syscall.SizeofRtAttr:l]
+		l = (l + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if l > len(attrs) {
+			break
+		}
+		attrs = attrs[l:]
+	}
+	return m, nil
+}
+
+// listenerSockaddr returns the local address and the address family
+// of the given listener.
+func listenerSockaddr(ln interface{}) (*net.TCPAddr, int, error) {
+	var sa syscall.Sockaddr
+	err := withFd(ln, func(fd uintptr) error {
+		var err error
+		sa, err = syscall.Getsockname(int(fd))
+		return err
+	})
+	if err != nil {
+		return nil, -1, fmt.Errorf("cannot obtain listener address: %s", err)
+	}
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return &net.TCPAddr{IP: net.IP(append([]byte(nil), sa.Addr[:]...)), Port: sa.Port}, syscall.AF_INET, nil
+	case *syscall.SockaddrInet6:
+		return &net.TCPAddr{IP: net.IP(append([]byte(nil), sa.Addr[:]...)), Port: sa.Port}, syscall.AF_INET6, nil
+	default:
+		return nil, -1, fmt.Errorf("unsupported listener address %T", sa)
+	}
+}
@@ -0,0 +1,115 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// NewDualStackListener returns a listener accepting connections from
+// a tcp4 and a tcp6 listener created for the port of addr, so the sockets
+// may be tuned differently, e.g. with larger buffers for IPv6.
+//
+// The host part of addr must be empty. The tcp4 listener is created
+// with cfg4 first, so port 0 is resolved to the same port for both
+// listeners. cfg6.V6Only is forced to V6OnlyEnabled, since otherwise
+// the tcp6 listener would conflict with the tcp4 one.
+//
+// Addr returns the address of the tcp4 listener.
+func NewDualStackListener(addr string, cfg4, cfg6 Config) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host != "" {
+		return nil, fmt.Errorf("cannot create dual-stack listener on %q: the host must be empty", addr)
+	}
+
+	ln4, err := NewListener("tcp4", addr, cfg4)
+	if err != nil {
+		return nil, err
+	}
+	port := ln4.Addr().(*net.TCPAddr).Port
+	cfg6.V6Only = V6OnlyEnabled
+	ln6, err := NewListener("tcp6", net.JoinHostPort("::", strconv.Itoa(port)), cfg6)
+	if err != nil {
+		ln4.Close()
+		return nil, err
+	}
+
+	ln := &dualStackListener{
+		lns:  [2]net.Listener{ln4, ln6},
+		ch:   make(chan acceptResult),
+		done: make(chan struct{}),
+	}
+	for _, l := range ln.lns {
+		go ln.acceptLoop(l)
+	}
+	return ln, nil
+}
+
+type acceptResult struct {
+	c   net.Conn
+	err error
+}
+
+// dualStackListener merges connections accepted from a tcp4
+// and a tcp6 listener.
+type dualStackListener struct {
+	lns [2]net.Listener
+
+	ch        chan acceptResult
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (ln *dualStackListener) acceptLoop(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		select {
+		case ln.ch <- acceptResult{c, err}:
+		case <-ln.done:
+			if c != nil {
+				c.Close()
+			}
+			return
+		}
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+	}
+}
+
+// Accept waits for and returns the next connection accepted
+// by either listener.
+func (ln *dualStackListener) Accept() (net.Conn, error) {
+	select {
+	case r := <-ln.ch:
+		return r.c, r.err
+	case <-ln.done:
+		return nil, ErrListenerClosed
+	}
+}
+
+// Close closes both listeners.
+func (ln *dualStackListener) Close() error {
+	var err error
+	ln.closeOnce.Do(func() {
+		close(ln.done)
+		for _, l := range ln.lns {
+			if cerr := l.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	})
+	return err
+}
+
+// Addr returns the address of the tcp4 listener.
+func (ln *dualStackListener) Addr() net.Addr {
+	return ln.lns[0].Addr()
+}
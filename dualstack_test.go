@@ -0,0 +1,38 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestDualStackListener(t *testing.T) {
+	ln, err := NewDualStackListener(":0", Config{Backlog: 32}, Config{Backlog: 64})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	for _, addr := range []string{"127.0.0.1:" + port, "[::1]:" + port} {
+		cc, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("cannot dial %s: %s", addr, err)
+		}
+		c, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept connection from %s: %s", addr, err)
+		}
+		if c.LocalAddr().String() != addr {
+			t.Fatalf("unexpected local address %s. Expecting %s", c.LocalAddr(), addr)
+		}
+		c.Close()
+		cc.Close()
+	}
+
+	if _, err = NewDualStackListener("127.0.0.1:0", Config{}, Config{}); err == nil {
+		t.Fatalf("expecting error for non-empty host")
+	}
+}
@@ -0,0 +1,36 @@
+package tcplisten
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ErrUnsupportedOption is returned when the requested option or helper
+// cannot be honored on the current platform.
+var ErrUnsupportedOption = errors.New("tcplisten: option is not supported on this platform")
+
+// ErrListenerClosed is returned from Accept on listener wrappers
+// provided by the package after they have been closed.
+var ErrListenerClosed = errors.New("tcplisten: listener closed")
+
+// UnsupportedError is returned by helpers which cannot work on
+// the current platform.
+//
+// errors.Is(err, ErrUnsupportedOption) reports true for UnsupportedError.
+type UnsupportedError struct {
+	// Op is the name of the unsupported helper.
+	Op string
+
+	// Reason explains why Op cannot work on the current platform.
+	Reason string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("tcplisten: %s is not supported on %s/%s: %s", e.Op, runtime.GOOS, runtime.GOARCH, e.Reason)
+}
+
+// Is makes UnsupportedError match ErrUnsupportedOption.
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupportedOption
+}
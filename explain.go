@@ -0,0 +1,77 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// explainer collects the steps of the plan produced by Config.Explain.
+type explainer struct {
+	steps []string
+}
+
+func (e *explainer) addf(format string, args ...interface{}) {
+	e.steps = append(e.steps, fmt.Sprintf(format, args...))
+}
+
+// trace adds the syscall described by r to the plan.
+func (e *explainer) trace(r TraceRecord) {
+	level, ok := levelNames[r.Level]
+	if !ok {
+		level = strconv.Itoa(r.Level)
+	}
+	var step string
+	switch r.Call {
+	case "setsockopt":
+		step = fmt.Sprintf("setsockopt(%s, %s, %d)", level, r.Option, r.Value)
+	case "getsockopt":
+		step = fmt.Sprintf("getsockopt(%s, %s)", level, r.Option)
+	case "sock_diag":
+		step = fmt.Sprintf("sock_diag(%s)", r.Addr)
+	case "unlink":
+		step = fmt.Sprintf("unlink(%s)", r.Addr)
+	default:
+		step = fmt.Sprintf("%s(%s, %d)", r.Call, r.Option, r.Value)
+	}
+	if r.Err != nil {
+		step += " failed: " + r.Err.Error()
+	}
+	e.steps = append(e.steps, step)
+}
+
+// skip adds the option ignored on the current platform to the plan.
+func (e *explainer) skip(name string) {
+	e.addf("skip %s: it is ignored on %s", name, runtime.GOOS)
+}
+
+// fail ends the plan with the error NewListener would fail with.
+func (e *explainer) fail(err error) (string, error) {
+	e.addf("fail: %s", err)
+	return e.String(), err
+}
+
+func (e *explainer) String() string {
+	return strings.Join(e.steps, "\n")
+}
+
+// checkLiteralAddr returns an error unless addr consists of an IP literal
+// or an empty host and a numeric port, so Explain never resolves names.
+func checkLiteralAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if i := strings.LastIndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+	if host != "" && net.ParseIP(host) == nil {
+		return fmt.Errorf("cannot explain listening on %q: the host must be an IP literal", addr)
+	}
+	if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("cannot explain listening on %q: the port must be a number", addr)
+	}
+	return nil
+}
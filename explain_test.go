@@ -0,0 +1,73 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigExplain(t *testing.T) {
+	var traced explainer
+	cfg := Config{
+		ReusePort:   true,
+		DeferAccept: true,
+		FastOpen:    true,
+		Backlog:     128,
+		Trace:       traced.trace,
+	}
+	ln, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	plan, err := cfg.Explain("tcp4", addr)
+	if err != nil {
+		t.Fatalf("cannot explain config: %s", err)
+	}
+	steps := strings.Split(plan, "\n")
+	if steps[0] != "socket(AF_INET, SOCK_STREAM, IPPROTO_TCP)" {
+		t.Fatalf("unexpected first step %q", steps[0])
+	}
+
+	// The options must be explained in the order NewListener applies them.
+	var options []string
+	for _, step := range steps[1:] {
+		if strings.HasPrefix(step, "setsockopt(") || strings.HasPrefix(step, "getsockopt(") {
+			options = append(options, step)
+		}
+	}
+	var expected []string
+	for _, step := range traced.steps {
+		if strings.HasPrefix(step, "setsockopt(") || strings.HasPrefix(step, "getsockopt(") {
+			expected = append(expected, step)
+		}
+	}
+	if strings.Join(options, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected options in the plan\n%s\nExpecting\n%s", strings.Join(options, "\n"), strings.Join(expected, "\n"))
+	}
+	tail := strings.Join(steps[len(steps)-2:], "\n")
+	if tail != "bind("+addr+")\nlisten(128)" {
+		t.Fatalf("unexpected plan tail %q", tail)
+	}
+
+	// Nothing must be bound by Explain.
+	if ln, err = NewListener("tcp4", addr, Config{}); err != nil {
+		t.Fatalf("cannot listen on %s after Explain: %s", addr, err)
+	}
+	ln.Close()
+}
+
+func TestConfigExplainLiteral(t *testing.T) {
+	if _, err := (Config{}).Explain("tcp4", "localhost:80"); err == nil {
+		t.Fatalf("expecting error for host name")
+	}
+	if _, err := (Config{}).Explain("tcp4", ":http"); err == nil {
+		t.Fatalf("expecting error for service name")
+	}
+	if _, err := (Config{ReusePort: true, ReusePortLB: true}).Explain("tcp4", ":80"); err == nil {
+		t.Fatalf("expecting error for conflicting options")
+	}
+}
@@ -0,0 +1,101 @@
+package tcplisten
+
+import (
+	"expvar"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// expvarListeners is the expvar map with the listeners published
+// by PublishExpvar.
+var expvarListeners = expvar.NewMap("tcplisten")
+
+// ListenerVars are the stats of a listener published by PublishExpvar.
+type ListenerVars struct {
+	// FD is the file descriptor of the listening socket,
+	// or -1 if it cannot be obtained.
+	FD int `json:"fd"`
+
+	// Addr is the address the listener is bound to.
+	Addr string `json:"addr"`
+
+	// Options contains names of the options enabled on the listening
+	// socket, as reported by GetOption.
+	Options []string `json:"options"`
+
+	// Accepted is the number of connections accepted from the listener
+	// returned by PublishExpvar.
+	Accepted uint64 `json:"accepted"`
+}
+
+// PublishExpvar publishes the stats of ln as ListenerVars under name
+// in the "tcplisten" expvar map, so they are served by the standard
+// /debug/vars endpoint.
+//
+// The returned listener counts the accepted connections and removes
+// the stats when closed. A listener published under the same name
+// earlier is replaced.
+func PublishExpvar(ln net.Listener, name string) net.Listener {
+	el := &expvarListener{
+		Listener: ln,
+		name:     name,
+	}
+	expvarListeners.Set(name, expvar.Func(el.vars))
+	return el
+}
+
+type expvarListener struct {
+	// accepted is first for 64-bit alignment on 32-bit platforms.
+	accepted uint64
+
+	net.Listener
+	name      string
+	closeOnce sync.Once
+}
+
+func (ln *expvarListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&ln.accepted, 1)
+	return c, nil
+}
+
+func (ln *expvarListener) acceptedCount() uint64 {
+	return atomic.LoadUint64(&ln.accepted)
+}
+
+func (ln *expvarListener) Close() error {
+	ln.closeOnce.Do(func() {
+		expvarListeners.Delete(ln.name)
+	})
+	return ln.Listener.Close()
+}
+
+func (ln *expvarListener) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(ln.Listener)
+}
+
+func (ln *expvarListener) vars() interface{} {
+	v := ListenerVars{
+		FD:       -1,
+		Addr:     ln.Addr().String(),
+		Accepted: ln.acceptedCount(),
+	}
+	withFd(ln.Listener, func(fd uintptr) error {
+		v.FD = int(fd)
+		return nil
+	})
+	for _, spec := range Options() {
+		n, err := GetOption(ln.Listener, spec.Option)
+		// SO_MAX_PACING_RATE is ~0U unless it is limited.
+		if err != nil || n == 0 || (spec.Option == OptionMaxPacingRate && n == -1) {
+			continue
+		}
+		v.Options = append(v.Options, spec.Name)
+	}
+	return v
+}
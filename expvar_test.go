@@ -0,0 +1,55 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"encoding/json"
+	"net"
+	"runtime"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	base, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln := PublishExpvar(base, "test")
+	defer ln.Close()
+
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial listener: %s", err)
+	}
+	defer c.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	sc.Close()
+
+	ev := expvarListeners.Get("test")
+	if ev == nil {
+		t.Fatalf("the listener isn't published")
+	}
+	var v ListenerVars
+	if err = json.Unmarshal([]byte(ev.String()), &v); err != nil {
+		t.Fatalf("cannot parse published vars %q: %s", ev, err)
+	}
+	if v.FD < 0 || v.Addr != ln.Addr().String() || v.Accepted != 1 {
+		t.Fatalf("unexpected published vars %+v", v)
+	}
+	// NewListener doesn't set SO_REUSEADDR on Windows.
+	found := runtime.GOOS == "windows"
+	for _, o := range v.Options {
+		found = found || o == "SO_REUSEADDR"
+	}
+	if !found {
+		t.Fatalf("SO_REUSEADDR is missing in published options %q", v.Options)
+	}
+
+	ln.Close()
+	if expvarListeners.Get("test") != nil {
+		t.Fatalf("the listener must be removed after Close")
+	}
+}
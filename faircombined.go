@@ -0,0 +1,174 @@
+package tcplisten
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+)
+
+// FairCombinedListener returns a listener accepting connections from all
+// the given listeners, e.g. the members of a SO_REUSEPORT group drained
+// by a single process.
+//
+// Unlike merging the listeners on a first-come basis, Accept services
+// the listeners round-robin: every call starts with the listener next
+// to the one the previous connection has been taken from, so a busy
+// member cannot starve the others. Every listener has at most a single
+// accepted connection waiting for Accept, and the rest stay in the accept
+// queue of its socket.
+//
+// A non-temporary accept error of a listener is returned from Accept
+// once, and the rest of the listeners keep being serviced. Once all
+// the listeners have failed, Accept returns the last such error.
+// Close closes all the listeners. Addr returns the address of the first
+// listener.
+//
+// At least a single listener must be given.
+func FairCombinedListener(listeners ...net.Listener) (net.Listener, error) {
+	if len(listeners) == 0 {
+		return nil, errors.New("cannot combine listeners: no listeners given")
+	}
+	ln := &fairCombinedListener{
+		lns:   append([]net.Listener(nil), listeners...),
+		chs:   make([]chan acceptResult, len(listeners)),
+		alive: len(listeners),
+		done:  make(chan struct{}),
+	}
+	ln.cases = make([]reflect.SelectCase, len(listeners)+1)
+	for i, l := range ln.lns {
+		ln.chs[i] = make(chan acceptResult)
+		ln.cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ln.chs[i])}
+		go ln.acceptLoop(l, ln.chs[i])
+	}
+	ln.cases[len(listeners)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ln.done)}
+	return ln, nil
+}
+
+type fairCombinedListener struct {
+	lns   []net.Listener
+	chs   []chan acceptResult
+	cases []reflect.SelectCase
+
+	// mu serializes Accept calls, so the rotation is preserved.
+	mu   sync.Mutex
+	next int
+
+	// alive is the number of listeners whose acceptLoop is running.
+	// lastErr is the last non-temporary error of the failed ones.
+	alive   int
+	lastErr error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// acceptLoop passes the connections accepted from l to ch. It closes ch
+// on exit, so Accept stops waiting for l.
+func (ln *fairCombinedListener) acceptLoop(l net.Listener, ch chan<- acceptResult) {
+	defer close(ch)
+	for {
+		c, err := l.Accept()
+		select {
+		case ch <- acceptResult{c, err}:
+		case <-ln.done:
+			if c != nil {
+				c.Close()
+			}
+			return
+		}
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+	}
+}
+
+// Accept returns the connection from the first listener with a pending
+// connection, starting with the listener next to the one serviced
+// by the previous call.
+func (ln *fairCombinedListener) Accept() (net.Conn, error) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	select {
+	case <-ln.done:
+		return nil, ErrListenerClosed
+	default:
+	}
+	n := len(ln.chs)
+	for ln.alive > 0 {
+		removed := false
+		for i := 0; i < n; i++ {
+			idx := (ln.next + i) % n
+			select {
+			case r, ok := <-ln.chs[idx]:
+				if ok {
+					return ln.result(idx, r)
+				}
+				ln.remove(idx)
+				removed = true
+			default:
+			}
+		}
+		if removed {
+			continue
+		}
+
+		// No listener has a pending connection, so take the first one.
+		idx, v, ok := reflect.Select(ln.cases)
+		if idx == n {
+			return nil, ErrListenerClosed
+		}
+		if !ok {
+			ln.remove(idx)
+			continue
+		}
+		return ln.result(idx, v.Interface().(acceptResult))
+	}
+	if ln.lastErr != nil {
+		return nil, ln.lastErr
+	}
+	return nil, ErrListenerClosed
+}
+
+// result advances the rotation past the listener idx and returns r.
+func (ln *fairCombinedListener) result(idx int, r acceptResult) (net.Conn, error) {
+	ln.next = (idx + 1) % len(ln.chs)
+	if r.err != nil {
+		if ne, ok := r.err.(net.Error); !ok || !ne.Temporary() {
+			ln.lastErr = r.err
+		}
+	}
+	return r.c, r.err
+}
+
+// remove stops waiting for the listener idx, whose acceptLoop has exited.
+func (ln *fairCombinedListener) remove(idx int) {
+	// Receiving from a nil channel never proceeds, and reflect.Select
+	// ignores the cases with the zero Chan.
+	ln.chs[idx] = nil
+	ln.cases[idx].Chan = reflect.Value{}
+	ln.alive--
+}
+
+// Close closes all the listeners.
+func (ln *fairCombinedListener) Close() error {
+	var err error
+	ln.closeOnce.Do(func() {
+		close(ln.done)
+		for _, l := range ln.lns {
+			if cerr := l.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	})
+	return err
+}
+
+// Addr returns the address of the first listener.
+func (ln *fairCombinedListener) Addr() net.Addr {
+	return ln.lns[0].Addr()
+}
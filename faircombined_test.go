@@ -0,0 +1,111 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFairCombinedListener(t *testing.T) {
+	var lns []net.Listener
+	for i := 0; i < 3; i++ {
+		l, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		lns = append(lns, l)
+	}
+	ln, err := FairCombinedListener(lns...)
+	if err != nil {
+		t.Fatalf("cannot combine listeners: %s", err)
+	}
+	defer ln.Close()
+
+	// Load the first listener heavily and the rest lightly.
+	for i, n := range []int{5, 1, 1} {
+		for j := 0; j < n; j++ {
+			cc, err := net.Dial("tcp4", lns[i].Addr().String())
+			if err != nil {
+				t.Fatalf("cannot dial listener %d: %s", i, err)
+			}
+			defer cc.Close()
+		}
+	}
+	// Let every listener pick up a connection.
+	time.Sleep(100 * time.Millisecond)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept connection: %s", err)
+		}
+		seen[c.LocalAddr().String()] = true
+		c.Close()
+	}
+	if len(seen) != 3 {
+		t.Fatalf("unexpected listeners serviced first: %v. Expecting all 3", seen)
+	}
+	for i := 0; i < 4; i++ {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept connection: %s", err)
+		}
+		c.Close()
+	}
+
+	ln.Close()
+	if _, err := ln.Accept(); err != ErrListenerClosed {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrListenerClosed)
+	}
+	if _, err := net.Dial("tcp4", lns[1].Addr().String()); err == nil {
+		t.Fatalf("expecting the member listeners to be closed")
+	}
+}
+
+func TestFairCombinedListenerMembersClosed(t *testing.T) {
+	if _, err := FairCombinedListener(); err == nil {
+		t.Fatalf("expecting error when no listeners are given")
+	}
+
+	var lns []net.Listener
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		lns = append(lns, l)
+	}
+	ln, err := FairCombinedListener(lns...)
+	if err != nil {
+		t.Fatalf("cannot combine listeners: %s", err)
+	}
+	defer ln.Close()
+
+	// Close the members behind the combined listener's back.
+	for _, l := range lns {
+		l.Close()
+	}
+	// Every member reports its error once, and then Accept keeps
+	// returning the last one.
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		for i := 0; i < len(lns)+1; i++ {
+			if _, err = ln.Accept(); err == nil {
+				break
+			}
+		}
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		if err == nil || err == ErrListenerClosed {
+			t.Fatalf("unexpected error %v. Expecting the last accept error of the members", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Accept blocks after all the members are closed")
+	}
+}
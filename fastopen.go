@@ -0,0 +1,32 @@
+package tcplisten
+
+import (
+	"net"
+)
+
+// Metric names reported by the listener returned from CountFastOpen.
+const (
+	MetricAccepted         = "tcplisten_accepted"
+	MetricFastOpenAccepted = "tcplisten_fastopen_accepted"
+)
+
+// CountFastOpen returns a listener reporting the number of accepted
+// connections and the number of connections with data in the SYN to sink,
+// so the share of connections using TCP Fast Open may be tracked.
+//
+// Connections for which AcceptedViaFastOpen fails, e.g. on platforms
+// other than Linux, aren't counted at all, so the ratio isn't skewed.
+func CountFastOpen(ln net.Listener, sink MetricsSink) net.Listener {
+	cl := composeListener(ln)
+	cl.sink = sink
+	return cl
+}
+
+func countFastOpen(c net.Conn, sink MetricsSink) {
+	if tfo, err := AcceptedViaFastOpen(c); err == nil {
+		sink.Counter(MetricAccepted, 1)
+		if tfo {
+			sink.Counter(MetricFastOpenAccepted, 1)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	tcpFastOpenConnect = 30
+	msgFastOpen        = 0x20000000
+	tcpiOptSynData     = 32
+)
+
+// FastOpenConn is a client connection sending the data passed to the first
+// Write in the SYN segment with TCP Fast Open.
+//
+// The connection is established by the first Write, or by the first Read
+// if nothing has been written. The data is sent in the SYN only if the TFO
+// cookie of the server is cached. Otherwise the SYN requests the cookie
+// for the subsequent connections, and the data is sent after the handshake.
+// Write succeeds before the server responds if the data fits the SYN,
+// so connection errors are reported by the subsequent calls then.
+//
+// TCP_FASTOPEN_CONNECT is used on Linux 4.11 and newer. Older kernels
+// send the SYN with sendmsg(MSG_FASTOPEN). A regular connect is used
+// if the client side of TFO is disabled with net.ipv4.tcp_fastopen.
+type FastOpenConn struct {
+	f      *os.File
+	rc     syscall.RawConn
+	sa     syscall.Sockaddr
+	remote *net.TCPAddr
+
+	// fastOpenConnect is set if TCP_FASTOPEN_CONNECT is enabled.
+	fastOpenConnect bool
+
+	mu         sync.Mutex
+	connected  bool
+	connectErr error
+	local      net.Addr
+}
+
+// DialFastOpen returns a connection to addr, which is established
+// with TCP Fast Open on the first Write.
+//
+// Only tcp4 and tcp6 networks are supported.
+func DialFastOpen(network, addr string) (*FastOpenConn, error) {
+	sa, soType, err := getSockaddr(context.Background(), network, addr)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := newSocketCloexec(soType, syscall.SOCK_STREAM, syscall.IPPROTO_TCP)
+	if err != nil {
+		return nil, err
+	}
+	c := &FastOpenConn{
+		sa:     sa,
+		remote: sockaddrToTCPAddr(sa),
+	}
+	// TCP_FASTOPEN_CONNECT is missing before Linux 4.11 and fails
+	// if the client side of TFO is disabled.
+	c.fastOpenConnect = syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpFastOpenConnect, 1) == nil
+
+	c.f = os.NewFile(uintptr(fd), fmt.Sprintf("tcplisten-fastopen.%s", c.remote))
+	if c.rc, err = c.f.SyscallConn(); err != nil {
+		c.f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// connect establishes the connection and sends the head of b in the SYN
+// if possible. It returns the number of bytes sent.
+func (c *FastOpenConn) connect(b []byte) (int, error) {
+	send := func(fd int) (int, error) {
+		return syscall.SendmsgN(fd, b, nil, c.sa, msgFastOpen)
+	}
+	if c.fastOpenConnect {
+		// connect defers the SYN until the first write if the cookie
+		// is cached. Otherwise it sends the SYN with the cookie request.
+		var err error
+		if cerr := c.rc.Control(func(fd uintptr) {
+			err = syscall.Connect(int(fd), c.sa)
+		}); cerr != nil {
+			return 0, cerr
+		}
+		if err != nil && err != syscall.EINPROGRESS {
+			return 0, err
+		}
+		send = func(fd int) (int, error) {
+			return syscall.Write(fd, b)
+		}
+	}
+
+	var (
+		n    int
+		werr error
+	)
+	err := c.rc.Write(func(fd uintptr) bool {
+		n, werr = send(int(fd))
+		switch werr {
+		case syscall.EOPNOTSUPP:
+			// The client side of TFO is disabled.
+			if werr = syscall.Connect(int(fd), c.sa); werr != syscall.EINPROGRESS {
+				return true
+			}
+		case syscall.EINPROGRESS:
+			// The SYN has been sent without data.
+		case syscall.EAGAIN:
+			return false
+		default:
+			return true
+		}
+		// Wait for the handshake and send the data with plain writes.
+		send = func(fd int) (int, error) {
+			return syscall.Write(fd, b)
+		}
+		return false
+	})
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n, werr
+}
+
+// handshake establishes the connection on the first call.
+// It returns the number of bytes of b sent in the process.
+func (c *FastOpenConn) handshake(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.connected {
+		return 0, c.connectErr
+	}
+	c.connected = true
+	n, err := c.connect(b)
+	if err != nil {
+		c.connectErr = &net.OpError{Op: "dial", Net: "tcp", Addr: c.remote, Err: os.NewSyscallError("connect", err)}
+	}
+	return n, c.connectErr
+}
+
+// Write writes b to the connection. The first Write establishes
+// the connection.
+func (c *FastOpenConn) Write(b []byte) (int, error) {
+	n, err := c.handshake(b)
+	if err != nil || n == len(b) {
+		return n, err
+	}
+	m, err := c.f.Write(b[n:])
+	return n + m, err
+}
+
+// Read reads from the connection. It establishes the connection
+// if nothing has been written yet.
+func (c *FastOpenConn) Read(b []byte) (int, error) {
+	if _, err := c.handshake(nil); err != nil {
+		return 0, err
+	}
+	return c.f.Read(b)
+}
+
+// Close closes the connection.
+func (c *FastOpenConn) Close() error {
+	return c.f.Close()
+}
+
+// LocalAddr returns the local address of the connection.
+// It is nil until the connection is established.
+func (c *FastOpenConn) LocalAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.local == nil && c.connected {
+		c.rc.Control(func(fd uintptr) {
+			if sa, err := syscall.Getsockname(int(fd)); err == nil {
+				if addr := sockaddrToTCPAddr(sa); addr != nil && addr.Port != 0 {
+					c.local = addr
+				}
+			}
+		})
+	}
+	return c.local
+}
+
+// RemoteAddr returns the address the connection has been dialed to.
+func (c *FastOpenConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *FastOpenConn) SetDeadline(t time.Time) error {
+	return c.f.SetDeadline(t)
+}
+
+func (c *FastOpenConn) SetReadDeadline(t time.Time) error {
+	return c.f.SetReadDeadline(t)
+}
+
+func (c *FastOpenConn) SetWriteDeadline(t time.Time) error {
+	return c.f.SetWriteDeadline(t)
+}
+
+func (c *FastOpenConn) SyscallConn() (syscall.RawConn, error) {
+	return c.rc, nil
+}
+
+// AcceptedViaFastOpen reports whether the data in the SYN of the accepted
+// connection c has been acknowledged, i.e. whether c used TCP Fast Open.
+//
+// It reads TCPI_OPT_SYN_DATA from TCP_INFO.
+func AcceptedViaFastOpen(c net.Conn) (bool, error) {
+	var tfo bool
+	err := withFd(c, func(fd uintptr) error {
+		var ti syscall.TCPInfo
+		if err := getTCPInfo(int(fd), &ti); err != nil {
+			return fmt.Errorf("cannot obtain TCP_INFO: %s", err)
+		}
+		tfo = ti.Options&tcpiOptSynData != 0
+		return nil
+	})
+	return tfo, err
+}
@@ -0,0 +1,203 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestDialFastOpen(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{FastOpen: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				io.Copy(c, c)
+			}()
+		}
+	}()
+
+	// The first connection obtains the cookie, so the data may be sent
+	// in the SYN of the second one.
+	var synData bool
+	for i := 0; i < 2; i++ {
+		c, err := DialFastOpen("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		req := []byte("hello, fast open")
+		if n, err := c.Write(req); err != nil || n != len(req) {
+			t.Fatalf("unexpected Write result: %d, %v. Expecting %d bytes written", n, err, len(req))
+		}
+		resp := make([]byte, len(req))
+		if _, err = io.ReadFull(c, resp); err != nil {
+			t.Fatalf("cannot read response: %s", err)
+		}
+		if !bytes.Equal(resp, req) {
+			t.Fatalf("unexpected response %q. Expecting %q", resp, req)
+		}
+		if c.LocalAddr() == nil {
+			t.Fatalf("missing local address")
+		}
+		withFd(c, func(fd uintptr) error {
+			var ti syscall.TCPInfo
+			if getTCPInfo(int(fd), &ti) == nil {
+				synData = ti.Options&tcpiOptSynData != 0
+			}
+			return nil
+		})
+		c.Close()
+	}
+
+	if tfoServerEnabled() && !synData {
+		t.Fatalf("the data hasn't been sent in the SYN")
+	}
+}
+
+func TestDialFastOpenReadFirst(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Write([]byte("banner"))
+		c.Close()
+	}()
+
+	c, err := DialFastOpen("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	resp, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatalf("cannot read banner: %s", err)
+	}
+	if string(resp) != "banner" {
+		t.Fatalf("unexpected banner %q. Expecting %q", resp, "banner")
+	}
+}
+
+func TestDialFastOpenRefused(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c, err := DialFastOpen("tcp4", addr)
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	// Write succeeds if the data has been sent in the SYN,
+	// so the error may be reported by Read.
+	if _, err = c.Write([]byte("x")); err == nil {
+		_, err = c.Read(make([]byte, 1))
+	}
+	if err == nil {
+		t.Fatalf("expecting error for closed port")
+	}
+}
+
+// tfoServerEnabled reports whether net.ipv4.tcp_fastopen enables
+// the server side of TFO.
+func tfoServerEnabled() bool {
+	data, err := ioutil.ReadFile("/proc/sys/net/ipv4/tcp_fastopen")
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return err == nil && n&2 != 0
+}
+
+func TestAcceptedViaFastOpen(t *testing.T) {
+	tln, err := NewListener("tcp4", "127.0.0.1:0", Config{FastOpen: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	sink := newTestSink()
+	ln := CountFastOpen(tln, sink)
+	defer ln.Close()
+
+	results := make(chan bool, 3)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			tfo, err := AcceptedViaFastOpen(c)
+			if err != nil {
+				t.Errorf("cannot check TFO: %s", err)
+			}
+			io.Copy(ioutil.Discard, c)
+			c.Close()
+			results <- tfo
+		}
+	}()
+
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	c.Write([]byte("plain"))
+	c.Close()
+	if <-results {
+		t.Fatalf("plain connection is reported as TFO")
+	}
+
+	// The first connection obtains the cookie unless it is cached already.
+	var (
+		lastTFO  bool
+		tfoCount uint64
+	)
+	for i := 0; i < 2; i++ {
+		fc, err := DialFastOpen("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		if _, err = fc.Write([]byte("fast open")); err != nil {
+			t.Fatalf("cannot write: %s", err)
+		}
+		fc.Close()
+		tfo := <-results
+		if tfo {
+			tfoCount++
+		}
+		lastTFO = tfo
+	}
+	if tfoServerEnabled() && !lastTFO {
+		t.Fatalf("TFO connection isn't detected")
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if n := sink.counters[MetricAccepted]; n != 3 {
+		t.Fatalf("unexpected %s %d. Expecting 3", MetricAccepted, n)
+	}
+	if n := sink.counters[MetricFastOpenAccepted]; n != tfoCount {
+		t.Fatalf("unexpected %s %d. Expecting %d", MetricFastOpenAccepted, n, tfoCount)
+	}
+}
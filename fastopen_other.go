@@ -0,0 +1,37 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// FastOpenConn is a client connection sending the data passed to the first
+// Write in the SYN segment with TCP Fast Open.
+//
+// It is implemented only on Linux.
+type FastOpenConn struct {
+	net.Conn
+}
+
+// DialFastOpen returns a connection to addr, which is established
+// with TCP Fast Open on the first Write.
+//
+// It is supported only on Linux.
+func DialFastOpen(network, addr string) (*FastOpenConn, error) {
+	return nil, &UnsupportedError{
+		Op:     "DialFastOpen",
+		Reason: "client-side TCP Fast Open is implemented only on Linux",
+	}
+}
+
+// AcceptedViaFastOpen reports whether the data in the SYN of the accepted
+// connection c has been acknowledged, i.e. whether c used TCP Fast Open.
+//
+// It is supported only on Linux.
+func AcceptedViaFastOpen(c net.Conn) (bool, error) {
+	return false, &UnsupportedError{
+		Op:     "AcceptedViaFastOpen",
+		Reason: "TCPI_OPT_SYN_DATA is exposed only by TCP_INFO on Linux",
+	}
+}
@@ -0,0 +1,303 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// DefaultMaxInFlight is the default number of connections FDPasser
+// may have sent without a confirmation from the worker.
+const DefaultMaxInFlight = 64
+
+// fdFrameVersion is the version of the fd passing frame format.
+//
+// Every passed fd is attached to the first byte of a frame consisting of
+// a big-endian uint32 payload length followed by the payload:
+// the version byte and length-prefixed RemoteAddr, LocalAddr and Hint.
+// The worker confirms every received frame with a single fdAck byte.
+const fdFrameVersion = 1
+
+// maxFDFrameLen limits the payload size of fd passing frames.
+const maxFDFrameLen = 4096
+
+const fdAck = 1
+
+// errFDPasserClosed is returned from FDPasser.Pass after Close.
+var errFDPasserClosed = errors.New("tcplisten: fd passer closed")
+
+// FDHeader is the metadata sent together with each passed connection.
+type FDHeader struct {
+	// RemoteAddr is the remote address of the original connection.
+	RemoteAddr string
+
+	// LocalAddr is the local address of the original connection.
+	LocalAddr string
+
+	// Hint is an arbitrary string for the worker, such as the reason
+	// it has been chosen for the connection.
+	Hint string
+}
+
+func (h *FDHeader) marshal() ([]byte, error) {
+	n := 1 + 3*2 + len(h.RemoteAddr) + len(h.LocalAddr) + len(h.Hint)
+	if n > maxFDFrameLen {
+		return nil, fmt.Errorf("too big fd header: %d bytes. Max %d bytes", n, maxFDFrameLen)
+	}
+	b := make([]byte, 4, 4+n)
+	binary.BigEndian.PutUint32(b, uint32(n))
+	b = append(b, fdFrameVersion)
+	for _, s := range [...]string{h.RemoteAddr, h.LocalAddr, h.Hint} {
+		b = append(b, byte(len(s)>>8), byte(len(s)))
+		b = append(b, s...)
+	}
+	return b, nil
+}
+
+func (h *FDHeader) unmarshal(b []byte) error {
+	if len(b) == 0 || b[0] != fdFrameVersion {
+		return fmt.Errorf("unsupported fd frame version")
+	}
+	b = b[1:]
+	var fields [3]string
+	for i := range fields {
+		if len(b) < 2 {
+			return fmt.Errorf("truncated fd header")
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		if len(b) < n {
+			return fmt.Errorf("truncated fd header")
+		}
+		fields[i] = string(b[:n])
+		b = b[n:]
+	}
+	if len(b) != 0 {
+		return fmt.Errorf("unexpected %d trailing bytes in fd header", len(b))
+	}
+	h.RemoteAddr, h.LocalAddr, h.Hint = fields[0], fields[1], fields[2]
+	return nil
+}
+
+// FDPasser hands accepted connections to a worker process listening
+// on a unix socket. The connection's fd is passed with SCM_RIGHTS,
+// so the worker serves the original socket instead of a proxied stream.
+//
+// Passed connections are kept open until the worker confirms them.
+// If the worker dies, the unconfirmed connections are re-sent over
+// a new connection to the same path, or closed if the path can't be
+// dialed anymore.
+//
+// Use DialFDPasser for creating FDPasser.
+type FDPasser struct {
+	// MaxInFlight is the maximum number of unconfirmed connections.
+	// Pass blocks while the limit is reached.
+	//
+	// DefaultMaxInFlight is used by default.
+	MaxInFlight int
+
+	path string
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	uc      *net.UnixConn
+	pending []*passedConn
+	closed  bool
+}
+
+type passedConn struct {
+	c     net.Conn
+	frame []byte
+}
+
+// DialFDPasser connects to the worker listening on the given unix socket.
+func DialFDPasser(path string) (*FDPasser, error) {
+	p := &FDPasser{
+		path: path,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	if err := p.dialLocked(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Pass sends c together with the hint to the worker.
+//
+// c is closed after the worker confirms it has received the fd,
+// so the caller mustn't use it after Pass returns. c is closed
+// immediately if Pass returns an error.
+func (p *FDPasser) Pass(c net.Conn, hint string) error {
+	h := FDHeader{
+		RemoteAddr: c.RemoteAddr().String(),
+		LocalAddr:  c.LocalAddr().String(),
+		Hint:       hint,
+	}
+	frame, err := h.marshal()
+	if err != nil {
+		c.Close()
+		return err
+	}
+	pc := &passedConn{
+		c:     c,
+		frame: frame,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for !p.closed && len(p.pending) >= p.maxInFlight() {
+		p.cond.Wait()
+	}
+	if p.closed {
+		c.Close()
+		return errFDPasserClosed
+	}
+
+	p.pending = append(p.pending, pc)
+	if p.uc != nil {
+		if err = sendFD(p.uc, pc); err == nil {
+			return nil
+		}
+		p.uc.Close()
+		p.uc = nil
+	}
+	// The worker has gone. Re-send all the unconfirmed connections
+	// to its successor.
+	if err = p.redialLocked(); err != nil {
+		return fmt.Errorf("cannot pass connection to %q: %s", p.path, err)
+	}
+	return nil
+}
+
+// Close closes the connection to the worker together with all
+// the unconfirmed connections.
+func (p *FDPasser) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	p.dropPendingLocked()
+	p.cond.Broadcast()
+	if p.uc == nil {
+		return nil
+	}
+	err := p.uc.Close()
+	p.uc = nil
+	return err
+}
+
+func (p *FDPasser) maxInFlight() int {
+	if p.MaxInFlight <= 0 {
+		return DefaultMaxInFlight
+	}
+	return p.MaxInFlight
+}
+
+func (p *FDPasser) dialLocked() error {
+	c, err := net.Dial("unix", p.path)
+	if err != nil {
+		return fmt.Errorf("cannot connect to worker at %q: %s", p.path, err)
+	}
+	p.uc = c.(*net.UnixConn)
+	go p.readAcks(p.uc)
+	return nil
+}
+
+// redialLocked connects to the worker again and re-sends
+// all the pending connections. The pending connections are closed
+// if this fails.
+func (p *FDPasser) redialLocked() error {
+	err := p.dialLocked()
+	if err == nil {
+		for _, pc := range p.pending {
+			if err = sendFD(p.uc, pc); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		p.uc.Close()
+		p.uc = nil
+	}
+	p.dropPendingLocked()
+	p.cond.Broadcast()
+	return err
+}
+
+func (p *FDPasser) dropPendingLocked() {
+	for _, pc := range p.pending {
+		pc.c.Close()
+	}
+	p.pending = nil
+}
+
+// readAcks closes the connections confirmed by the worker over uc.
+func (p *FDPasser) readAcks(uc *net.UnixConn) {
+	var buf [64]byte
+	for {
+		n, err := uc.Read(buf[:])
+
+		p.mu.Lock()
+		if p.uc != uc {
+			// The session has been replaced or closed.
+			p.mu.Unlock()
+			return
+		}
+		for _, b := range buf[:n] {
+			if b != fdAck || len(p.pending) == 0 {
+				err = fmt.Errorf("unexpected confirmation from worker")
+				break
+			}
+			p.pending[0].c.Close()
+			p.pending[0] = nil
+			p.pending = p.pending[1:]
+		}
+		if err != nil {
+			uc.Close()
+			p.uc = nil
+			if len(p.pending) > 0 {
+				p.redialLocked()
+			}
+		}
+		p.cond.Broadcast()
+		p.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// sendFD writes the frame of pc to uc with the fd of pc attached.
+//
+// The kernel duplicates the fd into the receiving process, so the local
+// copy stays valid until it is closed after the confirmation.
+func sendFD(uc *net.UnixConn, pc *passedConn) error {
+	n := 0
+	err := withFd(pc.c, func(fd uintptr) error {
+		var err error
+		n, _, err = uc.WriteMsgUnix(pc.frame, syscall.UnixRights(int(fd)), nil)
+		return err
+	})
+	if err != nil && n == 0 {
+		return err
+	}
+	// The fd is attached to the first byte, so the rest of a partially
+	// sent frame is written without it.
+	if n < len(pc.frame) {
+		if _, err = uc.Write(pc.frame[n:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// recvTestFD receives a single passed connection from uc.
+func recvTestFD(t *testing.T, uc *net.UnixConn) (net.Conn, FDHeader) {
+	buf := make([]byte, maxFDFrameLen+4)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := uc.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatalf("cannot read fd frame: %s", err)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected control messages %v: %v", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("unexpected fds %v: %v", fds, err)
+	}
+	var h FDHeader
+	if err = h.unmarshal(buf[4:n]); err != nil {
+		t.Fatalf("cannot parse fd header: %s", err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "passed")
+	defer f.Close()
+	c, err := net.FileConn(f)
+	if err != nil {
+		t.Fatalf("cannot create conn from passed fd: %s", err)
+	}
+	return c, h
+}
+
+func TestFDPasser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker.sock")
+	wln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("cannot listen on %q: %s", path, err)
+	}
+	defer wln.Close()
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	p, err := DialFDPasser(path)
+	if err != nil {
+		t.Fatalf("cannot create fd passer: %s", err)
+	}
+	defer p.Close()
+
+	client, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer client.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	if err = p.Pass(c, "worker-1"); err != nil {
+		t.Fatalf("cannot pass connection: %s", err)
+	}
+
+	// The first worker dies without confirming the connection,
+	// so it must be re-sent to the next one.
+	wc, err := wln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept worker connection: %s", err)
+	}
+	dead, _ := recvTestFD(t, wc.(*net.UnixConn))
+	dead.Close()
+	wc.Close()
+
+	wc, err = wln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept worker connection: %s", err)
+	}
+	defer wc.Close()
+	pc, h := recvTestFD(t, wc.(*net.UnixConn))
+	defer pc.Close()
+	if h.RemoteAddr != client.LocalAddr().String() || h.Hint != "worker-1" {
+		t.Fatalf("unexpected header %+v", h)
+	}
+	if _, err = wc.Write([]byte{fdAck}); err != nil {
+		t.Fatalf("cannot confirm fd: %s", err)
+	}
+
+	if _, err = client.Write([]byte("ping")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err = pc.Read(buf); err != nil {
+		t.Fatalf("cannot read from passed connection: %s", err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("unexpected data %q. Expecting %q", buf, "ping")
+	}
+}
@@ -0,0 +1,265 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// maxFDsPerRead is the number of fds a single read from the passer
+// may carry. Frames are small, so a read rarely carries more than one.
+const maxFDsPerRead = 16
+
+// fdReceiver is the worker side of FDPasser.
+type fdReceiver struct {
+	ln *net.UnixListener
+
+	ch        chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu       sync.Mutex
+	sessions map[*net.UnixConn]struct{}
+}
+
+// FDReceiverListener returns a listener for connections passed
+// by FDPasser instances connecting to the given unix socket.
+//
+// Accept returns the passed connections as is, so the existing server
+// code may serve them the same way as the connections accepted
+// from a TCP listener. RemoteAddr of the returned connections is
+// the remote address reported by the passer. Use ConnFDHeader
+// for obtaining the rest of the header.
+//
+// Malformed frames and frames without fds are confirmed and dropped.
+// The passer session is closed if fds have been lost because
+// of control message truncation, so the passer re-sends
+// the unconfirmed connections.
+func FDReceiverListener(path string) (net.Listener, error) {
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %q: %s", path, err)
+	}
+	r := &fdReceiver{
+		ln:       ln,
+		ch:       make(chan net.Conn),
+		done:     make(chan struct{}),
+		sessions: make(map[*net.UnixConn]struct{}),
+	}
+	go r.serve()
+	return r, nil
+}
+
+// Accept waits for and returns the next passed connection.
+func (r *fdReceiver) Accept() (net.Conn, error) {
+	select {
+	case c := <-r.ch:
+		return c, nil
+	case <-r.done:
+		return nil, ErrListenerClosed
+	}
+}
+
+// Close stops receiving connections and closes all the passer sessions.
+func (r *fdReceiver) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		err = r.ln.Close()
+		r.mu.Lock()
+		for uc := range r.sessions {
+			uc.Close()
+		}
+		r.mu.Unlock()
+	})
+	return err
+}
+
+// Addr returns the address of the unix socket.
+func (r *fdReceiver) Addr() net.Addr {
+	return r.ln.Addr()
+}
+
+func (r *fdReceiver) SyscallConn() (syscall.RawConn, error) {
+	return r.ln.SyscallConn()
+}
+
+func (r *fdReceiver) serve() {
+	for {
+		uc, err := r.ln.AcceptUnix()
+		if err != nil {
+			select {
+			case <-r.done:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			r.Close()
+			return
+		}
+		r.mu.Lock()
+		select {
+		case <-r.done:
+			uc.Close()
+		default:
+			r.sessions[uc] = struct{}{}
+			go r.readSession(uc)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// readSession receives connections from a single passer until
+// the session fails.
+func (r *fdReceiver) readSession(uc *net.UnixConn) {
+	var fds []int
+	defer func() {
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+		r.mu.Lock()
+		delete(r.sessions, uc)
+		r.mu.Unlock()
+		uc.Close()
+	}()
+
+	buf := make([]byte, 0, 4+maxFDFrameLen)
+	oob := make([]byte, syscall.CmsgSpace(4*maxFDsPerRead))
+	for {
+		n, oobn, flags, _, err := uc.ReadMsgUnix(buf[len(buf):cap(buf)], oob)
+		if oobn > 0 {
+			rights, perr := parseRights(oob[:oobn])
+			fds = append(fds, rights...)
+			if perr != nil {
+				return
+			}
+		}
+		if flags&syscall.MSG_CTRUNC != 0 {
+			// The kernel has closed the fds which didn't fit,
+			// so the frames can't be matched to fds anymore.
+			return
+		}
+		if err != nil {
+			return
+		}
+		buf = buf[:len(buf)+n]
+
+		for len(buf) >= 4 {
+			frameLen := int(binary.BigEndian.Uint32(buf))
+			if frameLen > maxFDFrameLen {
+				return
+			}
+			if len(buf) < 4+frameLen {
+				break
+			}
+			fd := -1
+			if len(fds) > 0 {
+				fd = fds[0]
+				fds = fds[1:]
+			}
+			c := newReceivedConn(fd, buf[4:4+frameLen])
+			buf = buf[:copy(buf, buf[4+frameLen:])]
+
+			if _, err = uc.Write([]byte{fdAck}); err != nil {
+				if c != nil {
+					c.Close()
+				}
+				return
+			}
+			if c == nil {
+				continue
+			}
+			select {
+			case r.ch <- c:
+			case <-r.done:
+				c.Close()
+				return
+			}
+		}
+	}
+}
+
+// parseRights returns all the fds passed in the given control messages.
+func parseRights(oob []byte) ([]int, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	var fds []int
+	for i := range msgs {
+		if msgs[i].Header.Level != syscall.SOL_SOCKET || msgs[i].Header.Type != syscall.SCM_RIGHTS {
+			continue
+		}
+		rights, err := syscall.ParseUnixRights(&msgs[i])
+		if err != nil {
+			return fds, err
+		}
+		fds = append(fds, rights...)
+	}
+	return fds, nil
+}
+
+// newReceivedConn returns the connection for the given passed fd
+// and frame payload. It returns nil and closes fd if the frame
+// is malformed or carries no fd.
+func newReceivedConn(fd int, payload []byte) net.Conn {
+	if fd < 0 {
+		return nil
+	}
+	var h FDHeader
+	if err := h.unmarshal(payload); err != nil {
+		syscall.Close(fd)
+		return nil
+	}
+	syscall.CloseOnExec(fd)
+
+	f := os.NewFile(uintptr(fd), "tcplisten-passed."+h.RemoteAddr)
+	c, err := net.FileConn(f)
+	f.Close()
+	if err != nil {
+		return nil
+	}
+	rc := &receivedConn{
+		Conn:   c,
+		header: h,
+	}
+	if addr, err := net.ResolveTCPAddr("tcp", h.RemoteAddr); err == nil {
+		rc.remote = addr
+	}
+	return rc
+}
+
+// receivedConn is a connection passed by FDPasser.
+type receivedConn struct {
+	net.Conn
+	header FDHeader
+	remote net.Addr
+}
+
+func (c *receivedConn) RemoteAddr() net.Addr {
+	if c.remote == nil {
+		return c.Conn.RemoteAddr()
+	}
+	return c.remote
+}
+
+func (c *receivedConn) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(c.Conn)
+}
+
+// ConnFDHeader returns the header the connection has been passed with
+// if the connection has been accepted from FDReceiverListener.
+func ConnFDHeader(c net.Conn) (FDHeader, bool) {
+	rc, ok := c.(*receivedConn)
+	if !ok {
+		return FDHeader{}, false
+	}
+	return rc.header, true
+}
@@ -0,0 +1,88 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestFDReceiverListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker.sock")
+	wln, err := FDReceiverListener(path)
+	if err != nil {
+		t.Fatalf("cannot create receiver: %s", err)
+	}
+	defer wln.Close()
+
+	// A frame without fd must be confirmed and dropped.
+	raw, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("cannot connect to receiver: %s", err)
+	}
+	defer raw.Close()
+	h := FDHeader{RemoteAddr: "127.0.0.1:1"}
+	frame, _ := h.marshal()
+	if _, err = raw.Write(frame); err != nil {
+		t.Fatalf("cannot write frame: %s", err)
+	}
+	ack := make([]byte, 1)
+	if _, err = io.ReadFull(raw, ack); err != nil || ack[0] != fdAck {
+		t.Fatalf("unexpected confirmation %v: %v", ack, err)
+	}
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	p, err := DialFDPasser(path)
+	if err != nil {
+		t.Fatalf("cannot create fd passer: %s", err)
+	}
+	defer p.Close()
+
+	client, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer client.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	if err = p.Pass(c, "hint"); err != nil {
+		t.Fatalf("cannot pass connection: %s", err)
+	}
+
+	wc, err := wln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept passed connection: %s", err)
+	}
+	defer wc.Close()
+	if wc.RemoteAddr().String() != client.LocalAddr().String() {
+		t.Fatalf("unexpected remote address %s. Expecting %s", wc.RemoteAddr(), client.LocalAddr())
+	}
+	if h, ok := ConnFDHeader(wc); !ok || h.Hint != "hint" {
+		t.Fatalf("unexpected header %+v", h)
+	}
+
+	if _, err = wc.Write([]byte("pong")); err != nil {
+		t.Fatalf("cannot write to passed connection: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err = io.ReadFull(client, buf); err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if string(buf) != "pong" {
+		t.Fatalf("unexpected data %q. Expecting %q", buf, "pong")
+	}
+
+	wln.Close()
+	if _, err = wln.Accept(); err != ErrListenerClosed {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrListenerClosed)
+	}
+}
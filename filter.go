@@ -0,0 +1,12 @@
+package tcplisten
+
+// RawInstruction is a classic BPF instruction (struct sock_filter).
+//
+// It has the same layout as golang.org/x/net/bpf.RawInstruction,
+// so programs assembled with that package may be converted element-wise.
+type RawInstruction struct {
+	Op uint16
+	Jt uint8
+	Jf uint8
+	K  uint32
+}
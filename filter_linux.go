@@ -0,0 +1,57 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// filterMu serializes socket filter updates.
+var filterMu sync.Mutex
+
+// UpdateSourceFilter attaches the classic BPF program to the listener,
+// replacing the previously attached one, e.g. for dropping SYNs
+// from blocked prefixes.
+//
+// The program is replaced atomically with a single SO_ATTACH_FILTER call,
+// so every packet is checked either by the old or by the new program.
+// SO_LOCK_FILTER isn't set, so the program may be updated or removed later.
+// Concurrent updates are serialized.
+func UpdateSourceFilter(ln net.Listener, prog []RawInstruction) error {
+	if len(prog) == 0 || len(prog) > 0xFFFF {
+		return fmt.Errorf("cannot attach BPF program with %d instructions", len(prog))
+	}
+	filterMu.Lock()
+	defer filterMu.Unlock()
+
+	return withFd(ln, func(fd uintptr) error {
+		fprog := syscall.SockFprog{
+			Len:    uint16(len(prog)),
+			Filter: (*syscall.SockFilter)(unsafe.Pointer(&prog[0])),
+		}
+		if err := setsockopt(int(fd), syscall.SOL_SOCKET, syscall.SO_ATTACH_FILTER, unsafe.Pointer(&fprog), uint32(unsafe.Sizeof(fprog))); err != nil {
+			return fmt.Errorf("cannot attach BPF program: %s", err)
+		}
+		return nil
+	})
+}
+
+// RemoveSourceFilter detaches the program attached by UpdateSourceFilter.
+//
+// It is no-op if no program is attached.
+func RemoveSourceFilter(ln net.Listener) error {
+	filterMu.Lock()
+	defer filterMu.Unlock()
+
+	return withFd(ln, func(fd uintptr) error {
+		err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_DETACH_FILTER, 0)
+		if err != nil && err != syscall.ENOENT {
+			return fmt.Errorf("cannot detach BPF program: %s", err)
+		}
+		return nil
+	})
+}
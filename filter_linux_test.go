@@ -0,0 +1,47 @@
+package tcplisten
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUpdateSourceFilter(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	// ret #0 drops every packet, including SYNs.
+	dropAll := []RawInstruction{{Op: 0x06, K: 0}}
+	if err = UpdateSourceFilter(ln, dropAll); err != nil {
+		t.Fatalf("cannot attach filter: %s", err)
+	}
+	if c, err := net.DialTimeout("tcp4", ln.Addr().String(), 200*time.Millisecond); err == nil {
+		c.Close()
+		t.Fatalf("filtered listener mustn't accept connections")
+	}
+
+	if err = RemoveSourceFilter(ln); err != nil {
+		t.Fatalf("cannot detach filter: %s", err)
+	}
+	c, err := net.DialTimeout("tcp4", ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("cannot dial after removing filter: %s", err)
+	}
+	c.Close()
+
+	if err = RemoveSourceFilter(ln); err != nil {
+		t.Fatalf("unexpected error when removing missing filter: %s", err)
+	}
+}
@@ -0,0 +1,27 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// UpdateSourceFilter attaches the classic BPF program to the listener.
+//
+// It is supported only on Linux.
+func UpdateSourceFilter(ln net.Listener, prog []RawInstruction) error {
+	return &UnsupportedError{
+		Op:     "UpdateSourceFilter",
+		Reason: "SO_ATTACH_FILTER is supported only on Linux",
+	}
+}
+
+// RemoveSourceFilter detaches the program attached by UpdateSourceFilter.
+//
+// It is supported only on Linux.
+func RemoveSourceFilter(ln net.Listener) error {
+	return &UnsupportedError{
+		Op:     "RemoveSourceFilter",
+		Reason: "SO_ATTACH_FILTER is supported only on Linux",
+	}
+}
@@ -0,0 +1,30 @@
+package tcplisten
+
+// FlowLabelMode controls the IPv6 flow label of packets sent
+// by the connections accepted on tcp6 listeners.
+//
+// Linux binds explicit flow labels registered with IPV6_FLOWLABEL_MGR
+// to a destination, and accepted connections don't inherit them,
+// so only kernel-generated and reflected labels may be requested
+// for listeners.
+type FlowLabelMode int
+
+const (
+	// FlowLabelDefault leaves the flow label at the system default,
+	// see net.ipv6.auto_flowlabels on Linux.
+	FlowLabelDefault FlowLabelMode = iota
+
+	// FlowLabelAuto enables IPV6_AUTOFLOWLABEL, so every connection
+	// sends a stable label derived from the hash of its addresses
+	// and ports. It requires net.ipv6.auto_flowlabels to be 1 or 2.
+	FlowLabelAuto
+
+	// FlowLabelDisabled disables IPV6_AUTOFLOWLABEL, so the connections
+	// send zero labels.
+	FlowLabelDisabled
+
+	// FlowLabelReflect makes the connections send the label received
+	// in the client's SYN (IPV6_FL_F_REFLECT). It requires
+	// net.ipv6.flowlabel_consistency to be 0.
+	FlowLabelReflect
+)
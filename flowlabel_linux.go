@@ -0,0 +1,67 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ipv6FlowlabelMgr  = 32
+	ipv6AutoFlowlabel = 70
+
+	ipv6FlActionGet   = 0
+	ipv6FlFlagReflect = 4
+)
+
+// in6FlowlabelReq is struct in6_flowlabel_req.
+type in6FlowlabelReq struct {
+	dst     [16]byte
+	label   uint32
+	action  uint8
+	share   uint8
+	flags   uint16
+	expires uint16
+	linger  uint16
+	pad     uint32
+}
+
+// setFlowLabel applies the mode to fd and returns the name
+// of the socket option it has set.
+func setFlowLabel(fd int, mode FlowLabelMode, tr tracer) (string, error) {
+	switch mode {
+	case FlowLabelAuto, FlowLabelDisabled:
+		v := 0
+		if mode == FlowLabelAuto {
+			v = 1
+		}
+		if err := tr.setsockoptInt(fd, syscall.IPPROTO_IPV6, ipv6AutoFlowlabel, "IPV6_AUTOFLOWLABEL", v); err != nil {
+			return "", fmt.Errorf("cannot set IPV6_AUTOFLOWLABEL: %s", err)
+		}
+		return "IPV6_AUTOFLOWLABEL", nil
+	case FlowLabelReflect:
+		req := in6FlowlabelReq{
+			action: ipv6FlActionGet,
+			flags:  ipv6FlFlagReflect,
+		}
+		err := setsockopt(fd, syscall.IPPROTO_IPV6, ipv6FlowlabelMgr, unsafe.Pointer(&req), uint32(unsafe.Sizeof(req)))
+		tr.trace(TraceRecord{
+			Call:   "setsockopt",
+			Level:  syscall.IPPROTO_IPV6,
+			Option: "IPV6_FLOWLABEL_MGR",
+			Value:  ipv6FlFlagReflect,
+			Err:    err,
+		})
+		if err == syscall.EPERM {
+			return "", fmt.Errorf("cannot enable flow label reflection: net.ipv6.flowlabel_consistency must be 0: %s", err)
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot enable flow label reflection: %s", err)
+		}
+		return "IPV6_FLOWLABEL_MGR", nil
+	default:
+		return "", fmt.Errorf("invalid FlowLabel mode %d", mode)
+	}
+}
@@ -0,0 +1,62 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"io/ioutil"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestConfigFlowLabel(t *testing.T) {
+	for _, m := range []struct {
+		mode  FlowLabelMode
+		value int
+	}{
+		{FlowLabelAuto, 1},
+		{FlowLabelDisabled, 0},
+	} {
+		ln, err := NewListener("tcp6", "[::1]:0", Config{FlowLabel: m.mode})
+		if err != nil {
+			t.Fatalf("cannot create listener with FlowLabel %d: %s", m.mode, err)
+		}
+		var v int
+		err = withFd(ln, func(fd uintptr) error {
+			v, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IPV6, ipv6AutoFlowlabel)
+			return err
+		})
+		ln.Close()
+		if err != nil {
+			t.Fatalf("cannot obtain IPV6_AUTOFLOWLABEL: %s", err)
+		}
+		if v != m.value {
+			t.Fatalf("unexpected IPV6_AUTOFLOWLABEL %d for FlowLabel %d. Expecting %d", v, m.mode, m.value)
+		}
+	}
+
+	if _, err := NewListener("tcp4", "127.0.0.1:0", Config{FlowLabel: FlowLabelAuto}); err == nil {
+		t.Fatalf("expecting error for FlowLabel on IPv4 listener")
+	}
+	if _, err := NewListener("tcp6", "[::1]:0", Config{FlowLabel: 42}); err == nil {
+		t.Fatalf("expecting error for invalid FlowLabel mode")
+	}
+}
+
+func TestConfigFlowLabelReflect(t *testing.T) {
+	data, err := ioutil.ReadFile("/proc/sys/net/ipv6/flowlabel_consistency")
+	if err != nil {
+		t.Skipf("cannot read net.ipv6.flowlabel_consistency: %s", err)
+	}
+	ln, err := NewListener("tcp6", "[::1]:0", Config{FlowLabel: FlowLabelReflect})
+	if strings.TrimSpace(string(data)) != "0" {
+		if err == nil || !strings.Contains(err.Error(), "flowlabel_consistency") {
+			t.Fatalf("unexpected error %v. Expecting error mentioning flowlabel_consistency", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln.Close()
+}
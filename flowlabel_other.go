@@ -0,0 +1,11 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"fmt"
+)
+
+func setFlowLabel(fd int, mode FlowLabelMode, tr tracer) (string, error) {
+	return "", fmt.Errorf("cannot set FlowLabel: it is supported only on Linux: %w", ErrUnsupportedOption)
+}
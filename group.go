@@ -0,0 +1,63 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// GroupMember describes a listening socket which belongs to a SO_REUSEPORT group.
+type GroupMember struct {
+	// Inode is the inode number of the socket.
+	Inode uint32
+
+	// UID is the owner of the socket.
+	UID uint32
+
+	// PIDs contains processes holding the socket.
+	//
+	// It is empty if the owners cannot be resolved, e.g. due to
+	// insufficient permissions for reading /proc.
+	PIDs []int
+}
+
+// GroupInfo describes the SO_REUSEPORT group a listener belongs to.
+type GroupInfo struct {
+	// Addr is the address shared by the group members.
+	Addr *net.TCPAddr
+
+	// Members contains all the listening sockets bound to Addr,
+	// including the socket the group was looked up for.
+	Members []GroupMember
+}
+
+// Count returns the number of sockets in the group.
+func (gi *GroupInfo) Count() int {
+	return len(gi.Members)
+}
+
+// ForeignReusePortError is returned by NewListener with
+// Config.ExclusiveReusePortGroup if sockets held by other processes
+// already listen with SO_REUSEPORT on the requested address, so binding
+// would silently split incoming connections with them.
+type ForeignReusePortError struct {
+	// Addr is the address the listener has been requested for.
+	Addr string
+
+	// Members contains the sockets held by other processes.
+	Members []GroupMember
+}
+
+func (e *ForeignReusePortError) Error() string {
+	owners := make([]string, 0, len(e.Members))
+	for _, m := range e.Members {
+		if len(m.PIDs) == 0 {
+			owners = append(owners, fmt.Sprintf("uid %d", m.UID))
+			continue
+		}
+		for _, pid := range m.PIDs {
+			owners = append(owners, fmt.Sprintf("pid %d", pid))
+		}
+	}
+	return fmt.Sprintf("tcplisten: cannot bind to %q: %d sockets of other processes already listen on it (%s)", e.Addr, len(e.Members), strings.Join(owners, ", "))
+}
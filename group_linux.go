@@ -0,0 +1,106 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReusePortGroup returns the SO_REUSEPORT group the given listener belongs to.
+//
+// The group consists of all the listening sockets in the current network
+// namespace bound to the same address and port as ln. This is useful
+// for verifying that the old and the new binary share the port
+// during rolling deploys.
+//
+// Socket owners are resolved by scanning /proc, so PIDs of processes
+// owned by other users are visible only with sufficient privileges.
+func ReusePortGroup(ln net.Listener) (GroupInfo, error) {
+	addr, family, err := listenerSockaddr(ln)
+	if err != nil {
+		return GroupInfo{}, err
+	}
+	members, err := reusePortMembers(addr, family)
+	if err != nil {
+		return GroupInfo{}, err
+	}
+	return GroupInfo{
+		Addr:    addr,
+		Members: members,
+	}, nil
+}
+
+// reusePortMembers returns the listening sockets of the given family
+// bound to addr together with their owners.
+func reusePortMembers(addr *net.TCPAddr, family int) ([]GroupMember, error) {
+	msgs, err := inetDiagListeners(family, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []GroupMember
+	inodes := make(map[uint32]int)
+	for _, m := range msgs {
+		if m.sport != addr.Port || !m.src.Equal(addr.IP) {
+			continue
+		}
+		inodes[m.inode] = len(members)
+		members = append(members, GroupMember{
+			Inode: m.inode,
+			UID:   m.uid,
+		})
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	for inode, pids := range socketOwners(inodes) {
+		members[inodes[inode]].PIDs = pids
+	}
+	return members, nil
+}
+
+// socketOwners returns processes holding file descriptors
+// for the given socket inodes.
+func socketOwners(inodes map[uint32]int) map[uint32][]int {
+	owners := make(map[uint32][]int)
+	procs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return owners
+	}
+	for _, p := range procs {
+		pid, err := strconv.Atoi(p.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", p.Name(), "fd")
+		fds, err := ioutil.ReadDir(fdDir)
+		if err != nil {
+			// The process has gone or belongs to another user.
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			n, err := strconv.ParseUint(link[len("socket:["):len(link)-1], 10, 32)
+			if err != nil {
+				continue
+			}
+			inode := uint32(n)
+			if _, ok := inodes[inode]; !ok {
+				continue
+			}
+			if ps := owners[inode]; len(ps) == 0 || ps[len(ps)-1] != pid {
+				owners[inode] = append(ps, pid)
+			}
+		}
+	}
+	return owners
+}
@@ -0,0 +1,101 @@
+package tcplisten
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestReusePortGroup(t *testing.T) {
+	cfg := Config{ReusePort: true}
+	ln1, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln1.Close()
+
+	ln2, err := NewListener("tcp4", ln1.Addr().String(), cfg)
+	if err != nil {
+		t.Fatalf("cannot create second listener: %s", err)
+	}
+	defer ln2.Close()
+
+	gi, err := ReusePortGroup(ln1)
+	if err != nil {
+		t.Skipf("sock_diag is unavailable: %s", err)
+	}
+	if gi.Count() != 2 {
+		t.Fatalf("unexpected group size %d. Expecting 2", gi.Count())
+	}
+	if gi.Addr.Port != ln1.Addr().(*net.TCPAddr).Port {
+		t.Fatalf("unexpected group address %s. Expecting %s", gi.Addr, ln1.Addr())
+	}
+	for _, m := range gi.Members {
+		if m.Inode == 0 {
+			t.Fatalf("unexpected zero inode in %#v", m)
+		}
+		if len(m.PIDs) != 1 || m.PIDs[0] != os.Getpid() {
+			t.Fatalf("unexpected owners %v. Expecting [%d]", m.PIDs, os.Getpid())
+		}
+	}
+}
+
+func TestExclusiveReusePortGroup(t *testing.T) {
+	cfg := Config{ReusePort: true, ExclusiveReusePortGroup: true, Logger: &testLogger{}}
+	ln, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	addr := ln.Addr().String()
+	if _, err = ReusePortGroup(ln); err != nil {
+		ln.Close()
+		t.Skipf("sock_diag is unavailable: %s", err)
+	}
+
+	// Listeners of the current process may share the port.
+	ln2, err := NewListener("tcp4", addr, cfg)
+	if err != nil {
+		t.Fatalf("cannot create second listener: %s", err)
+	}
+	ln2.Close()
+
+	// Hand the listener over to another process.
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("cannot obtain listener file: %s", err)
+	}
+	cmd := exec.Command("sleep", "10")
+	cmd.ExtraFiles = []*os.File{f}
+	if err = cmd.Start(); err != nil {
+		t.Skipf("cannot start child process: %s", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	f.Close()
+	ln.Close()
+
+	_, err = NewListener("tcp4", addr, cfg)
+	var fe *ForeignReusePortError
+	if !errors.As(err, &fe) {
+		t.Fatalf("unexpected error %v. Expecting ForeignReusePortError", err)
+	}
+	if len(fe.Members) != 1 || len(fe.Members[0].PIDs) != 1 || fe.Members[0].PIDs[0] != cmd.Process.Pid {
+		t.Fatalf("unexpected foreign members %+v. Expecting a socket of pid %d", fe.Members, cmd.Process.Pid)
+	}
+
+	var l testLogger
+	cfg.AllowForeignReusePort = true
+	cfg.Logger = &l
+	ln, err = NewListener("tcp4", addr, cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener with AllowForeignReusePort: %s", err)
+	}
+	ln.Close()
+	if len(l.lines) != 1 {
+		t.Fatalf("unexpected log lines %q. Expecting a single line", l.lines)
+	}
+}
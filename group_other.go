@@ -0,0 +1,19 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+)
+
+// ReusePortGroup returns the SO_REUSEPORT group the given listener belongs to.
+//
+// It is supported only on Linux.
+func ReusePortGroup(ln net.Listener) (GroupInfo, error) {
+	return GroupInfo{}, ErrUnsupportedOption
+}
+
+func reusePortMembers(addr *net.TCPAddr, family int) ([]GroupMember, error) {
+	return nil, fmt.Errorf("cannot list SO_REUSEPORT group members: it is supported only on Linux: %w", ErrUnsupportedOption)
+}
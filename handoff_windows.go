@@ -0,0 +1,100 @@
+// +build windows
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modws2_32              = syscall.NewLazyDLL("ws2_32.dll")
+	procWSADuplicateSocket = modws2_32.NewProc("WSADuplicateSocketW")
+	procWSASocket          = modws2_32.NewProc("WSASocketW")
+)
+
+const (
+	// fromProtocolInfo is FROM_PROTOCOL_INFO, i.e. -1.
+	fromProtocolInfo = ^uintptr(0)
+
+	wsaFlagOverlapped      = 0x01
+	wsaFlagNoHandleInherit = 0x80
+)
+
+// ProtocolInfo is the serialized WSAPROTOCOL_INFOW structure describing
+// a listening socket duplicated for another process by ExportListener.
+//
+// It is an opaque blob, which must be passed to the target process
+// by other means, e.g. via a pipe, and is valid only in that process.
+type ProtocolInfo []byte
+
+// ExportListener duplicates the listening socket of ln for the process
+// with the given pid via WSADuplicateSocket, e.g. for handing
+// the listener over to the new binary during graceful upgrades,
+// since Windows has no fd inheritance the Unix way.
+//
+// The target process adopts the socket with ImportListener. Both
+// processes may accept connections from the socket until ln is closed,
+// so there is no gap during the handoff.
+func ExportListener(ln net.Listener, targetPid int) (ProtocolInfo, error) {
+	var info syscall.WSAProtocolInfo
+	err := withFd(ln, func(fd uintptr) error {
+		r, _, err := procWSADuplicateSocket.Call(fd, uintptr(targetPid), uintptr(unsafe.Pointer(&info)))
+		if r != 0 {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot duplicate listener %s for process %d: %s", ln.Addr(), targetPid, err)
+	}
+	b := (*[unsafe.Sizeof(info)]byte)(unsafe.Pointer(&info))
+	return append(ProtocolInfo(nil), b[:]...), nil
+}
+
+// ImportListener adopts the listening socket exported for the current
+// process by ExportListener. The options from cfg are applied
+// as ApplyConfig does.
+//
+// It requires Go 1.25 or newer, since earlier versions of the standard
+// library don't support net.FileListener on Windows.
+func ImportListener(info ProtocolInfo, cfg Config) (net.Listener, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	var pi syscall.WSAProtocolInfo
+	if len(info) != int(unsafe.Sizeof(pi)) {
+		return nil, fmt.Errorf("cannot import listener: unexpected protocol info size %d. Expecting %d", len(info), unsafe.Sizeof(pi))
+	}
+	copy((*[unsafe.Sizeof(pi)]byte)(unsafe.Pointer(&pi))[:], info)
+
+	r, _, err := procWSASocket.Call(fromProtocolInfo, fromProtocolInfo, fromProtocolInfo,
+		uintptr(unsafe.Pointer(&pi)), 0, wsaFlagOverlapped|wsaFlagNoHandleInherit)
+	if syscall.Handle(r) == syscall.InvalidHandle {
+		return nil, fmt.Errorf("cannot import listener: WSASocket failed: %s", err)
+	}
+
+	// net.FileListener duplicates the socket, so the file is closed
+	// in any case.
+	file := os.NewFile(r, "tcplisten.imported")
+	ln, err := net.FileListener(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot import listener: %s", err)
+	}
+	if err = ApplyConfig(ln, cfg); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	res := &ListenResult{Listener: ln}
+	res.BoundAddr, _ = ln.Addr().(*net.TCPAddr)
+	res.Listener = cfg.wrapListener(res.Listener)
+	if cfg.Register {
+		register(ln.Addr().Network(), &cfg, res)
+	}
+	return res.Listener, nil
+}
@@ -0,0 +1,95 @@
+// +build windows
+
+package tcplisten
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// handoffChildEnv makes the test binary act as the process
+// adopting the listener in TestListenerHandoff.
+const handoffChildEnv = "TCPLISTEN_HANDOFF_CHILD"
+
+func TestListenerHandoff(t *testing.T) {
+	if os.Getenv(handoffChildEnv) != "" {
+		runHandoffChild(t)
+		return
+	}
+
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestListenerHandoff$")
+	cmd.Env = append(os.Environ(), handoffChildEnv+"=1")
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("cannot create stdin pipe: %s", err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("cannot start child process: %s", err)
+	}
+
+	info, err := ExportListener(ln, cmd.Process.Pid)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		t.Fatalf("cannot export listener: %s", err)
+	}
+	if _, err = stdin.Write([]byte(hex.EncodeToString(info) + "\n")); err != nil {
+		t.Fatalf("cannot pass protocol info to child process: %s", err)
+	}
+	stdin.Close()
+
+	// The child must accept connections after the parent stops listening.
+	ln.Close()
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial the handed over listener: %s", err)
+	}
+	resp, err := ioutil.ReadAll(c)
+	c.Close()
+	if err != nil {
+		t.Fatalf("cannot read response: %s", err)
+	}
+	if string(resp) != "adopted" {
+		t.Fatalf("unexpected response %q. Expecting %q", resp, "adopted")
+	}
+	if err = cmd.Wait(); err != nil {
+		t.Fatalf("child process failed: %s", err)
+	}
+}
+
+func runHandoffChild(t *testing.T) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		t.Fatalf("cannot read protocol info: %s", err)
+	}
+	info, err := hex.DecodeString(line[:len(line)-1])
+	if err != nil {
+		t.Fatalf("cannot decode protocol info: %s", err)
+	}
+	ln, err := ImportListener(info, Config{})
+	if err != nil {
+		t.Fatalf("cannot import listener: %s", err)
+	}
+	defer ln.Close()
+
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	defer c.Close()
+	if _, err = c.Write([]byte("adopted")); err != nil {
+		t.Fatalf("cannot write response: %s", err)
+	}
+}
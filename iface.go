@@ -0,0 +1,68 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// NewListenersForInterface returns a listener for every IP address
+// assigned to the interface with the given name, e.g. for multi-homed
+// hosts.
+//
+// The network selects the addresses: tcp4 and tcp6 bind only the addresses
+// of the corresponding family, and tcp binds all of them. IPv6 link-local
+// addresses are bound with the interface as the zone. If port is 0,
+// the first listener is bound to a port chosen by the kernel and the rest
+// of the listeners are created on that port.
+//
+// All the listeners are closed if any of them cannot be created.
+func NewListenersForInterface(network, ifaceName string, port int, cfg Config) ([]net.Listener, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("cannot listen on interface %q: unsupported network %q", ifaceName, network)
+	}
+	ifi, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find interface %q: %s", ifaceName, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain addresses of interface %q: %s", ifaceName, err)
+	}
+
+	var lns []net.Listener
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipnet.IP
+		lnet := "tcp6"
+		if ip.To4() != nil {
+			lnet = "tcp4"
+		}
+		if network != "tcp" && network != lnet {
+			continue
+		}
+		host := ip.String()
+		if ip.IsLinkLocalUnicast() && lnet == "tcp6" {
+			host += "%" + ifi.Name
+		}
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		ln, err := NewListener(lnet, addr, cfg)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, err
+		}
+		if port == 0 {
+			port = ln.Addr().(*net.TCPAddr).Port
+		}
+		lns = append(lns, ln)
+	}
+	if len(lns) == 0 {
+		return nil, fmt.Errorf("cannot listen on interface %q: it has no %s addresses", ifaceName, network)
+	}
+	return lns, nil
+}
@@ -0,0 +1,65 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"net"
+	"testing"
+)
+
+// loopbackInterface returns the name of the loopback interface.
+func loopbackInterface(t *testing.T) string {
+	ifis, err := net.Interfaces()
+	if err != nil {
+		t.Skipf("cannot list interfaces: %s", err)
+	}
+	for _, ifi := range ifis {
+		if ifi.Flags&net.FlagLoopback != 0 && ifi.Flags&net.FlagUp != 0 {
+			return ifi.Name
+		}
+	}
+	t.Skipf("no loopback interface")
+	return ""
+}
+
+func TestNewListenersForInterface(t *testing.T) {
+	name := loopbackInterface(t)
+	lns, err := NewListenersForInterface("tcp4", name, 0, Config{})
+	if err != nil {
+		t.Fatalf("cannot create listeners on %q: %s", name, err)
+	}
+	port := lns[0].Addr().(*net.TCPAddr).Port
+	for _, ln := range lns {
+		defer ln.Close()
+		addr := ln.Addr().(*net.TCPAddr)
+		if addr.IP.To4() == nil || !addr.IP.IsLoopback() {
+			t.Fatalf("unexpected address %s on %q", addr, name)
+		}
+		if addr.Port != port {
+			t.Fatalf("unexpected port %d. Expecting %d", addr.Port, port)
+		}
+		c, err := net.Dial("tcp4", addr.String())
+		if err != nil {
+			t.Fatalf("cannot dial %s: %s", addr, err)
+		}
+		c.Close()
+	}
+
+	all, err := NewListenersForInterface("tcp", name, 0, Config{})
+	if err != nil {
+		t.Fatalf("cannot create listeners on %q: %s", name, err)
+	}
+	for _, ln := range all {
+		ln.Close()
+	}
+	if len(all) < len(lns) {
+		t.Fatalf("unexpected number of tcp listeners %d. Expecting at least %d", len(all), len(lns))
+	}
+
+	if _, err = NewListenersForInterface("tcp4", "no-such-interface", 0, Config{}); err == nil {
+		t.Fatalf("expecting error for missing interface")
+	}
+	if _, err = NewListenersForInterface("udp", name, 0, Config{}); err == nil {
+		t.Fatalf("expecting error for udp network")
+	}
+}
@@ -0,0 +1,65 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"testing"
+)
+
+// TestOptionInheritance checks that the options set on the listener
+// are inherited by the accepted connections.
+func TestOptionInheritance(t *testing.T) {
+	const nonZero = -1
+	for _, tc := range []struct {
+		name    string
+		network string
+		addr    string
+		cfg     Config
+		option  Option
+		want    int
+	}{
+		{"NoDelayDefault", "tcp4", "127.0.0.1:0", Config{}, OptionNoDelay, nonZero},
+		{"NoDelay", "tcp4", "127.0.0.1:0", Config{NoDelay: true}, OptionNoDelay, nonZero},
+		{"V6Only", "tcp6", "[::1]:0", Config{V6Only: V6OnlyEnabled}, OptionV6Only, 1},
+		{"MaxPacingRate", "tcp4", "127.0.0.1:0", Config{MaxPacingRate: 1 << 20}, OptionMaxPacingRate, 1 << 20},
+		{"HardwareTimestamping", "tcp4", "127.0.0.1:0", Config{HardwareTimestamping: true}, OptionTimestamping, nonZero},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			so, err := lookupOption(tc.option)
+			if err != nil {
+				t.Skipf("%s isn't supported: %s", tc.option, err)
+			}
+			ln, err := NewListener(tc.network, tc.addr, tc.cfg)
+			if err != nil {
+				if tc.network == "tcp6" {
+					t.Skipf("cannot create tcp6 listener: %s", err)
+				}
+				t.Fatalf("cannot create listener: %s", err)
+			}
+			defer ln.Close()
+
+			c, err := net.Dial(tc.network, ln.Addr().String())
+			if err != nil {
+				t.Fatalf("cannot dial: %s", err)
+			}
+			defer c.Close()
+			sc, err := ln.Accept()
+			if err != nil {
+				t.Fatalf("cannot accept: %s", err)
+			}
+			defer sc.Close()
+
+			var v int
+			if err = withFd(sc, func(fd uintptr) error {
+				v, err = so.get(fd)
+				return err
+			}); err != nil {
+				t.Fatalf("cannot obtain %s of accepted connection: %s", tc.option, err)
+			}
+			if tc.want == nonZero && v == 0 || tc.want != nonZero && v != tc.want {
+				t.Fatalf("unexpected %s %d of accepted connection. Expecting %d", tc.option, v, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package tcplisten
+
+import (
+	"time"
+)
+
+// KeepAliveConfig contains TCP keep-alive options of the accepted
+// connections.
+//
+// It has the fields and the semantics of net.KeepAliveConfig added
+// in Go 1.23. The package defines its own type, since it supports
+// Go versions older than 1.23. With Go 1.23 and newer
+// a net.KeepAliveConfig may be converted with KeepAliveConfigFromNet.
+type KeepAliveConfig struct {
+	// If Enable is true, keep-alive probes are enabled.
+	// Otherwise SO_KEEPALIVE is disabled and the rest of the fields
+	// are ignored.
+	Enable bool
+
+	// Idle is the time that the connection must be idle before
+	// the first keep-alive probe is sent.
+	// If zero, a default value of 15 seconds is used.
+	Idle time.Duration
+
+	// Interval is the time between keep-alive probes.
+	// If zero, a default value of 15 seconds is used.
+	Interval time.Duration
+
+	// Count is the maximum number of keep-alive probes that
+	// can go unanswered before dropping a connection.
+	// If zero, a default value of 9 is used.
+	Count int
+}
+
+// The defaults match the ones of the net package.
+const (
+	defaultKeepAliveIdle     = 15 * time.Second
+	defaultKeepAliveInterval = 15 * time.Second
+	defaultKeepAliveCount    = 9
+)
+
+// keepAliveSeconds returns d rounded up to seconds, or the default
+// if d is zero. It returns -1 if d is negative, i.e. the option must
+// be left unchanged.
+func keepAliveSeconds(d, def time.Duration) int {
+	if d < 0 {
+		return -1
+	}
+	if d == 0 {
+		d = def
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// values returns the idle time and the interval in seconds and the count
+// to set on the socket. Negative values must be left unchanged.
+func (ka *KeepAliveConfig) values() (idle, interval, count int) {
+	idle = keepAliveSeconds(ka.Idle, defaultKeepAliveIdle)
+	interval = keepAliveSeconds(ka.Interval, defaultKeepAliveInterval)
+	count = ka.Count
+	if count == 0 {
+		count = defaultKeepAliveCount
+	}
+	return idle, interval, count
+}
+
+// listenerKeepAlive returns the keep-alive options of the listening socket
+// from Config.KeepAlive and the related fields, or nil if KeepAlive
+// isn't set. Zero fields are converted to negative ones, so they are
+// left unchanged.
+func (cfg *Config) listenerKeepAlive() *KeepAliveConfig {
+	if !cfg.KeepAlive {
+		return nil
+	}
+	ka := &KeepAliveConfig{
+		Enable:   true,
+		Idle:     cfg.KeepAliveIdle,
+		Interval: cfg.KeepAliveInterval,
+		Count:    cfg.KeepAliveCount,
+	}
+	if ka.Idle == 0 {
+		ka.Idle = -1
+	}
+	if ka.Interval == 0 {
+		ka.Interval = -1
+	}
+	if ka.Count == 0 {
+		ka.Count = -1
+	}
+	return ka
+}
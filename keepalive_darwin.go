@@ -0,0 +1,14 @@
+// +build darwin
+
+package tcplisten
+
+import (
+	"syscall"
+)
+
+const (
+	tcpKeepIdle     = syscall.TCP_KEEPALIVE
+	tcpKeepIdleName = "TCP_KEEPALIVE"
+	tcpKeepIntvl    = 0x101
+	tcpKeepCnt      = 0x102
+)
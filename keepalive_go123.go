@@ -0,0 +1,16 @@
+// +build go1.23
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// KeepAliveConfigFromNet returns KeepAliveConfig with the options of kc.
+//
+// Negative durations and counts of net.KeepAliveConfig, which leave
+// the corresponding options unchanged, are kept as is, so they are
+// ignored the same way.
+func KeepAliveConfigFromNet(kc net.KeepAliveConfig) KeepAliveConfig {
+	return KeepAliveConfig(kc)
+}
@@ -0,0 +1,27 @@
+// +build go1.23
+
+package tcplisten
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestKeepAliveConfigFromNet(t *testing.T) {
+	kc := net.KeepAliveConfig{
+		Enable:   true,
+		Idle:     time.Minute,
+		Interval: -1,
+		Count:    3,
+	}
+	expected := KeepAliveConfig{
+		Enable:   true,
+		Idle:     time.Minute,
+		Interval: -1,
+		Count:    3,
+	}
+	if ka := KeepAliveConfigFromNet(kc); ka != expected {
+		t.Fatalf("unexpected config %+v. Expecting %+v", ka, expected)
+	}
+}
@@ -0,0 +1,92 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConfigKeepAlive(t *testing.T) {
+	for _, tc := range []struct {
+		ka       KeepAliveConfig
+		expected [4]int
+	}{
+		{KeepAliveConfig{Enable: true, Idle: 30 * time.Second, Interval: 5 * time.Second, Count: 3}, [4]int{1, 30, 5, 3}},
+		// Negative fields keep the values set by the net package.
+		{KeepAliveConfig{Enable: true, Idle: -1, Interval: -1, Count: 4}, [4]int{1, 15, 15, 4}},
+		{KeepAliveConfig{}, [4]int{0, -1, -1, -1}},
+	} {
+		ka := tc.ka
+		ln, err := NewListener("tcp4", "127.0.0.1:0", Config{KeepAliveConfig: &ka})
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		c := acceptDialed(t, ln, ln.Addr().String())
+		v, err := keepAliveOptions(c)
+		c.Close()
+		ln.Close()
+		if err != nil {
+			t.Fatalf("cannot obtain keep-alive options: %s", err)
+		}
+		if v != tc.expected {
+			t.Fatalf("unexpected keep-alive options %v for %+v. Expecting %v", v, tc.ka, tc.expected)
+		}
+	}
+}
+
+func TestConfigListenerKeepAlive(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{
+		KeepAlive:      true,
+		KeepAliveIdle:  30 * time.Second,
+		KeepAliveCount: 4,
+	})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	v, err := keepAliveOptions(ln)
+	if err != nil {
+		t.Fatalf("cannot obtain keep-alive options: %s", err)
+	}
+	// TCP_KEEPINTVL is left with the system default.
+	if v[0] != 1 || v[1] != 30 || v[3] != 4 {
+		t.Fatalf("unexpected keep-alive options of listener %v. Expecting [1 30 * 4]", v)
+	}
+
+	c := acceptDialed(t, ln, ln.Addr().String())
+	v, err = keepAliveOptions(c)
+	c.Close()
+	if err != nil {
+		t.Fatalf("cannot obtain keep-alive options: %s", err)
+	}
+	// TCP_KEEPINTVL is left as set by the net package.
+	if expected := [4]int{1, 30, 15, 4}; v != expected {
+		t.Fatalf("unexpected keep-alive options of connection %v. Expecting %v", v, expected)
+	}
+}
+
+// keepAliveOptions returns SO_KEEPALIVE, TCP_KEEPIDLE, TCP_KEEPINTVL
+// and TCP_KEEPCNT of the socket. The timers are -1 if keep-alive
+// is disabled.
+func keepAliveOptions(s interface{}) ([4]int, error) {
+	var v [4]int
+	err := withFd(s, func(fd uintptr) error {
+		var err error
+		if v[0], err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE); err != nil {
+			return err
+		}
+		if v[0] == 0 {
+			v[1], v[2], v[3] = -1, -1, -1
+			return nil
+		}
+		for i, opt := range []int{syscall.TCP_KEEPIDLE, syscall.TCP_KEEPINTVL, syscall.TCP_KEEPCNT} {
+			if v[i+1], err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, opt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return v, err
+}
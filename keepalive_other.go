@@ -0,0 +1,12 @@
+// +build !linux,!dragonfly,!freebsd,!netbsd,!darwin,!windows,!plan9
+
+package tcplisten
+
+// The keep-alive timers are system-wide on the rest of the platforms,
+// e.g. net.inet.tcp.keepidle on OpenBSD, so only SO_KEEPALIVE is set.
+const (
+	tcpKeepIdle     = -1
+	tcpKeepIdleName = "TCP_KEEPIDLE"
+	tcpKeepIntvl    = -1
+	tcpKeepCnt      = -1
+)
@@ -0,0 +1,7 @@
+// +build plan9
+
+package tcplisten
+
+func setKeepAlive(fd uintptr, ka *KeepAliveConfig, tr tracer) error {
+	return ErrUnsupportedOption
+}
@@ -0,0 +1,46 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setKeepAlive applies ka to the socket of an accepted connection
+// or a listener.
+//
+// The tcpKeep* constants are defined per platform in keepalive_unix.go,
+// keepalive_darwin.go and keepalive_other.go, since e.g. darwin sets
+// the idle time with TCP_KEEPALIVE instead of TCP_KEEPIDLE. Options
+// the platform lacks, i.e. the ones with negative constants, are left
+// unchanged.
+func setKeepAlive(fd uintptr, ka *KeepAliveConfig, tr tracer) error {
+	if !ka.Enable {
+		if err := tr.setsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, "SO_KEEPALIVE", 0); err != nil {
+			return fmt.Errorf("cannot disable SO_KEEPALIVE: %s", err)
+		}
+		return nil
+	}
+	if err := tr.setsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, "SO_KEEPALIVE", 1); err != nil {
+		return fmt.Errorf("cannot enable SO_KEEPALIVE: %s", err)
+	}
+	idle, interval, count := ka.values()
+	for _, o := range [...]struct {
+		opt   int
+		name  string
+		value int
+	}{
+		{tcpKeepIdle, tcpKeepIdleName, idle},
+		{tcpKeepIntvl, "TCP_KEEPINTVL", interval},
+		{tcpKeepCnt, "TCP_KEEPCNT", count},
+	} {
+		if o.opt < 0 || o.value < 0 {
+			continue
+		}
+		if err := tr.setsockoptInt(int(fd), syscall.IPPROTO_TCP, o.opt, o.name, o.value); err != nil {
+			return fmt.Errorf("cannot set %s to %d: %s", o.name, o.value, err)
+		}
+	}
+	return nil
+}
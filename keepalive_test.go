@@ -0,0 +1,22 @@
+package tcplisten
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepAliveConfigValues(t *testing.T) {
+	for _, tc := range []struct {
+		ka                    KeepAliveConfig
+		idle, interval, count int
+	}{
+		{KeepAliveConfig{Enable: true}, 15, 15, 9},
+		{KeepAliveConfig{Enable: true, Idle: time.Minute, Interval: 1500 * time.Millisecond, Count: 3}, 60, 2, 3},
+		{KeepAliveConfig{Enable: true, Idle: -1, Interval: -1, Count: -1}, -1, -1, -1},
+	} {
+		idle, interval, count := tc.ka.values()
+		if idle != tc.idle || interval != tc.interval || count != tc.count {
+			t.Fatalf("unexpected values %d, %d, %d for %+v. Expecting %d, %d, %d", idle, interval, count, tc.ka, tc.idle, tc.interval, tc.count)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// +build linux dragonfly freebsd netbsd
+
+package tcplisten
+
+import (
+	"syscall"
+)
+
+const (
+	tcpKeepIdle     = syscall.TCP_KEEPIDLE
+	tcpKeepIdleName = "TCP_KEEPIDLE"
+	tcpKeepIntvl    = syscall.TCP_KEEPINTVL
+	tcpKeepCnt      = syscall.TCP_KEEPCNT
+)
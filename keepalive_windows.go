@@ -0,0 +1,48 @@
+// +build windows
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// The options require Windows 10 1709 or newer.
+const (
+	tcpKeepIdle  = 3
+	tcpKeepCnt   = 16
+	tcpKeepIntvl = 17
+)
+
+// setKeepAlive applies ka to the socket of an accepted connection
+// or a listener.
+func setKeepAlive(fd uintptr, ka *KeepAliveConfig, tr tracer) error {
+	h := syscall.Handle(fd)
+	if !ka.Enable {
+		if err := tr.setsockoptInt(h, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, "SO_KEEPALIVE", 0); err != nil {
+			return fmt.Errorf("cannot disable SO_KEEPALIVE: %s", err)
+		}
+		return nil
+	}
+	if err := tr.setsockoptInt(h, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, "SO_KEEPALIVE", 1); err != nil {
+		return fmt.Errorf("cannot enable SO_KEEPALIVE: %s", err)
+	}
+	idle, interval, count := ka.values()
+	for _, o := range [...]struct {
+		opt   int
+		name  string
+		value int
+	}{
+		{tcpKeepIdle, "TCP_KEEPIDLE", idle},
+		{tcpKeepIntvl, "TCP_KEEPINTVL", interval},
+		{tcpKeepCnt, "TCP_KEEPCNT", count},
+	} {
+		if o.value < 0 {
+			continue
+		}
+		if err := tr.setsockoptInt(h, syscall.IPPROTO_TCP, o.opt, o.name, o.value); err != nil {
+			return fmt.Errorf("cannot set %s to %d: %s", o.name, o.value, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package tcplisten
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// errDefaultConfigUsed is returned by SetDefaultConfig after Listen
+// has been called.
+var errDefaultConfigUsed = errors.New("tcplisten: cannot change the default config after Listen has been called")
+
+var defaultConfig struct {
+	mu   sync.Mutex
+	cfg  Config
+	used bool
+}
+
+// SetDefaultConfig sets the Config applied by Listen.
+//
+// It must be called before the first Listen call, e.g. at program init,
+// so all the listeners created by Listen share the same options.
+// It returns an error after Listen has been called.
+func SetDefaultConfig(cfg Config) error {
+	defaultConfig.mu.Lock()
+	defer defaultConfig.mu.Unlock()
+	if defaultConfig.used {
+		return errDefaultConfigUsed
+	}
+	defaultConfig.cfg = cfg
+	return nil
+}
+
+// DefaultConfig returns the Config applied by Listen.
+// It is the zero Config unless SetDefaultConfig has been called.
+func DefaultConfig() Config {
+	defaultConfig.mu.Lock()
+	cfg := defaultConfig.cfg
+	defaultConfig.mu.Unlock()
+	return cfg
+}
+
+// Listen works like net.Listen, but creates the listener with NewListener
+// and the Config set by SetDefaultConfig.
+//
+// It allows replacing net.Listen call sites without passing Config around.
+// Only the networks supported by NewListener may be used.
+func Listen(network, addr string) (net.Listener, error) {
+	defaultConfig.mu.Lock()
+	defaultConfig.used = true
+	cfg := defaultConfig.cfg
+	defaultConfig.mu.Unlock()
+	return NewListener(network, addr, cfg)
+}
@@ -0,0 +1,36 @@
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestListenDefaultConfig(t *testing.T) {
+	defer func() {
+		defaultConfig.mu.Lock()
+		defaultConfig.cfg = Config{}
+		defaultConfig.used = false
+		defaultConfig.mu.Unlock()
+	}()
+
+	var l testLogger
+	cfg := Config{Logger: &l, LogInspectHint: true}
+	if err := SetDefaultConfig(cfg); err != nil {
+		t.Fatalf("cannot set default config: %s", err)
+	}
+	if DefaultConfig().Logger != cfg.Logger {
+		t.Fatalf("unexpected default config")
+	}
+
+	ln, err := Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %s", err)
+	}
+	ln.Close()
+	if len(l.lines) == 0 {
+		t.Fatalf("the default config hasn't been applied")
+	}
+
+	if err = SetDefaultConfig(Config{}); err == nil {
+		t.Fatalf("expecting error when changing the default config after Listen")
+	}
+}
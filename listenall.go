@@ -0,0 +1,147 @@
+package tcplisten
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+)
+
+// Overrides contains per-family options merged over the base Config
+// by ListenAll, e.g. larger buffers or FlowLabel only for IPv6.
+type Overrides struct {
+	// TCP4 and TCP6 are overlaid over the base Config for IPv4
+	// and IPv6 listeners respectively. Nil means no override.
+	TCP4 *Config
+	TCP6 *Config
+
+	// Resolver resolves the hosts of the specs.
+	// net.DefaultResolver is used by default.
+	Resolver *net.Resolver
+}
+
+// Names of the override sets reported in ListenResult.Override.
+const (
+	OverrideTCP4 = "TCP4"
+	OverrideTCP6 = "TCP6"
+)
+
+// overlay returns cfg with the non-zero fields of o replacing
+// the corresponding fields of cfg.
+func (cfg Config) overlay(o *Config) Config {
+	if o == nil {
+		return cfg
+	}
+	dst := reflect.ValueOf(&cfg).Elem()
+	src := reflect.ValueOf(o).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if f := src.Field(i); !f.IsZero() && dst.Field(i).CanSet() {
+			dst.Field(i).Set(f)
+		}
+	}
+	return cfg
+}
+
+// ListenAll creates a listener for every address the specs resolve to,
+// e.g. for binding both the IPv4 and the IPv6 addresses of a host name.
+//
+// The host of every spec is resolved with ov.Resolver and the addresses
+// not matching the network of the spec are skipped. The empty host binds
+// both 0.0.0.0 and [::] with the tcp network, in which case V6Only
+// is enabled for [::], since it would conflict with 0.0.0.0 otherwise.
+// If the port is 0, the first listener of the spec is bound to a port
+// chosen by the kernel and the rest of the spec's listeners are created
+// on that port.
+//
+// The Config of every listener is cfg overlaid with ov.TCP4 or ov.TCP6
+// depending on the address family: the non-zero fields of the override
+// replace the fields of cfg, so an override may enable or change options,
+// but cannot disable the ones enabled in cfg. ListenResult.Override
+// reports the override applied to every listener.
+//
+// All the specs are checked before resolving anything, and SpecErrors
+// listing every invalid spec is returned if some of them are invalid.
+// All the listeners are closed if any of them cannot be created.
+func ListenAll(specs []ListenSpec, cfg Config, ov Overrides) ([]*ListenResult, error) {
+	if err := checkSpecs(specs); err != nil {
+		return nil, err
+	}
+	resolver := ov.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	var results []*ListenResult
+	closeAll := func() {
+		for _, res := range results {
+			res.Close()
+		}
+	}
+	for _, s := range specs {
+		addrs, err := resolveSpec(resolver, s)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		port := -1
+		for _, a := range addrs {
+			if port >= 0 {
+				a.Port = port
+			}
+			network, o, override := "tcp4", ov.TCP4, OverrideTCP4
+			if a.IP.To4() == nil {
+				network, o, override = "tcp6", ov.TCP6, OverrideTCP6
+			}
+			if o == nil {
+				override = ""
+			}
+			lcfg := cfg.overlay(o)
+			if network == "tcp6" && len(addrs) > 1 && a.IP.IsUnspecified() {
+				lcfg.V6Only = V6OnlyEnabled
+			}
+			res, err := NewListenerResult(network, a.String(), lcfg)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			res.Override = override
+			results = append(results, res)
+			if port < 0 {
+				port = res.Listener.Addr().(*net.TCPAddr).Port
+			}
+		}
+	}
+	return results, nil
+}
+
+// resolveSpec returns the addresses the spec must be bound to.
+func resolveSpec(resolver *net.Resolver, s ListenSpec) ([]*net.TCPAddr, error) {
+	host, portStr, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := resolver.LookupPort(context.Background(), "tcp", portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IPAddr
+	if host == "" {
+		ips = []net.IPAddr{{IP: net.IPv4zero}, {IP: net.IPv6unspecified}}
+	} else if ips, err = resolver.LookupIPAddr(context.Background(), host); err != nil {
+		return nil, fmt.Errorf("cannot resolve %q: %s", host, err)
+	}
+
+	var addrs []*net.TCPAddr
+	for _, ip := range ips {
+		isV4 := ip.IP.To4() != nil
+		if (s.Network == "tcp4" && !isV4) || (s.Network == "tcp6" && isV4) {
+			continue
+		}
+		addrs = append(addrs, &net.TCPAddr{IP: ip.IP, Port: port, Zone: ip.Zone})
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("cannot listen on %q: it has no %s addresses", s.Addr, s.Network)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,65 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestConfigOverlay(t *testing.T) {
+	base := Config{Backlog: 32, NoDelay: true, V6Only: V6OnlyDisabled}
+	cfg := base.overlay(&Config{Backlog: 64, ReusePort: true})
+	if cfg.Backlog != 64 || !cfg.ReusePort || !cfg.NoDelay || cfg.V6Only != V6OnlyDisabled {
+		t.Fatalf("unexpected overlaid config %+v", cfg)
+	}
+	if cfg = base.overlay(nil); cfg.Backlog != 32 || cfg.ReusePort {
+		t.Fatalf("unexpected config %+v without override", cfg)
+	}
+}
+
+func TestListenAll(t *testing.T) {
+	results, err := ListenAll([]ListenSpec{{Network: "tcp", Addr: ":0"}}, Config{Backlog: 32}, Overrides{
+		TCP6: &Config{Backlog: 64},
+	})
+	if err != nil {
+		t.Fatalf("cannot create listeners: %s", err)
+	}
+	defer func() {
+		for _, res := range results {
+			res.Close()
+		}
+	}()
+	if len(results) != 2 {
+		t.Fatalf("unexpected number of listeners %d. Expecting 2", len(results))
+	}
+
+	port := results[0].BoundAddr.Port
+	for i, tc := range []struct {
+		override string
+		backlog  int
+		v4       bool
+	}{
+		{"", 32, true},
+		{OverrideTCP6, 64, false},
+	} {
+		res := results[i]
+		if res.Override != tc.override || res.Backlog != tc.backlog {
+			t.Fatalf("unexpected override %q and backlog %d of %s. Expecting %q and %d", res.Override, res.Backlog, res.BoundAddr, tc.override, tc.backlog)
+		}
+		if (res.BoundAddr.IP.To4() != nil) != tc.v4 || res.BoundAddr.Port != port {
+			t.Fatalf("unexpected address %s", res.BoundAddr)
+		}
+	}
+
+	c, err := net.Dial("tcp6", net.JoinHostPort("::1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("cannot dial IPv6 listener: %s", err)
+	}
+	c.Close()
+
+	if _, err = ListenAll([]ListenSpec{{Network: "tcp4", Addr: "[::1]:0"}}, Config{}, Overrides{}); err == nil {
+		t.Fatalf("expecting error for IPv6 address with tcp4")
+	}
+}
@@ -0,0 +1,47 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// acquireLock takes an exclusive flock on the given file, creating it
+// if needed. The lock is held until the returned file is closed.
+func acquireLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open lock file %q: %s", path, err)
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, fmt.Errorf("cannot acquire lock file %q: it is held by another instance", path)
+		}
+		return nil, fmt.Errorf("cannot acquire lock file %q: %s", path, err)
+	}
+	return f, nil
+}
+
+// lockedListener releases the lock file when the listener is closed.
+type lockedListener struct {
+	net.Listener
+	lock      *os.File
+	closeOnce sync.Once
+}
+
+func (ln *lockedListener) Close() error {
+	err := ln.Listener.Close()
+	ln.closeOnce.Do(func() {
+		ln.lock.Close()
+	})
+	return err
+}
+
+func (ln *lockedListener) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(ln.Listener)
+}
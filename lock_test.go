@@ -0,0 +1,32 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigSingletonLock(t *testing.T) {
+	cfg := Config{
+		SingletonLock: filepath.Join(t.TempDir(), "tcplisten.lock"),
+	}
+	ln, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+
+	if ln2, err := NewListener("tcp4", "127.0.0.1:0", cfg); err == nil {
+		ln2.Close()
+		t.Fatalf("expecting error when the lock is held by another listener")
+	}
+
+	if err = ln.Close(); err != nil {
+		t.Fatalf("unexpected error when closing listener: %s", err)
+	}
+	ln, err = NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener after the lock has been released: %s", err)
+	}
+	ln.Close()
+}
@@ -0,0 +1,26 @@
+package tcplisten
+
+import (
+	"log"
+)
+
+// Logger is used for logging messages produced by the package.
+//
+// *log.Logger satisfies the interface.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+// loggerOrDefault returns l or the standard logger if l is nil.
+func loggerOrDefault(l Logger) Logger {
+	if l == nil {
+		return stdLogger{}
+	}
+	return l
+}
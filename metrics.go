@@ -0,0 +1,20 @@
+package tcplisten
+
+// MetricsSink receives metrics reported by the package helpers.
+//
+// Implementations must be safe for concurrent use.
+type MetricsSink interface {
+	// Counter increments the counter with the given name by delta.
+	Counter(name string, delta uint64)
+
+	// Gauge sets the gauge with the given name to value.
+	Gauge(name string, value float64)
+}
+
+// HistogramSink may be implemented by MetricsSink for receiving
+// distributions, e.g. latencies. Helpers reporting histograms skip them
+// if the sink doesn't implement HistogramSink.
+type HistogramSink interface {
+	// Observe adds the value to the histogram with the given name.
+	Observe(name string, value float64)
+}
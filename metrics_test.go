@@ -0,0 +1,39 @@
+package tcplisten
+
+import (
+	"sync"
+)
+
+// testSink records the metrics reported to it.
+type testSink struct {
+	mu         sync.Mutex
+	counters   map[string]uint64
+	gauges     map[string]float64
+	histograms map[string][]float64
+}
+
+func newTestSink() *testSink {
+	return &testSink{
+		counters:   make(map[string]uint64),
+		gauges:     make(map[string]float64),
+		histograms: make(map[string][]float64),
+	}
+}
+
+func (s *testSink) Counter(name string, delta uint64) {
+	s.mu.Lock()
+	s.counters[name] += delta
+	s.mu.Unlock()
+}
+
+func (s *testSink) Gauge(name string, value float64) {
+	s.mu.Lock()
+	s.gauges[name] = value
+	s.mu.Unlock()
+}
+
+func (s *testSink) Observe(name string, value float64) {
+	s.mu.Lock()
+	s.histograms[name] = append(s.histograms[name], value)
+	s.mu.Unlock()
+}
@@ -0,0 +1,30 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+const soIncomingNapiID = 0x38
+
+// ConnNapiID returns the id of the NAPI context (NIC receive queue)
+// the last packet of the given connection has been received from.
+//
+// The id may be used for dispatching the connection to the goroutine
+// or CPU serving the corresponding queue. Zero is returned
+// for connections without NAPI context, e.g. loopback ones.
+func ConnNapiID(conn net.Conn) (int, error) {
+	var id int
+	err := withFd(conn, func(fd uintptr) error {
+		var err error
+		id, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, soIncomingNapiID)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot read SO_INCOMING_NAPI_ID: %s", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,30 @@
+package tcplisten
+
+import (
+	"net"
+	"testing"
+)
+
+func TestConnNapiID(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial listener: %s", err)
+	}
+	defer c.Close()
+
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	defer sc.Close()
+
+	if _, err = ConnNapiID(sc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,15 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// ConnNapiID returns the id of the NAPI context (NIC receive queue)
+// the last packet of the given connection has been received from.
+//
+// It is supported only on Linux.
+func ConnNapiID(conn net.Conn) (int, error) {
+	return 0, ErrUnsupportedOption
+}
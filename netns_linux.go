@@ -0,0 +1,81 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// NewListenerInNS works like NewListener, but creates the listener
+// in the network namespace at nsPath, e.g. /run/netns/tenant1.
+//
+// The socket is created, bound and put into listening state from an OS
+// thread switched to the namespace, which is switched back afterwards.
+// The returned listener keeps serving the namespace while being used
+// from the current one.
+//
+// Creating listeners in other namespaces requires CAP_SYS_ADMIN.
+func NewListenerInNS(nsPath, network, addr string, cfg Config) (net.Listener, error) {
+	type result struct {
+		ln  net.Listener
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		// The goroutine may exit with the thread still locked if the
+		// namespace cannot be restored. The runtime terminates such
+		// threads, so they aren't reused in the wrong namespace.
+		ln, err := listenInNS(nsPath, network, addr, cfg)
+		ch <- result{ln, err}
+	}()
+	r := <-ch
+	return r.ln, r.err
+}
+
+func listenInNS(nsPath, network, addr string, cfg Config) (ln net.Listener, err error) {
+	runtime.LockOSThread()
+
+	orig, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", syscall.Gettid()))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return nil, fmt.Errorf("cannot open the current network namespace: %s", err)
+	}
+	defer orig.Close()
+
+	target, err := os.Open(nsPath)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return nil, fmt.Errorf("cannot open network namespace %q: %s", nsPath, err)
+	}
+	defer target.Close()
+
+	if err = setns(int(target.Fd()), syscall.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return nil, fmt.Errorf("cannot enter network namespace %q: %s", nsPath, err)
+	}
+	defer func() {
+		if rerr := setns(int(orig.Fd()), syscall.CLONE_NEWNET); rerr != nil {
+			if ln != nil {
+				ln.Close()
+				ln = nil
+			}
+			err = fmt.Errorf("cannot restore the network namespace after listening in %q: %s", nsPath, rerr)
+			return
+		}
+		runtime.UnlockOSThread()
+	}()
+
+	return NewListener(network, addr, cfg)
+}
+
+func setns(fd, nstype int) error {
+	_, _, e := syscall.Syscall(sysSetns, uintptr(fd), uintptr(nstype), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
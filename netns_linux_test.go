@@ -0,0 +1,61 @@
+package tcplisten
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+// newTestNetNS creates a network namespace and returns a file referring to it.
+func newTestNetNS(t *testing.T) *os.File {
+	type result struct {
+		f   *os.File
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		runtime.LockOSThread()
+		// The thread stays locked, so it is terminated on exit
+		// instead of being reused in the new namespace.
+		if err := syscall.Unshare(syscall.CLONE_NEWNET); err != nil {
+			ch <- result{nil, err}
+			return
+		}
+		f, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", syscall.Gettid()))
+		ch <- result{f, err}
+	}()
+	r := <-ch
+	if r.err != nil {
+		t.Skipf("cannot create network namespace: %s", r.err)
+	}
+	return r.f
+}
+
+func TestNewListenerInNS(t *testing.T) {
+	ns := newTestNetNS(t)
+	defer ns.Close()
+
+	// The thread which created the namespace is gone, so refer
+	// to the namespace via the open file.
+	nsPath := fmt.Sprintf("/proc/self/fd/%d", ns.Fd())
+
+	const addr = "0.0.0.0:10084"
+	ln, err := NewListenerInNS(nsPath, "tcp4", addr, Config{DeferAccept: true})
+	if err != nil {
+		t.Fatalf("cannot create listener in namespace: %s", err)
+	}
+	defer ln.Close()
+
+	// The port is busy only in the other namespace.
+	ln2, err := NewListener("tcp4", addr, Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener in the current namespace: %s", err)
+	}
+	ln2.Close()
+
+	if _, err = NewListenerInNS("/nonexistent", "tcp4", addr, Config{}); err == nil {
+		t.Fatalf("expecting error for missing namespace")
+	}
+}
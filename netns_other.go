@@ -0,0 +1,18 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// NewListenerInNS works like NewListener, but creates the listener
+// in the given network namespace.
+//
+// Network namespaces exist only on Linux.
+func NewListenerInNS(nsPath, network, addr string, cfg Config) (net.Listener, error) {
+	return nil, &UnsupportedError{
+		Op:     "NewListenerInNS",
+		Reason: "network namespaces exist only on Linux",
+	}
+}
@@ -0,0 +1,216 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+)
+
+// Option is a socket option of a listening socket, which may be queried
+// with GetOption and changed with SetOption.
+type Option int
+
+const (
+	// OptionDeferAccept is TCP_DEFER_ACCEPT. The value is the timeout
+	// in seconds.
+	OptionDeferAccept Option = iota + 1
+
+	// OptionFastOpen is TCP_FASTOPEN. The value is the maximum length
+	// of the queue of pending TFO requests.
+	OptionFastOpen
+
+	// OptionNoDelay is TCP_NODELAY inherited by the accepted connections.
+	OptionNoDelay
+
+	// OptionQuickACK is TCP_QUICKACK.
+	OptionQuickACK
+
+	// OptionReuseAddr is SO_REUSEADDR. It cannot be changed after listen.
+	OptionReuseAddr
+
+	// OptionReusePort is SO_REUSEPORT. It cannot be changed after listen.
+	OptionReusePort
+
+	// OptionV6Only is IPV6_V6ONLY. It cannot be changed after listen.
+	OptionV6Only
+
+	// OptionReusePortLB is SO_REUSEPORT_LB. It cannot be changed after listen.
+	OptionReusePortLB
+
+	// OptionMaxPacingRate is SO_MAX_PACING_RATE in bytes per second.
+	OptionMaxPacingRate
+
+	// OptionTimestamping is SO_TIMESTAMPING. The value is a set
+	// of SOF_TIMESTAMPING_* flags.
+	OptionTimestamping
+)
+
+// OptionKind is the kind of the option value.
+type OptionKind int
+
+const (
+	// KindBool is an option which is either enabled or disabled.
+	KindBool OptionKind = iota + 1
+
+	// KindInt is an integer option, e.g. a queue length or a rate.
+	KindInt
+
+	// KindDuration is a timeout option.
+	KindDuration
+
+	// KindString is a string option, e.g. a name of an algorithm.
+	KindString
+
+	// KindBytes is an option with a binary value.
+	KindBytes
+)
+
+// OptionPhase is the stage of the listener life when the option
+// may be applied.
+type OptionPhase int
+
+const (
+	// PhasePreBind is for options which must be set before bind
+	// and cannot be changed after listen.
+	PhasePreBind OptionPhase = iota + 1
+
+	// PhasePostListen is for options which may also be changed
+	// on a listening socket, e.g. with SetOption or ApplyConfig.
+	PhasePostListen
+)
+
+// OptionSpec describes an option known to the package.
+type OptionSpec struct {
+	// Option is the option identifier.
+	Option Option
+
+	// Name is the socket option name, e.g. "TCP_NODELAY".
+	Name string
+
+	// Kind is the kind of the option value.
+	Kind OptionKind
+
+	// Platforms contains GOOS values of the platforms supporting
+	// the option.
+	Platforms []string
+
+	// Phase is the stage of the listener life when the option
+	// may be applied.
+	Phase OptionPhase
+
+	// ConfigField is the name of the Config field controlling the option.
+	// It is empty for options which NewListener sets by default,
+	// e.g. SO_REUSEADDR, which may only be disabled with
+	// Config.DisableReuseAddr.
+	ConfigField string
+}
+
+var (
+	platformsLinux = []string{"linux"}
+	platformsUnix  = []string{"linux", "darwin", "dragonfly", "freebsd", "netbsd", "openbsd"}
+	platformsAll   = []string{"linux", "darwin", "dragonfly", "freebsd", "netbsd", "openbsd", "windows"}
+)
+
+// optionTable describes the options known to the package. It is used
+// for applying Config, by GetOption and SetOption and by Options.
+//
+// The platform-specific level and option numbers are in optionSockopts.
+var optionTable = [...]OptionSpec{
+	OptionDeferAccept:   {OptionDeferAccept, "TCP_DEFER_ACCEPT", KindDuration, platformsLinux, PhasePostListen, "DeferAccept"},
+	OptionFastOpen:      {OptionFastOpen, "TCP_FASTOPEN", KindInt, platformsLinux, PhasePostListen, "FastOpen"},
+	OptionNoDelay:       {OptionNoDelay, "TCP_NODELAY", KindBool, platformsAll, PhasePostListen, "NoDelay"},
+	OptionQuickACK:      {OptionQuickACK, "TCP_QUICKACK", KindBool, platformsLinux, PhasePostListen, "QuickACK"},
+	OptionReuseAddr:     {OptionReuseAddr, "SO_REUSEADDR", KindBool, platformsAll, PhasePreBind, ""},
+	OptionReusePort:     {OptionReusePort, "SO_REUSEPORT", KindBool, platformsUnix, PhasePreBind, "ReusePort"},
+	OptionV6Only:        {OptionV6Only, "IPV6_V6ONLY", KindBool, platformsAll, PhasePreBind, "V6Only"},
+	OptionReusePortLB:   {OptionReusePortLB, "SO_REUSEPORT_LB", KindBool, []string{"freebsd"}, PhasePreBind, "ReusePortLB"},
+	OptionMaxPacingRate: {OptionMaxPacingRate, "SO_MAX_PACING_RATE", KindInt, platformsLinux, PhasePostListen, "MaxPacingRate"},
+	OptionTimestamping:  {OptionTimestamping, "SO_TIMESTAMPING", KindInt, platformsLinux, PhasePostListen, "HardwareTimestamping"},
+}
+
+// Options returns the specs of all the options known to the package,
+// in the order of Option values.
+func Options() []OptionSpec {
+	specs := make([]OptionSpec, 0, len(optionTable)-1)
+	for _, spec := range optionTable[1:] {
+		spec.Platforms = append([]string(nil), spec.Platforms...)
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// sockopt is the level and the number of a socket option.
+type sockopt struct {
+	level int
+	opt   int
+}
+
+func (o Option) valid() bool {
+	return o > 0 && int(o) < len(optionTable)
+}
+
+// String returns the name of the socket option, e.g. "TCP_NODELAY".
+func (o Option) String() string {
+	if !o.valid() {
+		return fmt.Sprintf("Option(%d)", int(o))
+	}
+	return optionTable[o].Name
+}
+
+// ImmutableOptionError is returned by SetOption for options which
+// cannot be changed on a listening socket.
+type ImmutableOptionError struct {
+	Option Option
+}
+
+func (e *ImmutableOptionError) Error() string {
+	return fmt.Sprintf("tcplisten: %s cannot be changed after listen", e.Option)
+}
+
+// lookupOption returns the socket option for o on the current platform.
+func lookupOption(o Option) (sockopt, error) {
+	if !o.valid() {
+		return sockopt{}, fmt.Errorf("tcplisten: unknown option %d", int(o))
+	}
+	so, ok := optionSockopts[o]
+	if !ok {
+		return sockopt{}, fmt.Errorf("cannot use %s: %w", o, ErrUnsupportedOption)
+	}
+	return so, nil
+}
+
+// GetOption returns the current value of the option on the listener.
+func GetOption(ln net.Listener, o Option) (int, error) {
+	so, err := lookupOption(o)
+	if err != nil {
+		return 0, err
+	}
+	var v int
+	err = withFd(ln, func(fd uintptr) error {
+		var err error
+		if v, err = so.get(fd); err != nil {
+			return fmt.Errorf("cannot obtain %s: %s", o, err)
+		}
+		return nil
+	})
+	return v, err
+}
+
+// SetOption changes the option on the listener.
+//
+// It returns *ImmutableOptionError for options which take effect only
+// before listen.
+func SetOption(ln net.Listener, o Option, v int) error {
+	so, err := lookupOption(o)
+	if err != nil {
+		return err
+	}
+	if optionTable[o].Phase != PhasePostListen {
+		return &ImmutableOptionError{Option: o}
+	}
+	return withFd(ln, func(fd uintptr) error {
+		if err := so.set(fd, v); err != nil {
+			return fmt.Errorf("cannot set %s to %d: %s", o, v, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,17 @@
+// +build plan9
+
+package tcplisten
+
+// Plan 9 has no socket options.
+var optionSockopts = map[Option]sockopt{}
+
+// levelNames is empty, since there are no socket option levels on Plan 9.
+var levelNames = map[int]string{}
+
+func (so sockopt) get(fd uintptr) (int, error) {
+	return 0, ErrUnsupportedOption
+}
+
+func (so sockopt) set(fd uintptr, v int) error {
+	return ErrUnsupportedOption
+}
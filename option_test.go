@@ -0,0 +1,129 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestGetSetOption(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	for _, v := range []int{0, 1} {
+		if err = SetOption(ln, OptionNoDelay, v); err != nil {
+			t.Fatalf("cannot set %s: %s", OptionNoDelay, err)
+		}
+		n, err := GetOption(ln, OptionNoDelay)
+		if err != nil {
+			t.Fatalf("cannot obtain %s: %s", OptionNoDelay, err)
+		}
+		if (n != 0) != (v != 0) {
+			t.Fatalf("unexpected %s value %d. Expecting %d", OptionNoDelay, n, v)
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		if err = SetOption(ln, OptionDeferAccept, 5); err != nil {
+			t.Fatalf("cannot set %s: %s", OptionDeferAccept, err)
+		}
+		n, err := GetOption(ln, OptionDeferAccept)
+		if err != nil {
+			t.Fatalf("cannot obtain %s: %s", OptionDeferAccept, err)
+		}
+		if n == 0 {
+			t.Fatalf("%s hasn't been set", OptionDeferAccept)
+		}
+	}
+}
+
+func TestSetOptionImmutable(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	err = SetOption(ln, OptionReuseAddr, 0)
+	var ie *ImmutableOptionError
+	if !errors.As(err, &ie) || ie.Option != OptionReuseAddr {
+		t.Fatalf("unexpected error %v. Expecting ImmutableOptionError for %s", err, OptionReuseAddr)
+	}
+	if _, err = GetOption(ln, OptionReuseAddr); err != nil {
+		t.Fatalf("cannot obtain %s: %s", OptionReuseAddr, err)
+	}
+
+	if err = SetOption(ln, Option(0), 1); err == nil {
+		t.Fatalf("expecting error for unknown option")
+	}
+}
+
+func TestOptionsPlatforms(t *testing.T) {
+	for _, spec := range Options() {
+		if spec.Name != spec.Option.String() {
+			t.Fatalf("unexpected name %q of %s", spec.Name, spec.Option)
+		}
+		supported := false
+		for _, goos := range spec.Platforms {
+			if goos == runtime.GOOS {
+				supported = true
+			}
+		}
+		_, err := lookupOption(spec.Option)
+		if supported != (err == nil) {
+			t.Fatalf("the spec of %s disagrees with the platform: supported=%v, lookup error %v", spec.Option, supported, err)
+		}
+	}
+}
+
+func TestOptionsConfigFields(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows applies only a few options from Config")
+	}
+	cfgType := reflect.TypeOf(Config{})
+	for _, spec := range Options() {
+		if spec.ConfigField == "" {
+			continue
+		}
+		f, ok := cfgType.FieldByName(spec.ConfigField)
+		if !ok {
+			t.Fatalf("Config has no field %s for %s", spec.ConfigField, spec.Option)
+		}
+		if _, err := lookupOption(spec.Option); err != nil {
+			continue
+		}
+
+		var cfg Config
+		network, addr := "tcp4", "127.0.0.1:0"
+		v := reflect.ValueOf(&cfg).Elem().FieldByIndex(f.Index)
+		switch {
+		case f.Type == reflect.TypeOf(V6OnlyEnabled):
+			v.Set(reflect.ValueOf(V6OnlyEnabled))
+			network, addr = "tcp6", "[::1]:0"
+		case v.Kind() == reflect.Bool:
+			v.SetBool(true)
+		case v.Kind() == reflect.Uint64:
+			v.SetUint(1 << 20)
+		default:
+			t.Fatalf("unexpected type %s of Config.%s", f.Type, f.Name)
+		}
+
+		res, err := NewListenerResult(network, addr, cfg)
+		if err != nil {
+			if network == "tcp6" {
+				continue
+			}
+			t.Fatalf("cannot create listener with Config.%s: %s", f.Name, err)
+		}
+		res.Close()
+		if !res.hasOption(spec.Name) {
+			t.Fatalf("Config.%s hasn't applied %s. Applied options: %v", f.Name, spec.Name, res.AppliedOptions)
+		}
+	}
+}
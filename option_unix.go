@@ -0,0 +1,32 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"syscall"
+)
+
+// levelNames contains the names of socket option levels for Config.Explain.
+var levelNames = map[int]string{
+	syscall.SOL_SOCKET:   "SOL_SOCKET",
+	syscall.IPPROTO_IP:   "IPPROTO_IP",
+	syscall.IPPROTO_IPV6: "IPPROTO_IPV6",
+	syscall.IPPROTO_TCP:  "IPPROTO_TCP",
+}
+
+func (so sockopt) get(fd uintptr) (int, error) {
+	return syscall.GetsockoptInt(int(fd), so.level, so.opt)
+}
+
+func (so sockopt) set(fd uintptr, v int) error {
+	return syscall.SetsockoptInt(int(fd), so.level, so.opt, v)
+}
+
+// setOption sets the option from the option table and traces the call.
+func (tr tracer) setOption(fd int, o Option, v int) error {
+	so, err := lookupOption(o)
+	if err != nil {
+		return err
+	}
+	return tr.setsockoptInt(fd, so.level, so.opt, o.String(), v)
+}
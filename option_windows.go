@@ -0,0 +1,57 @@
+// +build windows
+
+package tcplisten
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var optionSockopts = map[Option]sockopt{
+	OptionNoDelay:   {syscall.IPPROTO_TCP, syscall.TCP_NODELAY},
+	OptionReuseAddr: {syscall.SOL_SOCKET, syscall.SO_REUSEADDR},
+	OptionV6Only:    {syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY},
+}
+
+// levelNames contains the names of socket option levels for Config.Explain.
+var levelNames = map[int]string{
+	syscall.SOL_SOCKET:   "SOL_SOCKET",
+	syscall.IPPROTO_IP:   "IPPROTO_IP",
+	syscall.IPPROTO_IPV6: "IPPROTO_IPV6",
+	syscall.IPPROTO_TCP:  "IPPROTO_TCP",
+}
+
+func (so sockopt) get(fd uintptr) (int, error) {
+	var v int32
+	l := int32(unsafe.Sizeof(v))
+	if err := syscall.Getsockopt(syscall.Handle(fd), int32(so.level), int32(so.opt), (*byte)(unsafe.Pointer(&v)), &l); err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+func (so sockopt) set(fd uintptr, v int) error {
+	return syscall.SetsockoptInt(syscall.Handle(fd), so.level, so.opt, v)
+}
+
+// setOption sets the option from the option table and traces the call.
+func (tr tracer) setOption(fd syscall.Handle, o Option, v int) error {
+	so, err := lookupOption(o)
+	if err != nil {
+		return err
+	}
+	return tr.setsockoptInt(fd, so.level, so.opt, o.String(), v)
+}
+
+// setsockoptInt calls syscall.SetsockoptInt and traces the call.
+func (tr tracer) setsockoptInt(fd syscall.Handle, level, opt int, option string, value int) error {
+	err := syscall.SetsockoptInt(fd, level, opt, value)
+	tr.trace(TraceRecord{
+		Call:   "setsockopt",
+		Level:  level,
+		Option: option,
+		Value:  value,
+		Err:    err,
+	})
+	return err
+}
@@ -0,0 +1,79 @@
+package tcplisten
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// orderedConfigFields are the names accepted in Config.OptionOrder
+// for the options missing from the option table.
+var orderedConfigFields = [...]string{"DisableRecvAutotune", "Transparent", "ServiceClass", "ReceiveBufferSize", "SendBufferSize", "FlowLabel", "KeepAlive", "InitialRTO"}
+
+// optionStep applies a single option from Config.
+type optionStep struct {
+	// name is the name of the option in Config.OptionOrder.
+	name  string
+	apply func() error
+}
+
+// applyOptionSteps applies the steps in the order of Config.OptionOrder
+// if it is set, or in the given order otherwise.
+func (cfg *Config) applyOptionSteps(steps []optionStep) error {
+	if len(cfg.OptionOrder) > 0 {
+		pos := make(map[string]int, len(cfg.OptionOrder))
+		for i, name := range cfg.OptionOrder {
+			pos[name] = i
+		}
+		var missing []string
+		for _, s := range steps {
+			if _, ok := pos[s.name]; !ok && !containsString(missing, s.name) {
+				missing = append(missing, s.name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("cannot apply options: Config.OptionOrder doesn't name %s", strings.Join(missing, ", "))
+		}
+		sort.SliceStable(steps, func(i, j int) bool {
+			return pos[steps[i].name] < pos[steps[j].name]
+		})
+	}
+	for _, s := range steps {
+		if err := s.apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOptionOrder checks Config.OptionOrder contains only known
+// names without duplicates.
+func (cfg *Config) validateOptionOrder() error {
+	for i, name := range cfg.OptionOrder {
+		if !knownOrderName(name) {
+			return fmt.Errorf("unknown option %q in Config.OptionOrder", name)
+		}
+		if containsString(cfg.OptionOrder[:i], name) {
+			return fmt.Errorf("duplicate option %q in Config.OptionOrder", name)
+		}
+	}
+	return nil
+}
+
+func knownOrderName(name string) bool {
+	for _, spec := range optionTable[1:] {
+		if spec.Name == name {
+			return true
+		}
+	}
+	return containsString(orderedConfigFields[:], name)
+}
+
+func containsString(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
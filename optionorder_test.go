@@ -0,0 +1,44 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigOptionOrder(t *testing.T) {
+	var options []string
+	cfg := Config{
+		ReusePort:   true,
+		OptionOrder: []string{"SO_REUSEPORT", "TCP_NODELAY", "SO_REUSEADDR"},
+		Trace: func(r TraceRecord) {
+			if r.Call == "setsockopt" {
+				options = append(options, r.Option)
+			}
+		},
+	}
+	ln, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln.Close()
+	if strings.Join(options, ",") != "SO_REUSEPORT,TCP_NODELAY,SO_REUSEADDR" {
+		t.Fatalf("unexpected order of options %q. Expecting SO_REUSEPORT,TCP_NODELAY,SO_REUSEADDR", options)
+	}
+
+	cfg.Trace = nil
+	cfg.NoDelay = true
+	cfg.OptionOrder = []string{"SO_REUSEPORT", "SO_REUSEADDR"}
+	_, err = NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err == nil || !strings.Contains(err.Error(), "TCP_NODELAY") {
+		t.Fatalf("unexpected error %v. Expecting error about unnamed TCP_NODELAY", err)
+	}
+
+	for _, order := range [][]string{{"SO_REUSEPORT", "TCP_NODELAY", "SO_REUSEADDR", "SO_RCVLOWAT"}, {"SO_REUSEPORT", "TCP_NODELAY", "SO_REUSEADDR", "SO_REUSEPORT"}} {
+		cfg.OptionOrder = order
+		if _, err = NewListener("tcp4", "127.0.0.1:0", cfg); err == nil {
+			t.Fatalf("expecting error for Config.OptionOrder %q", order)
+		}
+	}
+}
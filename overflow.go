@@ -0,0 +1,92 @@
+package tcplisten
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Metric names reported by QueueMonitor.Run.
+const (
+	MetricListenQueueLen = "tcplisten_listen_queue_len"
+	MetricListenQueueMax = "tcplisten_listen_queue_max"
+	MetricListenDrops    = "tcplisten_listen_drops"
+	MetricListenSynLen   = "tcplisten_listen_syn_queue_len"
+)
+
+// QueueSample is a snapshot of the listener's accept queue.
+type QueueSample struct {
+	// Len is the number of connections waiting for Accept.
+	Len int
+
+	// Max is the maximum length of the accept queue.
+	Max int
+
+	// Drops is the total number of incoming connections dropped
+	// by the listener since its creation, e.g. due to accept queue overflow.
+	//
+	// It is valid only if DropsAvailable is true.
+	Drops uint64
+
+	// DropsAvailable is false if the kernel doesn't expose per-listener
+	// drop counters.
+	DropsAvailable bool
+
+	// SynLen is the number of connections waiting for the handshake
+	// to complete, i.e. the length of the SYN queue.
+	//
+	// It is valid only if SynLenAvailable is true, which is the case
+	// only on FreeBSD.
+	SynLen int
+
+	// SynLenAvailable is false if the kernel doesn't expose the SYN queue
+	// length of the listener.
+	SynLenAvailable bool
+}
+
+// QueueMonitor samples the accept queue of a single listener.
+//
+// Use OverflowMonitor for creating QueueMonitor.
+type QueueMonitor struct {
+	ln    net.Listener
+	inode uint64
+	fam   int
+}
+
+// Run samples the accept queue every interval and reports it to sink
+// until ctx is done.
+//
+// The drop counter is reported as a delta since the previous sample.
+// It is not reported at all if drops aren't available, so the absence
+// of the metric may be told apart from zero drops.
+func (m *QueueMonitor) Run(ctx context.Context, interval time.Duration, sink MetricsSink) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var prevDrops uint64
+	first := true
+	for {
+		s, err := m.Sample()
+		if err != nil {
+			return err
+		}
+		sink.Gauge(MetricListenQueueLen, float64(s.Len))
+		sink.Gauge(MetricListenQueueMax, float64(s.Max))
+		if s.SynLenAvailable {
+			sink.Gauge(MetricListenSynLen, float64(s.SynLen))
+		}
+		if s.DropsAvailable {
+			if !first && s.Drops >= prevDrops {
+				sink.Counter(MetricListenDrops, s.Drops-prevDrops)
+			}
+			prevDrops = s.Drops
+			first = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// +build freebsd
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// OverflowMonitor returns a monitor reporting accept queue usage
+// of the given listener.
+//
+// The queues are read with SO_LISTENQLEN, SO_LISTENINCQLEN
+// and SO_LISTENQLIMIT. FreeBSD doesn't count drops per listener,
+// so QueueSample.DropsAvailable is always false.
+func OverflowMonitor(ln net.Listener) (*QueueMonitor, error) {
+	if _, _, err := AcceptQueueLen(ln); err != nil {
+		return nil, err
+	}
+	return &QueueMonitor{
+		ln: ln,
+	}, nil
+}
+
+// Sample returns the current state of the listener's accept queue.
+func (m *QueueMonitor) Sample() (QueueSample, error) {
+	var s QueueSample
+	err := withFd(m.ln, func(fd uintptr) error {
+		var err error
+		if s.Len, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_LISTENQLEN); err != nil {
+			return fmt.Errorf("cannot obtain SO_LISTENQLEN: %s", err)
+		}
+		if s.Max, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_LISTENQLIMIT); err != nil {
+			return fmt.Errorf("cannot obtain SO_LISTENQLIMIT: %s", err)
+		}
+		if s.SynLen, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_LISTENINCQLEN); err != nil {
+			return fmt.Errorf("cannot obtain SO_LISTENINCQLEN: %s", err)
+		}
+		s.SynLenAvailable = true
+		return nil
+	})
+	return s, err
+}
+
+// AcceptQueueLen returns the number of connections waiting for Accept
+// and the maximum length of the accept queue of the listener.
+//
+// It reads SO_LISTENQLEN and SO_LISTENQLIMIT, so it is cheap enough
+// to be called on every Accept for load-shedding.
+func AcceptQueueLen(ln net.Listener) (current, max int, err error) {
+	err = withFd(ln, func(fd uintptr) error {
+		if current, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_LISTENQLEN); err != nil {
+			return fmt.Errorf("cannot obtain SO_LISTENQLEN: %s", err)
+		}
+		if max, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_LISTENQLIMIT); err != nil {
+			return fmt.Errorf("cannot obtain SO_LISTENQLIMIT: %s", err)
+		}
+		return nil
+	})
+	return current, max, err
+}
@@ -0,0 +1,86 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+const (
+	inetDiagSkMemInfo = 7
+	skMemInfoDrops    = 8
+)
+
+// OverflowMonitor returns a monitor reporting accept queue usage
+// and drops attributable to the given listener.
+//
+// The queue is sampled via NETLINK_SOCK_DIAG. Drops are taken from
+// the listener's sk_drops counter exposed in INET_DIAG_SKMEMINFO,
+// which the kernel increments on accept queue overflows. Kernels
+// which do not expose the counter result in QueueSample.DropsAvailable
+// set to false. Tracing the tcp_listen_overflow events via perf or eBPF
+// isn't implemented.
+func OverflowMonitor(ln net.Listener) (*QueueMonitor, error) {
+	_, fam, err := listenerSockaddr(ln)
+	if err != nil {
+		return nil, err
+	}
+	var st syscall.Stat_t
+	if err = withFd(ln, func(fd uintptr) error {
+		return syscall.Fstat(int(fd), &st)
+	}); err != nil {
+		return nil, fmt.Errorf("cannot obtain listener inode: %s", err)
+	}
+	return &QueueMonitor{
+		ln:    ln,
+		inode: uint64(st.Ino),
+		fam:   fam,
+	}, nil
+}
+
+// Sample returns the current state of the listener's accept queue.
+func (m *QueueMonitor) Sample() (QueueSample, error) {
+	msgs, err := inetDiagListeners(m.fam, 1<<(inetDiagSkMemInfo-1))
+	if err != nil {
+		return QueueSample{}, err
+	}
+	for _, msg := range msgs {
+		if uint64(msg.inode) != m.inode {
+			continue
+		}
+		s := QueueSample{
+			Len: int(msg.rqueue),
+			Max: int(msg.wqueue),
+		}
+		if mi := msg.attrs[inetDiagSkMemInfo]; len(mi) >= 4*(skMemInfoDrops+1) {
+			s.Drops = uint64(nativeEndian.Uint32(mi[4*skMemInfoDrops:]))
+			s.DropsAvailable = true
+		}
+		return s, nil
+	}
+	return QueueSample{}, fmt.Errorf("cannot find listener %s in sock_diag dump", m.ln.Addr())
+}
+
+// AcceptQueueLen returns the number of connections waiting for Accept
+// and the maximum length of the accept queue of the listener.
+//
+// Unlike QueueMonitor, it reads TCP_INFO of the listening socket directly,
+// so it is cheap enough to be called on every Accept for load-shedding.
+func AcceptQueueLen(ln net.Listener) (current, max int, err error) {
+	err = withFd(ln, func(fd uintptr) error {
+		var ti syscall.TCPInfo
+		if err := getTCPInfo(int(fd), &ti); err != nil {
+			return fmt.Errorf("cannot obtain TCP_INFO: %s", err)
+		}
+		if ti.State != tcpListenState {
+			return fmt.Errorf("cannot obtain accept queue length: the socket isn't listening")
+		}
+		// tcpi_unacked and tcpi_sacked hold the current and the maximum
+		// accept queue length for listening sockets.
+		current, max = int(ti.Unacked), int(ti.Sacked)
+		return nil
+	})
+	return current, max, err
+}
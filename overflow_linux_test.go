@@ -0,0 +1,96 @@
+package tcplisten
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOverflowMonitor(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{Backlog: 1})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	m, err := OverflowMonitor(ln)
+	if err != nil {
+		t.Fatalf("cannot create monitor: %s", err)
+	}
+	if _, err = m.Sample(); err != nil {
+		t.Skipf("sock_diag is unavailable: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c, err := net.DialTimeout("tcp4", ln.Addr().String(), 200*time.Millisecond); err == nil {
+				defer c.Close()
+				time.Sleep(300 * time.Millisecond)
+			}
+		}()
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	s, err := m.Sample()
+	if err != nil {
+		t.Fatalf("cannot sample accept queue: %s", err)
+	}
+	if s.Max != 1 {
+		t.Fatalf("unexpected queue max %d. Expecting 1", s.Max)
+	}
+	if s.Len == 0 {
+		t.Fatalf("expecting non-empty accept queue")
+	}
+	if s.DropsAvailable && s.Drops == 0 {
+		t.Fatalf("expecting non-zero drops for the overflowed queue")
+	}
+	wg.Wait()
+
+	sink := newTestSink()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = m.Run(ctx, time.Second, sink); err != context.Canceled {
+		t.Fatalf("unexpected error %v. Expecting %v", err, context.Canceled)
+	}
+	if sink.gauges[MetricListenQueueMax] != 1 {
+		t.Fatalf("unexpected %s gauge %v. Expecting 1", MetricListenQueueMax, sink.gauges[MetricListenQueueMax])
+	}
+}
+
+func TestAcceptQueueLen(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{Backlog: 16})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	for i := 0; i < 3; i++ {
+		c, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial listener: %s", err)
+		}
+		defer c.Close()
+	}
+
+	var current, max int
+	for i := 0; i < 50; i++ {
+		if current, max, err = AcceptQueueLen(ln); err != nil {
+			t.Fatalf("cannot obtain accept queue length: %s", err)
+		}
+		if current == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if current != 3 {
+		t.Fatalf("unexpected accept queue length %d. Expecting 3", current)
+	}
+	if max != 16 {
+		t.Fatalf("unexpected accept queue max %d. Expecting 16", max)
+	}
+}
@@ -0,0 +1,31 @@
+// +build !linux,!freebsd
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// OverflowMonitor returns a monitor reporting accept queue usage
+// and drops attributable to the given listener.
+//
+// It is supported only on Linux and FreeBSD.
+func OverflowMonitor(ln net.Listener) (*QueueMonitor, error) {
+	return nil, ErrUnsupportedOption
+}
+
+// Sample returns the current state of the listener's accept queue.
+func (m *QueueMonitor) Sample() (QueueSample, error) {
+	return QueueSample{}, ErrUnsupportedOption
+}
+
+// AcceptQueueLen returns the number of connections waiting for Accept
+// and the maximum length of the accept queue of the listener.
+//
+// It is supported only on Linux and FreeBSD.
+func AcceptQueueLen(ln net.Listener) (current, max int, err error) {
+	return 0, 0, &UnsupportedError{
+		Op:     "AcceptQueueLen",
+		Reason: "accept queue length is exposed only by TCP_INFO on Linux and SO_LISTENQLEN on FreeBSD",
+	}
+}
@@ -0,0 +1,34 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+	"unsafe"
+)
+
+const soMaxPacingRate = 47
+
+// setMaxPacingRate sets SO_MAX_PACING_RATE in bytes per second.
+func setMaxPacingRate(fd uintptr, rate uint64, tr tracer) error {
+	var err error
+	if rate <= math.MaxUint32 {
+		err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soMaxPacingRate, int(rate))
+	} else {
+		// Rates above 4GB/s require Linux 4.20 or newer.
+		err = setsockopt(int(fd), syscall.SOL_SOCKET, soMaxPacingRate, unsafe.Pointer(&rate), uint32(unsafe.Sizeof(rate)))
+	}
+	tr.trace(TraceRecord{
+		Call:   "setsockopt",
+		Level:  syscall.SOL_SOCKET,
+		Option: "SO_MAX_PACING_RATE",
+		Value:  int(rate),
+		Err:    err,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot set SO_MAX_PACING_RATE to %d: %s", rate, err)
+	}
+	return nil
+}
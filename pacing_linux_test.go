@@ -0,0 +1,55 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func TestConfigMaxPacingRate(t *testing.T) {
+	const rate = 1 << 20
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{MaxPacingRate: rate})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	if v, err := GetOption(ln, OptionMaxPacingRate); err != nil || v != rate {
+		t.Fatalf("unexpected listener pacing rate %d, err %v. Expecting %d", v, err, rate)
+	}
+
+	c := acceptDialed(t, ln, ln.Addr().String())
+	defer c.Close()
+	if v := getMaxPacingRate(t, c); v != rate {
+		t.Fatalf("unexpected inherited pacing rate %d. Expecting %d", v, rate)
+	}
+
+	overrides := []uint64{1 << 16}
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		// 32-bit kernels report rates above 4GB/s as ~0U.
+		overrides = append(overrides, 1<<40)
+	}
+	for _, override := range overrides {
+		cc := ConnConfig{MaxPacingRate: override}
+		if err = cc.Apply(c); err != nil {
+			t.Fatalf("cannot apply ConnConfig: %s", err)
+		}
+		if v := getMaxPacingRate(t, c); v != override {
+			t.Fatalf("unexpected pacing rate %d. Expecting %d", v, override)
+		}
+	}
+}
+
+func getMaxPacingRate(t *testing.T, c interface{}) uint64 {
+	var rate uint64
+	err := withFd(c, func(fd uintptr) error {
+		l := uint32(unsafe.Sizeof(rate))
+		return getsockopt(int(fd), syscall.SOL_SOCKET, soMaxPacingRate, unsafe.Pointer(&rate), &l)
+	})
+	if err != nil {
+		t.Fatalf("cannot obtain SO_MAX_PACING_RATE: %s", err)
+	}
+	return rate
+}
@@ -0,0 +1,11 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"fmt"
+)
+
+func setMaxPacingRate(fd uintptr, rate uint64, tr tracer) error {
+	return fmt.Errorf("cannot set SO_MAX_PACING_RATE: it exists only on Linux: %w", ErrUnsupportedOption)
+}
@@ -0,0 +1,31 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"syscall"
+)
+
+// peek reads the first bytes of the connection's receive queue into b
+// without consuming them. It blocks until at least a single byte
+// is available or the read deadline expires. Zero is returned on EOF.
+func peek(c net.Conn, b []byte) (int, error) {
+	rc, err := rawConn(c)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	var rerr error
+	err = rc.Read(func(fd uintptr) bool {
+		n, _, rerr = syscall.Recvfrom(int(fd), b, syscall.MSG_PEEK)
+		return rerr != syscall.EAGAIN
+	})
+	if err != nil {
+		return 0, err
+	}
+	if rerr != nil {
+		return 0, rerr
+	}
+	return n, nil
+}
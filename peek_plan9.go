@@ -0,0 +1,22 @@
+// +build plan9
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// peek reads the first bytes of the connection's receive queue into b
+// without consuming them.
+//
+// It isn't supported on Plan 9.
+func peek(c net.Conn, b []byte) (int, error) {
+	return 0, ErrUnsupportedOption
+}
+
+// peekFull peeks into the receive queue of c until b is filled.
+//
+// It isn't supported on Plan 9.
+func peekFull(c net.Conn, b []byte) (int, error) {
+	return 0, ErrUnsupportedOption
+}
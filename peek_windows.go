@@ -0,0 +1,61 @@
+// +build windows
+
+package tcplisten
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fionread = 0x4004667f
+	msgPeek  = 0x2
+)
+
+// peek reads the first bytes of the connection's receive queue into b
+// without consuming them. It blocks until at least a single byte
+// is available or the read deadline expires. Zero is returned on EOF.
+//
+// The runtime waits for readability with a zero-byte read, which also
+// completes when the peer closes the connection, so the queue length
+// is checked with FIONREAD before peeking.
+func peek(c net.Conn, b []byte) (int, error) {
+	rc, err := rawConn(c)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	var rerr error
+	waited := false
+	err = rc.Read(func(fd uintptr) bool {
+		var avail, ret uint32
+		rerr = syscall.WSAIoctl(syscall.Handle(fd), fionread, nil, 0, (*byte)(unsafe.Pointer(&avail)), uint32(unsafe.Sizeof(avail)), &ret, nil, 0)
+		if rerr != nil || len(b) == 0 {
+			return true
+		}
+		if avail == 0 {
+			// The queue is empty after waiting, so the peer
+			// has closed the connection.
+			if waited {
+				return true
+			}
+			waited = true
+			return false
+		}
+		buf := syscall.WSABuf{Len: uint32(len(b)), Buf: &b[0]}
+		var qty uint32
+		flags := uint32(msgPeek)
+		// The data is queued, so the call doesn't block.
+		rerr = syscall.WSARecv(syscall.Handle(fd), &buf, 1, &qty, &flags, nil, nil)
+		n = int(qty)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if rerr != nil {
+		return 0, rerr
+	}
+	return n, nil
+}
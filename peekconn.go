@@ -0,0 +1,40 @@
+package tcplisten
+
+import (
+	"io"
+	"net"
+)
+
+// PeekConn returns the first n bytes of the receive queue of c without
+// consuming them, so the following reads return them again. This allows
+// detecting the protocol of an accepted connection, e.g. TLS
+// or plaintext, before dispatching it to a handler which reads
+// the connection from the start. See also SniffListener.
+//
+// PeekConn blocks until n bytes arrive or the read deadline of c expires.
+// If the peer closes the connection earlier, the bytes received so far
+// are returned with io.ErrUnexpectedEOF, or io.EOF if there are none.
+// The bytes received so far are returned with the deadline error too.
+//
+// On Linux 6.9 and newer the queue is peeked incrementally using
+// SO_PEEK_OFF, so PeekConn sleeps until more bytes arrive. Otherwise
+// the queue is polled with MSG_PEEK while it is shorter than n bytes,
+// and the peer closing the connection after sending fewer bytes is
+// detected only by the read deadline.
+// The peek offset of the socket is disabled when PeekConn returns.
+//
+// It isn't supported on Plan 9.
+func PeekConn(c net.Conn, n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	b := make([]byte, n)
+	m, err := peekFull(c, b)
+	if err == nil && m < n {
+		err = io.ErrUnexpectedEOF
+		if m == 0 {
+			err = io.EOF
+		}
+	}
+	return b[:m], err
+}
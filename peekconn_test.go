@@ -0,0 +1,70 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPeekConn(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	accept := func(parts ...string) (net.Conn, net.Conn) {
+		cc, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		c, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept connection: %s", err)
+		}
+		go func() {
+			for _, p := range parts {
+				time.Sleep(20 * time.Millisecond)
+				cc.Write([]byte(p))
+			}
+			cc.(*net.TCPConn).CloseWrite()
+		}()
+		return c, cc
+	}
+
+	c, cc := accept("GE", "T / HTTP/1.1")
+	defer cc.Close()
+	defer c.Close()
+	b, err := PeekConn(c, 4)
+	if err != nil {
+		t.Fatalf("cannot peek: %s", err)
+	}
+	if string(b) != "GET " {
+		t.Fatalf("unexpected bytes peeked %q. Expecting %q", b, "GET ")
+	}
+	all, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatalf("cannot read connection: %s", err)
+	}
+	if string(all) != "GET / HTTP/1.1" {
+		t.Fatalf("unexpected bytes read %q. Expecting %q", all, "GET / HTTP/1.1")
+	}
+
+	c2, cc2 := accept("ab")
+	defer cc2.Close()
+	defer c2.Close()
+	c2.SetReadDeadline(time.Now().Add(time.Second))
+	b, err = PeekConn(c2, 4)
+	if string(b) != "ab" {
+		t.Fatalf("unexpected bytes peeked %q. Expecting %q", b, "ab")
+	}
+	if err != io.ErrUnexpectedEOF && !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("unexpected error %v. Expecting %v or a timeout", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,39 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"net"
+	"time"
+)
+
+// peekFull peeks into the receive queue of c until b is filled, the peer
+// closes the connection or the read deadline expires.
+func peekFull(c net.Conn, b []byte) (int, error) {
+	n, err := peekOffset(c, b)
+	if err != errNoPeekOffset {
+		return n, err
+	}
+
+	// Peeking returns immediately while there are unread bytes,
+	// so poll the queue until it is long enough. The read deadline
+	// is checked by every peek.
+	prev := 0
+	delay := time.Millisecond
+	for {
+		n, err := peek(c, b)
+		if err != nil {
+			return prev, err
+		}
+		if n == 0 || n == len(b) {
+			return n, nil
+		}
+		if n == prev {
+			time.Sleep(delay)
+			if delay < 20*time.Millisecond {
+				delay *= 2
+			}
+		}
+		prev = n
+	}
+}
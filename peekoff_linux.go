@@ -0,0 +1,62 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+const soPeekOff = 42
+
+// errNoPeekOffset is returned from peekOffset if the socket doesn't support
+// SO_PEEK_OFF.
+var errNoPeekOffset = errors.New("SO_PEEK_OFF isn't supported")
+
+// peekOffset peeks into the receive queue of c until b is filled, the peer
+// closes the connection or the read deadline expires. Every peek starts
+// at the SO_PEEK_OFF offset following the bytes peeked before, so it blocks
+// until new bytes arrive.
+//
+// TCP sockets support SO_PEEK_OFF since Linux 6.9.
+func peekOffset(c net.Conn, b []byte) (int, error) {
+	rc, err := rawConn(c)
+	if err != nil {
+		return 0, err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soPeekOff, 0)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if serr != nil {
+		return 0, errNoPeekOffset
+	}
+	defer rc.Control(func(fd uintptr) {
+		syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soPeekOff, -1)
+	})
+
+	n := 0
+	for n < len(b) {
+		var m int
+		var rerr error
+		err = rc.Read(func(fd uintptr) bool {
+			m, _, rerr = syscall.Recvfrom(int(fd), b[n:], syscall.MSG_PEEK)
+			return rerr != syscall.EAGAIN
+		})
+		if err != nil {
+			return n, err
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+		if m == 0 {
+			break
+		}
+		n += m
+	}
+	return n, nil
+}
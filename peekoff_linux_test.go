@@ -0,0 +1,57 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPeekOffset(t *testing.T) {
+	if major, minor := kernelVersion(); major < 6 || major == 6 && minor < 9 {
+		t.Skipf("SO_PEEK_OFF isn't supported for TCP on Linux %d.%d. Linux 6.9 or newer is required", major, minor)
+	}
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	cc, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer cc.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	defer c.Close()
+
+	go func() {
+		cc.Write([]byte("a"))
+		time.Sleep(20 * time.Millisecond)
+		cc.Write([]byte("b"))
+		cc.(*net.TCPConn).CloseWrite()
+	}()
+
+	// The peer closing the connection must be detected without waiting
+	// for the deadline.
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 4)
+	n, err := peekOffset(c, b)
+	if err == errNoPeekOffset {
+		t.Fatalf("unexpected error %v on Linux 6.9 or newer", err)
+	}
+	if string(b[:n]) != "ab" {
+		t.Fatalf("unexpected bytes peeked %q. Expecting %q", b[:n], "ab")
+	}
+	b, err = PeekConn(c, 4)
+	if string(b) != "ab" {
+		t.Fatalf("unexpected bytes peeked %q. Expecting %q", b, "ab")
+	}
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("unexpected error %v. Expecting %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,18 @@
+// +build !linux,!plan9
+
+package tcplisten
+
+import (
+	"errors"
+	"net"
+)
+
+// errNoPeekOffset is returned from peekOffset if the socket doesn't support
+// SO_PEEK_OFF.
+var errNoPeekOffset = errors.New("SO_PEEK_OFF isn't supported")
+
+// peekOffset always returns errNoPeekOffset, since SO_PEEK_OFF
+// is supported only on Linux.
+func peekOffset(c net.Conn, b []byte) (int, error) {
+	return 0, errNoPeekOffset
+}
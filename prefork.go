@@ -0,0 +1,64 @@
+package tcplisten
+
+import (
+	"os"
+	"time"
+)
+
+// PreforkChildEnv is the environment variable marking child processes
+// spawned by Prefork.
+const PreforkChildEnv = "TCPLISTEN_PREFORK_CHILD"
+
+const (
+	// DefaultRespawnDelay is the default delay before respawning
+	// a child process which exited unexpectedly.
+	DefaultRespawnDelay = 100 * time.Millisecond
+
+	// DefaultMaxRespawnDelay is the default upper bound for the respawn
+	// delay, which doubles on every consecutive crash.
+	DefaultMaxRespawnDelay = 10 * time.Second
+
+	// DefaultStopTimeout is the default time children are given
+	// for exiting after SIGTERM.
+	DefaultStopTimeout = 10 * time.Second
+)
+
+// Prefork runs the server in multiple processes, each accepting
+// connections from its own SO_REUSEPORT listener, so the kernel balances
+// connections between the processes.
+//
+// It is supported only on Linux. Run returns *UnsupportedError
+// on other platforms.
+type Prefork struct {
+	// Children is the number of child processes.
+	//
+	// runtime.NumCPU() is used by default.
+	Children int
+
+	// RespawnDelay is the initial delay before respawning a child process
+	// which exited while the parent is running.
+	//
+	// DefaultRespawnDelay is used by default.
+	RespawnDelay time.Duration
+
+	// MaxRespawnDelay limits the respawn delay.
+	//
+	// DefaultMaxRespawnDelay is used by default.
+	MaxRespawnDelay time.Duration
+
+	// StopTimeout is the time children are given for exiting after SIGTERM
+	// before being killed.
+	//
+	// DefaultStopTimeout is used by default.
+	StopTimeout time.Duration
+
+	// Logger is used for logging child exits. The standard logger is used
+	// by default.
+	Logger Logger
+}
+
+// IsPreforkChild reports whether the current process has been spawned
+// by Prefork.
+func IsPreforkChild() bool {
+	return os.Getenv(PreforkChildEnv) == "1"
+}
@@ -0,0 +1,202 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Run runs the server in multiple processes.
+//
+// In the parent process Run spawns Children copies of the current binary
+// with the same arguments and PreforkChildEnv set, respawns the children
+// which exit and returns after ctx is done or the parent receives SIGTERM.
+// Children receive SIGTERM in both cases and are killed if they don't
+// exit in StopTimeout. They also receive SIGTERM if the parent dies.
+//
+// In a child process Run creates the listener with Config.ReusePort
+// enabled and calls child with it. The listener is closed when ctx
+// is done or the child receives SIGTERM, so the server returns. Run
+// returns the error returned from child.
+func (p *Prefork) Run(ctx context.Context, network, addr string, cfg Config, child func(ln net.Listener) error) error {
+	if major, minor := kernelVersion(); major > 0 && (major < 3 || major == 3 && minor < 9) {
+		return &UnsupportedError{
+			Op:     "Prefork",
+			Reason: "SO_REUSEPORT requires Linux 3.9 or newer",
+		}
+	}
+	if IsPreforkChild() {
+		return p.runChild(ctx, network, addr, cfg, child)
+	}
+	return p.runParent(ctx)
+}
+
+func (p *Prefork) runChild(ctx context.Context, network, addr string, cfg Config, child func(ln net.Listener) error) error {
+	cfg.ReusePort = true
+	ln, err := NewListener(network, addr, cfg)
+	if err != nil {
+		return err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-sigs:
+		case <-done:
+		}
+		ln.Close()
+	}()
+	return child(ln)
+}
+
+func (p *Prefork) runParent(ctx context.Context) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot locate the executable for prefork: %s", err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-sigs:
+		}
+		close(stop)
+	}()
+
+	n := p.Children
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p.superviseChild(exe, i, stop)
+		}(i)
+	}
+	wg.Wait()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// superviseChild keeps the i-th child running until stop is closed.
+func (p *Prefork) superviseChild(exe string, i int, stop <-chan struct{}) {
+	// Pdeathsig is delivered when the thread which has started
+	// the child exits rather than the parent process, so the children
+	// must be started from a thread living as long as they do.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	logger := loggerOrDefault(p.Logger)
+	minDelay := p.RespawnDelay
+	if minDelay <= 0 {
+		minDelay = DefaultRespawnDelay
+	}
+	maxDelay := p.MaxRespawnDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxRespawnDelay
+	}
+	delay := minDelay
+
+	for {
+		started := time.Now()
+		err := p.runChildProcess(exe, stop)
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		// Reset the backoff for children which have been running
+		// long enough, so rare crashes are respawned quickly.
+		if time.Since(started) > maxDelay {
+			delay = minDelay
+		}
+		logger.Printf("tcplisten: prefork child #%d exited: %v; respawning in %s", i, err, delay)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-stop:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// runChildProcess runs the child process until it exits.
+// The child is terminated when stop is closed, or when the calling
+// thread exits, e.g. because the parent has died. The caller must
+// be locked to its OS thread.
+func (p *Prefork) runChildProcess(exe string, stop <-chan struct{}) error {
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), PreforkChildEnv+"=1")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Pdeathsig: syscall.SIGTERM,
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		// Wait reaps the child, so it doesn't become a zombie.
+		exited <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-exited:
+		if err == nil {
+			err = fmt.Errorf("exit status 0")
+		}
+		return err
+	case <-stop:
+	}
+
+	cmd.Process.Signal(syscall.SIGTERM)
+	timeout := p.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case err := <-exited:
+		return err
+	case <-t.C:
+		cmd.Process.Kill()
+		return <-exited
+	}
+}
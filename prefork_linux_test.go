@@ -0,0 +1,80 @@
+package tcplisten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPrefork(t *testing.T) {
+	const addr = "127.0.0.1:10083"
+	p := &Prefork{
+		Children:    2,
+		StopTimeout: time.Second,
+	}
+
+	if IsPreforkChild() {
+		// The test binary has been respawned by Prefork.
+		err := p.Run(context.Background(), "tcp4", addr, Config{}, func(ln net.Listener) error {
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return nil
+				}
+				fmt.Fprintf(c, "%d", os.Getpid())
+				c.Close()
+			}
+		})
+		if err != nil {
+			t.Fatalf("unexpected error in child: %s", err)
+		}
+		return
+	}
+
+	// Children must run only this test.
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestPrefork$"}
+	defer func() { os.Args = args }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan error, 1)
+	go func() {
+		ch <- p.Run(ctx, "tcp4", addr, Config{}, nil)
+	}()
+
+	var pid int
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		c, err := net.Dial("tcp4", addr)
+		if err != nil {
+			continue
+		}
+		b, err := ioutil.ReadAll(c)
+		c.Close()
+		if err == nil {
+			pid, _ = strconv.Atoi(string(b))
+			break
+		}
+	}
+	if pid == 0 || pid == os.Getpid() {
+		t.Fatalf("unexpected pid %d of the server. Expecting a child pid", pid)
+	}
+
+	cancel()
+	select {
+	case err := <-ch:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error %v. Expecting %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout when waiting for prefork to stop")
+	}
+	if _, err := net.Dial("tcp4", addr); err == nil {
+		t.Fatalf("children are still listening after stop")
+	}
+}
@@ -0,0 +1,19 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"context"
+	"net"
+)
+
+// Run runs the server in multiple processes.
+//
+// It isn't supported on the current platform, since SO_REUSEPORT
+// doesn't balance connections between listeners here.
+func (p *Prefork) Run(ctx context.Context, network, addr string, cfg Config, child func(ln net.Listener) error) error {
+	return &UnsupportedError{
+		Op:     "Prefork",
+		Reason: "SO_REUSEPORT doesn't balance connections between processes",
+	}
+}
@@ -0,0 +1,40 @@
+package tcplisten
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPrivilegedPort is matched by errors.Is for PrivilegedPortError.
+var ErrPrivilegedPort = errors.New("tcplisten: binding to a privileged port requires privileges")
+
+// PrivilegedPortError is returned by NewListener if binding fails with
+// EACCES because the port is privileged and the process may not bind
+// privileged ports.
+//
+// errors.Is reports true for ErrPrivilegedPort and for the underlying
+// syscall.EACCES.
+type PrivilegedPortError struct {
+	// Addr is the address the listener has been requested for.
+	Addr string
+
+	// Port is the privileged port.
+	Port int
+
+	// Err is the error returned by bind.
+	Err error
+}
+
+func (e *PrivilegedPortError) Error() string {
+	return fmt.Sprintf("tcplisten: cannot bind to %q: port %d is privileged, %s: %s", e.Addr, e.Port, privilegedPortHint, e.Err)
+}
+
+// Unwrap returns the error returned by bind.
+func (e *PrivilegedPortError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes PrivilegedPortError match ErrPrivilegedPort.
+func (e *PrivilegedPortError) Is(target error) bool {
+	return target == ErrPrivilegedPort
+}
@@ -0,0 +1,33 @@
+// +build linux
+
+package tcplisten
+
+const (
+	capNetBindService = 10
+
+	privilegedPortHint = "grant CAP_NET_BIND_SERVICE to the binary, e.g. with `setcap cap_net_bind_service=+ep`, " +
+		"or lower net.ipv4.ip_unprivileged_port_start"
+)
+
+// CanBindPrivileged reports whether the process may bind ports below 1024,
+// i.e. whether it has CAP_NET_BIND_SERVICE in the effective capability set
+// or net.ipv4.ip_unprivileged_port_start allows binding all the ports.
+func CanBindPrivileged() bool {
+	return hasEffectiveCap(capNetBindService) || unprivilegedPortStart() <= 1
+}
+
+// unprivilegedPortStart returns net.ipv4.ip_unprivileged_port_start,
+// which is 1024 on kernels older than 4.11 lacking the sysctl.
+func unprivilegedPortStart() int {
+	n, err := readSysctlInt("net/ipv4/ip_unprivileged_port_start")
+	if err != nil {
+		return 1024
+	}
+	return n
+}
+
+// isPrivilegedPort reports whether binding the port failed with EACCES
+// because the port is privileged.
+func isPrivilegedPort(port int) bool {
+	return port > 0 && port < unprivilegedPortStart() && !hasEffectiveCap(capNetBindService)
+}
@@ -0,0 +1,27 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"os"
+	"runtime"
+)
+
+const privilegedPortHint = "run the process as root"
+
+// CanBindPrivileged reports whether the process may bind ports below 1024.
+//
+// It reports true for root on Unix platforms other than Linux, and always
+// on Windows and Plan 9, which have no privileged ports.
+func CanBindPrivileged() bool {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		return true
+	}
+	return os.Geteuid() == 0
+}
+
+// isPrivilegedPort reports whether binding the port failed with EACCES
+// because the port is privileged.
+func isPrivilegedPort(port int) bool {
+	return port > 0 && port < 1024 && !CanBindPrivileged()
+}
@@ -0,0 +1,36 @@
+package tcplisten
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestPrivilegedPortError(t *testing.T) {
+	var err error = &PrivilegedPortError{Addr: ":443", Port: 443, Err: syscall.EACCES}
+	if !errors.Is(err, ErrPrivilegedPort) {
+		t.Fatalf("%v doesn't match ErrPrivilegedPort", err)
+	}
+	if !errors.Is(err, syscall.EACCES) {
+		t.Fatalf("%v doesn't match EACCES", err)
+	}
+	if runtime.GOOS == "linux" {
+		for _, s := range []string{"CAP_NET_BIND_SERVICE", "net.ipv4.ip_unprivileged_port_start"} {
+			if !strings.Contains(err.Error(), s) {
+				t.Fatalf("%q doesn't mention %s", err, s)
+			}
+		}
+	}
+}
+
+func TestCanBindPrivileged(t *testing.T) {
+	if os.Geteuid() == 0 && !CanBindPrivileged() {
+		t.Fatalf("root cannot bind privileged ports")
+	}
+	if CanBindPrivileged() && isPrivilegedPort(443) {
+		t.Fatalf("443 is privileged for a process which may bind privileged ports")
+	}
+}
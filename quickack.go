@@ -0,0 +1,55 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+)
+
+// SetQuickAck enables or disables TCP_QUICKACK on the accepted
+// connection c.
+//
+// Linux clears TCP_QUICKACK by itself when it switches the connection
+// back to delayed ACKs, e.g. after an ACK is sent, so Config.QuickACK
+// set on the listener affects only the start of the connections.
+// Applications sensitive to latency should call SetQuickAck after
+// every read they want to be acknowledged immediately.
+//
+// It is supported only on Linux. An error wrapping ErrUnsupportedOption
+// is returned on other platforms.
+func SetQuickAck(c net.Conn, on bool) error {
+	so, err := lookupOption(OptionQuickACK)
+	if err != nil {
+		return err
+	}
+	v := 0
+	if on {
+		v = 1
+	}
+	return withFd(c, func(fd uintptr) error {
+		if err := so.set(fd, v); err != nil {
+			return fmt.Errorf("cannot set %s to %d: %s", OptionQuickACK, v, err)
+		}
+		return nil
+	})
+}
+
+// GetQuickAck reports whether TCP_QUICKACK is enabled on the accepted
+// connection c at the moment, see SetQuickAck.
+//
+// It is supported only on Linux. An error wrapping ErrUnsupportedOption
+// is returned on other platforms.
+func GetQuickAck(c net.Conn) (bool, error) {
+	so, err := lookupOption(OptionQuickACK)
+	if err != nil {
+		return false, err
+	}
+	var v int
+	err = withFd(c, func(fd uintptr) error {
+		var err error
+		if v, err = so.get(fd); err != nil {
+			return fmt.Errorf("cannot obtain %s: %s", OptionQuickACK, err)
+		}
+		return nil
+	})
+	return v != 0, err
+}
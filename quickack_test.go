@@ -0,0 +1,50 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"testing"
+)
+
+func TestQuickAck(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	cc, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer cc.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	defer c.Close()
+
+	if runtime.GOOS != "linux" {
+		if err = SetQuickAck(c, true); !errors.Is(err, ErrUnsupportedOption) {
+			t.Fatalf("unexpected error %v. Expecting %v", err, ErrUnsupportedOption)
+		}
+		if _, err = GetQuickAck(c); !errors.Is(err, ErrUnsupportedOption) {
+			t.Fatalf("unexpected error %v. Expecting %v", err, ErrUnsupportedOption)
+		}
+		return
+	}
+	for _, on := range []bool{false, true} {
+		if err = SetQuickAck(c, on); err != nil {
+			t.Fatalf("cannot set TCP_QUICKACK to %v: %s", on, err)
+		}
+		v, err := GetQuickAck(c)
+		if err != nil {
+			t.Fatalf("cannot obtain TCP_QUICKACK: %s", err)
+		}
+		if v != on {
+			t.Fatalf("unexpected TCP_QUICKACK %v. Expecting %v", v, on)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// RawListener is a listening socket for custom event loops.
+//
+// Unlike listeners returned from NewListener, it isn't registered
+// in the Go runtime network poller, so connections are accepted
+// as raw file descriptors.
+//
+// Use NewRawListener for creating RawListener.
+type RawListener struct {
+	fd   int
+	addr *net.TCPAddr
+	lock *os.File
+
+	mu          sync.Mutex
+	nonblocking bool
+	closed      bool
+
+	// accepting is the number of in-flight Accept calls. The fd
+	// is closed by the last of them if Close is called meanwhile,
+	// so Accept never operates on a reused fd.
+	accepting int
+}
+
+// NewRawListener returns RawListener with options set in the Config.
+//
+// The listening socket is in non-blocking mode by default,
+// so Accept returns syscall.EAGAIN when the accept queue is empty.
+func NewRawListener(network, addr string, cfg Config) (*RawListener, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	sa, soType, err := getSockaddr(context.Background(), network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err = cfg.checkLoopback(sockaddrIP(sa), addr); err != nil {
+		return nil, err
+	}
+
+	var lock *os.File
+	if cfg.SingletonLock != "" {
+		if lock, err = acquireLock(cfg.SingletonLock); err != nil {
+			return nil, err
+		}
+	}
+
+	fd, err := newSocketCloexec(soType, syscall.SOCK_STREAM, syscall.IPPROTO_TCP)
+	if err == nil {
+		if err = cfg.fdSetup(fd, sa, addr, &ListenResult{}); err != nil {
+			syscall.Close(fd)
+		}
+	}
+	if err != nil {
+		if lock != nil {
+			lock.Close()
+		}
+		return nil, err
+	}
+
+	ln := &RawListener{
+		fd:          fd,
+		lock:        lock,
+		nonblocking: true,
+	}
+	if bound, err := syscall.Getsockname(fd); err == nil {
+		ln.addr = sockaddrToTCPAddr(bound)
+	}
+	return ln, nil
+}
+
+// Fd returns the file descriptor of the listening socket.
+//
+// The descriptor is valid until Close is called.
+func (ln *RawListener) Fd() int {
+	return ln.fd
+}
+
+// Addr returns the address the listener is bound to.
+func (ln *RawListener) Addr() net.Addr {
+	return ln.addr
+}
+
+// SetNonblocking switches between blocking and non-blocking accept.
+//
+// In non-blocking mode Accept returns syscall.EAGAIN immediately when
+// the accept queue is empty, which is what event loops polling the fd
+// expect. In blocking mode Accept waits for the next connection,
+// occupying an OS thread.
+func (ln *RawListener) SetNonblocking(nonblocking bool) error {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	if ln.closed {
+		return ErrListenerClosed
+	}
+	if err := syscall.SetNonblock(ln.fd, nonblocking); err != nil {
+		return fmt.Errorf("cannot change O_NONBLOCK on the listening socket: %s", err)
+	}
+	ln.nonblocking = nonblocking
+	return nil
+}
+
+// Nonblocking reports whether Accept is non-blocking.
+func (ln *RawListener) Nonblocking() bool {
+	ln.mu.Lock()
+	nonblocking := ln.nonblocking
+	ln.mu.Unlock()
+	return nonblocking
+}
+
+// Accept accepts the next connection.
+//
+// The returned fd is close-on-exec and non-blocking regardless
+// of the listener mode. The caller is responsible for closing it.
+//
+// ErrListenerClosed is returned after Close.
+func (ln *RawListener) Accept() (int, syscall.Sockaddr, error) {
+	ln.mu.Lock()
+	if ln.closed {
+		ln.mu.Unlock()
+		return -1, nil, ErrListenerClosed
+	}
+	ln.accepting++
+	ln.mu.Unlock()
+
+	fd, sa, err := ln.accept()
+
+	ln.mu.Lock()
+	ln.accepting--
+	closed := ln.closed
+	if closed && ln.accepting == 0 {
+		syscall.Close(ln.fd)
+	}
+	ln.mu.Unlock()
+	if err != nil && closed {
+		return -1, nil, ErrListenerClosed
+	}
+	return fd, sa, err
+}
+
+func (ln *RawListener) accept() (int, syscall.Sockaddr, error) {
+	for {
+		syscall.ForkLock.RLock()
+		fd, sa, err := syscall.Accept(ln.fd)
+		if err == nil {
+			syscall.CloseOnExec(fd)
+		}
+		syscall.ForkLock.RUnlock()
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return -1, nil, err
+		}
+		if err = syscall.SetNonblock(fd, true); err != nil {
+			syscall.Close(fd)
+			return -1, nil, fmt.Errorf("cannot make non-blocked connection socket: %s", err)
+		}
+		return fd, sa, nil
+	}
+}
+
+// Close closes the listening socket.
+//
+// Accept blocked in blocking mode is woken up on platforms
+// supporting shutdown(2) on listening sockets. The fd is closed
+// once such Accept returns.
+func (ln *RawListener) Close() error {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	if ln.closed {
+		return nil
+	}
+	ln.closed = true
+	syscall.Shutdown(ln.fd, syscall.SHUT_RDWR)
+	var err error
+	if ln.accepting == 0 {
+		err = syscall.Close(ln.fd)
+	}
+	if ln.lock != nil {
+		ln.lock.Close()
+	}
+	return err
+}
+
+func sockaddrToTCPAddr(sa syscall.Sockaddr) *net.TCPAddr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return &net.TCPAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port}
+	case *syscall.SockaddrInet6:
+		addr := &net.TCPAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port}
+		if sa.ZoneId != 0 {
+			if ifi, err := net.InterfaceByIndex(int(sa.ZoneId)); err == nil {
+				addr.Zone = ifi.Name
+			}
+		}
+		return addr
+	}
+	return nil
+}
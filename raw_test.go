@@ -0,0 +1,60 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestRawListenerNonblocking(t *testing.T) {
+	ln, err := NewRawListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create raw listener: %s", err)
+	}
+	defer ln.Close()
+
+	if !ln.Nonblocking() {
+		t.Fatalf("raw listener must be non-blocking by default")
+	}
+	if _, _, err = ln.Accept(); err != syscall.EAGAIN {
+		t.Fatalf("unexpected error %v. Expecting %v", err, syscall.EAGAIN)
+	}
+
+	if err = ln.SetNonblocking(false); err != nil {
+		t.Fatalf("cannot make raw listener blocking: %s", err)
+	}
+	ch := make(chan error, 1)
+	go func() {
+		c, err := net.Dial("tcp4", ln.Addr().String())
+		if err == nil {
+			c.Close()
+		}
+		ch <- err
+	}()
+	fd, sa, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	syscall.Close(fd)
+	if _, ok := sa.(*syscall.SockaddrInet4); !ok {
+		t.Fatalf("unexpected peer address %#v", sa)
+	}
+	if err = <-ch; err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+}
+
+func TestRawListenerAcceptAfterClose(t *testing.T) {
+	ln, err := NewRawListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create raw listener: %s", err)
+	}
+	if err = ln.Close(); err != nil {
+		t.Fatalf("cannot close raw listener: %s", err)
+	}
+	if _, _, err = ln.Accept(); err != ErrListenerClosed {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrListenerClosed)
+	}
+}
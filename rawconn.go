@@ -0,0 +1,30 @@
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// rawConn returns the raw connection of the given listener or connection.
+func rawConn(v interface{}) (syscall.RawConn, error) {
+	sc, ok := v.(syscall.Conn)
+	if !ok {
+		return nil, fmt.Errorf("%T does not expose its file descriptor", v)
+	}
+	return sc.SyscallConn()
+}
+
+// withFd calls fn with the file descriptor of the given listener or connection.
+func withFd(v interface{}, fn func(fd uintptr) error) error {
+	rc, err := rawConn(v)
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	if err = rc.Control(func(fd uintptr) {
+		fnErr = fn(fd)
+	}); err != nil {
+		return err
+	}
+	return fnErr
+}
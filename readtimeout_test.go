@@ -0,0 +1,73 @@
+package tcplisten
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAcceptReadTimeout(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{AcceptReadTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	accept := func() (net.Conn, net.Conn) {
+		t.Helper()
+		c, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		sc, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept: %s", err)
+		}
+		return c, sc
+	}
+
+	// The silent client times out.
+	c, sc := accept()
+	defer c.Close()
+	defer sc.Close()
+	var buf [8]byte
+	_, err = sc.Read(buf[:])
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("unexpected error %v. Expecting timeout", err)
+	}
+
+	// The deadline is cleared after the first read.
+	c, sc = accept()
+	defer c.Close()
+	defer sc.Close()
+	if _, err = c.Write([]byte("a")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	if _, err = sc.Read(buf[:]); err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, err = c.Write([]byte("b")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	if _, err = sc.Read(buf[:]); err != nil {
+		t.Fatalf("cannot read after the initial deadline: %s", err)
+	}
+
+	// The deadline set by the caller is kept.
+	c, sc = accept()
+	defer c.Close()
+	defer sc.Close()
+	if err = sc.SetReadDeadline(time.Now().Add(300 * time.Millisecond)); err != nil {
+		t.Fatalf("cannot set deadline: %s", err)
+	}
+	if _, err = c.Write([]byte("a")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	if _, err = sc.Read(buf[:]); err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if _, err = sc.Read(buf[:]); err == nil {
+		t.Fatalf("expecting error after the caller's deadline")
+	}
+}
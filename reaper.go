@@ -0,0 +1,103 @@
+package tcplisten
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// DefaultReaperRate is the default maximum number of connections
+// IdleReaper samples per second.
+const DefaultReaperRate = 1000
+
+// IdleReaper closes tracked connections whose peers have been silent
+// for too long, e.g. because they vanished without sending FIN.
+//
+// The idle time is obtained from the kernel (TCP_INFO on Linux),
+// so it covers connections which aren't being read by the application.
+type IdleReaper struct {
+	// Idle is the maximum duration without receiving data or ACKs
+	// from the peer.
+	Idle time.Duration
+
+	// Interval is the period for sampling every tracked connection.
+	// Samples are spread evenly over the interval.
+	//
+	// Idle is used by default.
+	Interval time.Duration
+
+	// MaxRate is the maximum number of connections sampled per second.
+	// The interval is stretched if there are too many connections.
+	//
+	// DefaultReaperRate is used by default.
+	MaxRate int
+
+	// OnReap is called with the connection and its idle time
+	// before closing the connection.
+	OnReap func(c net.Conn, idle time.Duration)
+}
+
+// Run samples connections from set and closes the idle ones until ctx
+// is done.
+//
+// ErrUnsupportedOption is returned if the idle time of connections
+// cannot be obtained on the current platform.
+func (r *IdleReaper) Run(ctx context.Context, set ConnSet) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = r.Idle
+	}
+	maxRate := r.MaxRate
+	if maxRate <= 0 {
+		maxRate = DefaultReaperRate
+	}
+	minStep := time.Second / time.Duration(maxRate)
+
+	t := time.NewTimer(interval)
+	defer t.Stop()
+	for {
+		conns := set.Conns()
+		step := interval
+		if len(conns) > 0 {
+			step = interval / time.Duration(len(conns))
+		}
+		if step < minStep {
+			step = minStep
+		}
+
+		for _, c := range conns {
+			t.Reset(step)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-t.C:
+			}
+			if err := r.check(c); err == ErrUnsupportedOption {
+				return err
+			}
+		}
+		if len(conns) == 0 {
+			t.Reset(interval)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+	}
+}
+
+func (r *IdleReaper) check(c net.Conn) error {
+	idle, err := connIdle(c)
+	if err != nil {
+		// The connection may have been closed concurrently.
+		return err
+	}
+	if idle < r.Idle {
+		return nil
+	}
+	if r.OnReap != nil {
+		r.OnReap(c, idle)
+	}
+	return c.Close()
+}
@@ -0,0 +1,28 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"net"
+	"syscall"
+	"time"
+)
+
+// connIdle returns the time since the last data or ACK has been
+// received on the connection.
+func connIdle(c net.Conn) (time.Duration, error) {
+	var idle time.Duration
+	err := withFd(c, func(fd uintptr) error {
+		var ti syscall.TCPInfo
+		if err := getTCPInfo(int(fd), &ti); err != nil {
+			return err
+		}
+		ms := ti.Last_data_recv
+		if ti.Last_ack_recv < ms {
+			ms = ti.Last_ack_recv
+		}
+		idle = time.Duration(ms) * time.Millisecond
+		return nil
+	})
+	return idle, err
+}
@@ -0,0 +1,84 @@
+package tcplisten
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIdleReaper(t *testing.T) {
+	base, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln := TrackConns(base)
+	defer ln.Close()
+
+	idleClient, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial listener: %s", err)
+	}
+	defer idleClient.Close()
+	idleConn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+
+	activeClient, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial listener: %s", err)
+	}
+	defer activeClient.Close()
+	activeConn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	defer activeConn.Close()
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := activeConn.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	reaped := make(chan net.Conn, 2)
+	r := &IdleReaper{
+		Idle:     150 * time.Millisecond,
+		Interval: 20 * time.Millisecond,
+		OnReap: func(c net.Conn, idle time.Duration) {
+			reaped <- c
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, ln)
+
+	deadline := time.After(time.Second)
+	tick := time.NewTicker(30 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case c := <-reaped:
+			if c != idleConn {
+				t.Fatalf("unexpected connection reaped")
+			}
+			// OnReap is called before closing the connection.
+			for i := 0; i < 100 && ln.Len() != 1; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			if ln.Len() != 1 {
+				t.Fatalf("unexpected number of tracked connections %d. Expecting 1", ln.Len())
+			}
+			return
+		case <-tick.C:
+			if _, err := activeClient.Write([]byte("x")); err != nil {
+				t.Fatalf("cannot write to active connection: %s", err)
+			}
+		case <-deadline:
+			t.Fatalf("timeout when waiting for idle connection to be reaped")
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+	"time"
+)
+
+// connIdle returns the time since the last data or ACK has been
+// received on the connection.
+//
+// It is supported only on Linux.
+func connIdle(c net.Conn) (time.Duration, error) {
+	return 0, ErrUnsupportedOption
+}
@@ -0,0 +1,102 @@
+package tcplisten
+
+import (
+	"errors"
+	"net"
+)
+
+// liveOption is an option Reconfigure turns off if cfg doesn't request it.
+type liveOption struct {
+	option  Option
+	enabled func(cfg *Config) bool
+
+	// off is the value turning the option off.
+	off int
+}
+
+var liveOptions = []liveOption{
+	{OptionDeferAccept, func(cfg *Config) bool { return cfg.DeferAccept }, 0},
+	{OptionFastOpen, func(cfg *Config) bool { return cfg.FastOpen }, 0},
+	{OptionQuickACK, func(cfg *Config) bool { return cfg.QuickACK }, 0},
+	// ~0U means unlimited pacing rate.
+	{OptionMaxPacingRate, func(cfg *Config) bool { return cfg.MaxPacingRate > 0 }, -1},
+	{OptionTimestamping, func(cfg *Config) bool { return cfg.HardwareTimestamping }, 0},
+}
+
+// Reconfigure changes the options of the running listener ln to cfg
+// without recreating it, so neither the queued nor the accepted
+// connections are dropped. The connections accepted afterwards inherit
+// the new options, while the already accepted ones keep the old options.
+//
+// DeferAccept, FastOpen, NoDelay, QuickACK, InitialRTO, MaxPacingRate
+// and HardwareTimestamping may be changed live. Unlike ApplyConfig,
+// Reconfigure also turns off the options cfg doesn't request.
+//
+// The rest of the options require a new listener, e.g. one created
+// with NewListener and ReusePort enabled on the same port before closing
+// the old one. Reconfigure returns *ImmutableOptionError if cfg changes
+// ReusePort, ReusePortLB or V6Only of ln. Changes of the other options,
+// e.g. Backlog or FlowLabel, cannot be detected on a listening socket
+// and are ignored.
+func Reconfigure(ln net.Listener, cfg Config) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	if err := checkImmutableOptions(ln, &cfg); err != nil {
+		return err
+	}
+	if err := ApplyConfig(ln, cfg); err != nil {
+		return err
+	}
+	for _, lo := range liveOptions {
+		if lo.enabled(&cfg) {
+			continue
+		}
+		v, err := GetOption(ln, lo.option)
+		if errors.Is(err, ErrUnsupportedOption) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if v != lo.off {
+			if err = SetOption(ln, lo.option, lo.off); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// immutableCheck is a pre-bind option and whether cfg enables it.
+type immutableCheck struct {
+	option Option
+	want   bool
+}
+
+// checkImmutableOptions returns *ImmutableOptionError if cfg requests
+// a value of a pre-bind option other than the one set on ln.
+func checkImmutableOptions(ln net.Listener, cfg *Config) error {
+	checks := []immutableCheck{
+		{OptionReusePort, cfg.ReusePort},
+		{OptionReusePortLB, cfg.ReusePortLB},
+	}
+	if v, ok := cfg.V6Only.sockoptValue(); ok {
+		if addr, isTCP := ln.Addr().(*net.TCPAddr); isTCP && addr.IP.To4() == nil {
+			checks = append(checks, immutableCheck{OptionV6Only, v != 0})
+		}
+	}
+	for _, c := range checks {
+		v, err := GetOption(ln, c.option)
+		if errors.Is(err, ErrUnsupportedOption) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if (v != 0) != c.want {
+			return &ImmutableOptionError{Option: c.option}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReconfigure(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{DeferAccept: true, MaxPacingRate: 1 << 20})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	if err = Reconfigure(ln, Config{FastOpen: true}); err != nil {
+		t.Fatalf("cannot reconfigure listener: %s", err)
+	}
+	for _, tc := range []struct {
+		o Option
+		v int
+	}{
+		{OptionDeferAccept, 0},
+		{OptionMaxPacingRate, -1},
+	} {
+		v, err := GetOption(ln, tc.o)
+		if err != nil {
+			t.Fatalf("cannot obtain %s: %s", tc.o, err)
+		}
+		if v != tc.v {
+			t.Fatalf("unexpected %s %d. Expecting %d", tc.o, v, tc.v)
+		}
+	}
+
+	// The kernel limits the TCP_FASTOPEN queue by somaxconn.
+	if v, err := GetOption(ln, OptionFastOpen); err != nil || v == 0 {
+		t.Fatalf("TCP_FASTOPEN isn't enabled: %d, %v", v, err)
+	}
+
+	var ie *ImmutableOptionError
+	if err = Reconfigure(ln, Config{ReusePort: true}); !errors.As(err, &ie) || ie.Option != OptionReusePort {
+		t.Fatalf("unexpected error %v. Expecting ImmutableOptionError for SO_REUSEPORT", err)
+	}
+}
+
+func TestReconfigureV6Only(t *testing.T) {
+	ln, err := NewListener("tcp6", "[::1]:0", Config{V6Only: V6OnlyEnabled})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	if err = Reconfigure(ln, Config{V6Only: V6OnlyEnabled}); err != nil {
+		t.Fatalf("cannot reconfigure listener: %s", err)
+	}
+	var ie *ImmutableOptionError
+	if err = Reconfigure(ln, Config{V6Only: V6OnlyDisabled}); !errors.As(err, &ie) || ie.Option != OptionV6Only {
+		t.Fatalf("unexpected error %v. Expecting ImmutableOptionError for IPV6_V6ONLY", err)
+	}
+}
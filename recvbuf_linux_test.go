@@ -0,0 +1,90 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestDisableRecvAutotune(t *testing.T) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, syscall.IPPROTO_TCP)
+	if err != nil {
+		t.Fatalf("cannot create socket: %s", err)
+	}
+	defaultSize, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	syscall.Close(fd)
+	if err != nil {
+		t.Fatalf("cannot obtain SO_RCVBUF: %s", err)
+	}
+
+	res, err := NewListenerResult("tcp4", "127.0.0.1:0", Config{DisableRecvAutotune: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer res.Close()
+	if !res.hasOption("SO_RCVBUF") {
+		t.Fatalf("SO_RCVBUF is missing in applied options %q", res.AppliedOptions)
+	}
+
+	c := acceptDialed(t, res, res.Addr().String())
+	defer c.Close()
+	var size int
+	err = withFd(c, func(fd uintptr) error {
+		var err error
+		size, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("cannot obtain SO_RCVBUF: %s", err)
+	}
+	if size != defaultSize {
+		t.Fatalf("unexpected SO_RCVBUF %d. Expecting the default %d", size, defaultSize)
+	}
+}
+
+func TestConfigBufferSizes(t *testing.T) {
+	const size = 256 << 10
+	cfg := Config{ReceiveBufferSize: size, SendBufferSize: size}
+	res, err := NewListenerResult("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer res.Close()
+	if !res.hasOption("SO_RCVBUF") || !res.hasOption("SO_SNDBUF") {
+		t.Fatalf("SO_RCVBUF or SO_SNDBUF is missing in applied options %q", res.AppliedOptions)
+	}
+
+	for _, tc := range []struct {
+		opt   int
+		limit string
+	}{
+		{syscall.SO_RCVBUF, "net/core/rmem_max"},
+		{syscall.SO_SNDBUF, "net/core/wmem_max"},
+	} {
+		expected := size
+		if limit, err := readSysctlInt(tc.limit); err == nil && limit < expected {
+			expected = limit
+		}
+		// Linux doubles the value.
+		expected *= 2
+
+		var v int
+		err = withFd(res.Listener, func(fd uintptr) error {
+			var err error
+			v, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, tc.opt)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("cannot obtain option %d: %s", tc.opt, err)
+		}
+		if v != expected {
+			t.Fatalf("unexpected value %d of option %d. Expecting %d", v, tc.opt, expected)
+		}
+	}
+
+	cfg.DisableRecvAutotune = true
+	if _, err = NewListener("tcp4", "127.0.0.1:0", cfg); err == nil {
+		t.Fatalf("expecting error when both DisableRecvAutotune and ReceiveBufferSize are set")
+	}
+}
@@ -0,0 +1,35 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// pinRecvBuffer sets SO_RCVBUF to the current receive buffer size
+// of the socket, which disables the kernel buffer autotuning for
+// the accepted connections without changing their initial buffer size.
+func pinRecvBuffer(fd int, tr tracer) error {
+	size, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	tr.trace(TraceRecord{
+		Call:   "getsockopt",
+		Level:  syscall.SOL_SOCKET,
+		Option: "SO_RCVBUF",
+		Value:  size,
+		Err:    err,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot obtain SO_RCVBUF: %s", err)
+	}
+	// Linux doubles the value passed to SO_RCVBUF for the bookkeeping
+	// overhead and reports the doubled value.
+	if runtime.GOOS == "linux" {
+		size /= 2
+	}
+	if err = tr.setsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, "SO_RCVBUF", size); err != nil {
+		return fmt.Errorf("cannot set SO_RCVBUF to %d: %s", size, err)
+	}
+	return nil
+}
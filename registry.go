@@ -0,0 +1,85 @@
+package tcplisten
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// ListenerInfo describes a listener created with Config.Register.
+type ListenerInfo struct {
+	// Listener is the listener returned by NewListener.
+	Listener net.Listener
+
+	// Network is the network the listener has been created for.
+	Network string
+
+	// Addr is the address the listener is bound to.
+	Addr net.Addr
+
+	// AppliedOptions contains names of the socket options which have been
+	// set on the listening socket, as in ListenResult.
+	AppliedOptions []string
+
+	// Backlog is the backlog passed to listen(2), as in ListenResult.
+	Backlog int
+
+	// Config is the config the listener has been created with.
+	Config Config
+
+	// Created is the time the listener has been created at.
+	Created time.Time
+}
+
+// registry contains the listeners created with Config.Register.
+var registry struct {
+	mu    sync.Mutex
+	infos []ListenerInfo
+}
+
+func register(network string, cfg *Config, res *ListenResult) {
+	info := ListenerInfo{
+		Listener:       res.Listener,
+		Network:        network,
+		Addr:           res.Listener.Addr(),
+		AppliedOptions: append([]string(nil), res.AppliedOptions...),
+		Backlog:        res.Backlog,
+		Config:         *cfg,
+		Created:        time.Now(),
+	}
+	registry.mu.Lock()
+	registry.infos = append(registry.infos, info)
+	registry.mu.Unlock()
+}
+
+// ActiveListeners returns the listeners created with Config.Register
+// in the order of creation, omitting the closed ones.
+func ActiveListeners() []ListenerInfo {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	infos := registry.infos[:0]
+	for _, info := range registry.infos {
+		if !listenerClosed(info.Listener) {
+			infos = append(infos, info)
+		}
+	}
+	for i := len(infos); i < len(registry.infos); i++ {
+		// Release the closed listeners.
+		registry.infos[i] = ListenerInfo{}
+	}
+	registry.infos = infos
+	return append([]ListenerInfo(nil), infos...)
+}
+
+// listenerClosed reports whether the listener has been closed.
+//
+// Listeners which don't expose their file descriptor are assumed open.
+func listenerClosed(ln net.Listener) bool {
+	rc, err := rawConn(ln)
+	if err != nil {
+		return false
+	}
+	err = rc.Control(func(fd uintptr) {})
+	return err != nil && isClosedError(err)
+}
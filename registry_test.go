@@ -0,0 +1,39 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestActiveListeners(t *testing.T) {
+	ln1, err := NewListener("tcp4", "127.0.0.1:0", Config{Register: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln1.Close()
+	ln2, err := NewListener("tcp4", "127.0.0.1:0", Config{Register: true, NoDelay: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln3, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln3.Close()
+
+	infos := ActiveListeners()
+	if len(infos) != 2 || infos[0].Listener != ln1 || infos[1].Listener != ln2 {
+		t.Fatalf("unexpected active listeners %+v. Expecting %s and %s", infos, ln1.Addr(), ln2.Addr())
+	}
+	info := infos[1]
+	if info.Network != "tcp4" || info.Addr.String() != ln2.Addr().String() || info.Created.IsZero() {
+		t.Fatalf("unexpected listener info %+v", info)
+	}
+
+	ln2.Close()
+	infos = ActiveListeners()
+	if len(infos) != 1 || infos[0].Listener != ln1 {
+		t.Fatalf("unexpected active listeners %+v after closing %s. Expecting %s", infos, ln2.Addr(), ln1.Addr())
+	}
+}
@@ -0,0 +1,340 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ReloadSpec is the set of listeners owned by Reloader.
+type ReloadSpec struct {
+	// Listeners contains the networks and the addresses to listen on.
+	Listeners []ListenSpec
+
+	// Config is the config of all the listeners.
+	Config Config
+}
+
+// ReloadEventKind is the kind of ReloadEvent.
+type ReloadEventKind int
+
+const (
+	// ListenerAdded is sent for a listener the consumer should start
+	// accepting from.
+	ListenerAdded ReloadEventKind = iota + 1
+
+	// ListenerRemoved is sent for a listener about to be closed.
+	// The consumer's accept loop stops when Accept fails on the closed
+	// listener.
+	ListenerRemoved
+)
+
+// ReloadEvent notifies the Reloader consumer about a change
+// of the listener set.
+type ReloadEvent struct {
+	Kind     ReloadEventKind
+	Spec     ListenSpec
+	Listener net.Listener
+}
+
+// reloadPollInterval is the interval of polling the accept queue
+// of a listener being drained.
+const reloadPollInterval = 10 * time.Millisecond
+
+// liveConfigFields are the Config fields Reconfigure changes in place.
+var liveConfigFields = map[string]bool{
+	"DeferAccept":          true,
+	"FastOpen":             true,
+	"NoDelay":              true,
+	"QuickACK":             true,
+	"InitialRTO":           true,
+	"MaxPacingRate":        true,
+	"HardwareTimestamping": true,
+}
+
+// Reloader owns a set of listeners and rebuilds it when the spec changes,
+// e.g. when the config is pushed by a config service, instead
+// of restarting the process.
+//
+// The zero value owns no listeners. The first Apply creates them.
+type Reloader struct {
+	// OnChange is called synchronously from Apply and Close for every
+	// listener added to or removed from the set, so the consumer's accept
+	// loops follow along. ListenerAdded for a replacement listener
+	// is sent before ListenerRemoved for the replaced one if they
+	// overlap, see Apply.
+	OnChange func(ev ReloadEvent)
+
+	// DrainTimeout is the maximum time a removed listener is kept open
+	// after ListenerRemoved, so the consumer may accept the connections
+	// queued on it. The listener is closed as soon as its accept queue
+	// is empty, as reported by AcceptQueueLen. Removed listeners are
+	// closed immediately by default.
+	DrainTimeout time.Duration
+
+	mu   sync.Mutex
+	spec ReloadSpec
+	lns  map[ListenSpec]net.Listener
+}
+
+// reloadChange is a listener replaced by Apply.
+type reloadChange struct {
+	spec ListenSpec
+	old  net.Listener
+	new  net.Listener
+}
+
+// Apply changes the listener set to spec.
+//
+// The listeners for the new addresses are created, and the ones for
+// the addresses missing from spec are drained and closed. If only
+// the options Reconfigure may change differ from the previous spec,
+// the kept listeners are reconfigured in place. Otherwise they are
+// replaced with new listeners. The new listener is created before closing
+// the old one if ReusePort is enabled in both the previous and the new
+// config. Otherwise the old listener is closed first, so there is a gap
+// when incoming connections are refused.
+//
+// The removed listeners are drained concurrently after the new set is
+// in place, so Listeners and other Apply calls don't wait for them.
+//
+// If Apply fails, the changes are rolled back and the set stays
+// as it was, unless a replaced listener cannot be re-created.
+// ListenerRemoved is sent for it then.
+//
+// Config hooks such as Control cannot be compared, so the listeners
+// are always replaced if the config has hooks.
+func (r *Reloader) Apply(spec ReloadSpec) error {
+	if err := spec.Config.validate(); err != nil {
+		return err
+	}
+	if err := checkSpecs(spec.Listeners); err != nil {
+		return err
+	}
+	newSpecs := make(map[ListenSpec]bool, len(spec.Listeners))
+	for _, s := range spec.Listeners {
+		if newSpecs[s] {
+			return fmt.Errorf("duplicate listener spec %s %q", s.Network, s.Addr)
+		}
+		newSpecs[s] = true
+	}
+
+	r.mu.Lock()
+	removed, err := r.apply(spec, newSpecs)
+	timeout := r.DrainTimeout
+	r.mu.Unlock()
+
+	drainListeners(removed, timeout)
+	return err
+}
+
+// apply changes the listener set to spec and returns the removed
+// listeners to drain.
+func (r *Reloader) apply(spec ReloadSpec, newSpecs map[ListenSpec]bool) ([]net.Listener, error) {
+	oldCfg, newCfg := &r.spec.Config, &spec.Config
+	same := configsEqual(oldCfg, newCfg, nil)
+	live := !same && configsEqual(oldCfg, newCfg, liveConfigFields)
+	overlap := oldCfg.ReusePort && newCfg.ReusePort
+
+	var added, replaced, gapped []reloadChange
+	var reconfigured []net.Listener
+	rollback := func() {
+		for _, ln := range reconfigured {
+			Reconfigure(ln, *oldCfg)
+		}
+		for _, c := range append(added, replaced...) {
+			c.new.Close()
+		}
+	}
+
+	// Create the new listeners while the old ones are still serving.
+	for _, s := range spec.Listeners {
+		old, ok := r.lns[s]
+		if ok && (same || live || !overlap) {
+			continue
+		}
+		ln, err := NewListener(s.Network, s.Addr, *newCfg)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		if ok {
+			replaced = append(replaced, reloadChange{spec: s, old: old, new: ln})
+		} else {
+			added = append(added, reloadChange{spec: s, new: ln})
+		}
+	}
+
+	// Change the options of the kept listeners in place.
+	for _, s := range spec.Listeners {
+		old, ok := r.lns[s]
+		if !ok || !live {
+			continue
+		}
+		if err := Reconfigure(old, *newCfg); err != nil {
+			// The failed listener may have been changed partially.
+			reconfigured = append(reconfigured, old)
+			rollback()
+			return nil, err
+		}
+		reconfigured = append(reconfigured, old)
+	}
+
+	// Replace the kept listeners which cannot overlap with the new ones.
+	for _, s := range spec.Listeners {
+		old, ok := r.lns[s]
+		if !ok || same || live || overlap {
+			continue
+		}
+		old.Close()
+		ln, err := NewListener(s.Network, s.Addr, *newCfg)
+		if err == nil {
+			gapped = append(gapped, reloadChange{spec: s, old: old, new: ln})
+			continue
+		}
+		rollback()
+		r.restore(append(gapped, reloadChange{spec: s, old: old}), oldCfg)
+		return nil, err
+	}
+
+	for _, c := range gapped {
+		r.emit(ListenerRemoved, c.spec, c.old)
+		r.lns[c.spec] = c.new
+		r.emit(ListenerAdded, c.spec, c.new)
+	}
+	if r.lns == nil {
+		r.lns = make(map[ListenSpec]net.Listener, len(added))
+	}
+	for _, c := range append(added, replaced...) {
+		r.lns[c.spec] = c.new
+		r.emit(ListenerAdded, c.spec, c.new)
+	}
+	var removed []net.Listener
+	for _, c := range replaced {
+		r.emit(ListenerRemoved, c.spec, c.old)
+		removed = append(removed, c.old)
+	}
+	for s, ln := range r.lns {
+		if !newSpecs[s] {
+			delete(r.lns, s)
+			r.emit(ListenerRemoved, s, ln)
+			removed = append(removed, ln)
+		}
+	}
+	r.spec = ReloadSpec{
+		Listeners: append([]ListenSpec(nil), spec.Listeners...),
+		Config:    spec.Config,
+	}
+	return removed, nil
+}
+
+// restore re-creates the closed listeners of the changes with cfg
+// after a failed Apply. The listeners which cannot be re-created
+// are removed from the set.
+func (r *Reloader) restore(changes []reloadChange, cfg *Config) {
+	for _, c := range changes {
+		if c.new != nil {
+			c.new.Close()
+		}
+		r.emit(ListenerRemoved, c.spec, c.old)
+		ln, err := NewListener(c.spec.Network, c.spec.Addr, *cfg)
+		if err != nil {
+			delete(r.lns, c.spec)
+			continue
+		}
+		r.lns[c.spec] = ln
+		r.emit(ListenerAdded, c.spec, ln)
+	}
+}
+
+// Listeners returns the current listener set.
+func (r *Reloader) Listeners() map[ListenSpec]net.Listener {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lns := make(map[ListenSpec]net.Listener, len(r.lns))
+	for s, ln := range r.lns {
+		lns[s] = ln
+	}
+	return lns
+}
+
+// Close drains and closes all the listeners.
+func (r *Reloader) Close() error {
+	r.mu.Lock()
+	removed := make([]net.Listener, 0, len(r.lns))
+	for s, ln := range r.lns {
+		r.emit(ListenerRemoved, s, ln)
+		removed = append(removed, ln)
+	}
+	r.lns = nil
+	r.spec = ReloadSpec{}
+	timeout := r.DrainTimeout
+	r.mu.Unlock()
+
+	drainListeners(removed, timeout)
+	return nil
+}
+
+func (r *Reloader) emit(kind ReloadEventKind, s ListenSpec, ln net.Listener) {
+	if r.OnChange != nil {
+		r.OnChange(ReloadEvent{Kind: kind, Spec: s, Listener: ln})
+	}
+}
+
+// drainListeners drains and closes the removed listeners concurrently,
+// so the total time is bounded by timeout regardless of their number.
+func drainListeners(lns []net.Listener, timeout time.Duration) {
+	if timeout <= 0 {
+		for _, ln := range lns {
+			ln.Close()
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	for _, ln := range lns {
+		wg.Add(1)
+		go func(ln net.Listener) {
+			defer wg.Done()
+			drainListener(ln, timeout)
+		}(ln)
+	}
+	wg.Wait()
+}
+
+// drainListener waits until the accept queue of ln is empty
+// or the timeout expires, then closes ln.
+func drainListener(ln net.Listener, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if n, _, err := AcceptQueueLen(ln); err != nil || n == 0 {
+			break
+		}
+		time.Sleep(reloadPollInterval)
+	}
+	ln.Close()
+}
+
+// configsEqual reports whether a and b are equal except for the fields
+// in skip. Hooks are equal only if both are nil, since different closures
+// may share the code pointer and must not be treated as the same hook.
+func configsEqual(a, b *Config, skip map[string]bool) bool {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		if skip[va.Type().Field(i).Name] {
+			continue
+		}
+		fa, fb := va.Field(i), vb.Field(i)
+		if fa.Kind() == reflect.Func {
+			if !fa.IsNil() || !fb.IsNil() {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReloader(t *testing.T) {
+	var events []ReloadEvent
+	r := &Reloader{
+		OnChange: func(ev ReloadEvent) {
+			events = append(events, ev)
+		},
+	}
+	defer r.Close()
+
+	a := ListenSpec{"tcp4", "127.0.0.1:0"}
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	b := ListenSpec{"tcp4", ln.Addr().String()}
+	ln.Close()
+
+	cfg := Config{ReusePort: true}
+	if err = r.Apply(ReloadSpec{Listeners: []ListenSpec{a, b}, Config: cfg}); err != nil {
+		t.Fatalf("cannot apply initial spec: %s", err)
+	}
+	if len(events) != 2 || events[0].Kind != ListenerAdded || events[1].Kind != ListenerAdded {
+		t.Fatalf("unexpected events %+v. Expecting two ListenerAdded", events)
+	}
+	lnB := r.Listeners()[b]
+
+	// Options changeable in place don't recreate the listeners.
+	events = nil
+	cfg.NoDelay = true
+	if err = r.Apply(ReloadSpec{Listeners: []ListenSpec{a, b}, Config: cfg}); err != nil {
+		t.Fatalf("cannot apply live options: %s", err)
+	}
+	if len(events) != 0 || r.Listeners()[b] != lnB {
+		t.Fatalf("unexpected events %+v. Expecting the listeners to be reconfigured in place", events)
+	}
+
+	// The backlog requires a new listener created before closing the old one.
+	events = nil
+	cfg.Backlog = 16
+	if err = r.Apply(ReloadSpec{Listeners: []ListenSpec{b}, Config: cfg}); err != nil {
+		t.Fatalf("cannot apply backlog: %s", err)
+	}
+	if len(events) != 3 || events[0].Kind != ListenerAdded || events[0].Spec != b || events[1].Kind != ListenerRemoved || events[1].Listener != lnB || events[2].Kind != ListenerRemoved || events[2].Spec != a {
+		t.Fatalf("unexpected events %+v. Expecting the listener on %s to be replaced and the one on %s to be removed", events, b.Addr, a.Addr)
+	}
+	lns := r.Listeners()
+	if len(lns) != 1 || lns[b] == lnB || lns[b].Addr().String() != b.Addr {
+		t.Fatalf("unexpected listeners %v. Expecting a new listener on %s", lns, b.Addr)
+	}
+	if _, err = lnB.Accept(); err == nil {
+		t.Fatalf("the replaced listener hasn't been closed")
+	}
+
+	// A failed apply keeps the listener set.
+	events = nil
+	lnB = lns[b]
+	cfg.Backlog = 32
+	bad := ListenSpec{"tcp4", "192.0.2.1:1"}
+	if err = r.Apply(ReloadSpec{Listeners: []ListenSpec{b, bad}, Config: cfg}); err == nil {
+		t.Fatalf("expecting error when listening on %s", bad.Addr)
+	}
+	lns = r.Listeners()
+	if len(events) != 0 || len(lns) != 1 || lns[b] != lnB {
+		t.Fatalf("unexpected events %+v and listeners %v after failed apply", events, lns)
+	}
+	c, err := net.Dial("tcp4", b.Addr)
+	if err != nil {
+		t.Fatalf("cannot dial the kept listener: %s", err)
+	}
+	c.Close()
+
+	// Hooks cannot be compared, so the listener is replaced.
+	events = nil
+	cfg.Control = func(network, address string, fd uintptr) error { return nil }
+	for i := 0; i < 2; i++ {
+		if err = r.Apply(ReloadSpec{Listeners: []ListenSpec{b}, Config: cfg}); err != nil {
+			t.Fatalf("cannot apply config with hook: %s", err)
+		}
+	}
+	if len(events) != 4 {
+		t.Fatalf("unexpected events %+v. Expecting the listener to be replaced twice", events)
+	}
+}
+
+func TestReloaderDrainConcurrently(t *testing.T) {
+	const timeout = 300 * time.Millisecond
+	r := &Reloader{DrainTimeout: timeout}
+
+	var specs []ListenSpec
+	for i := 0; i < 3; i++ {
+		ln, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		specs = append(specs, ListenSpec{"tcp4", ln.Addr().String()})
+		ln.Close()
+	}
+	if err := r.Apply(ReloadSpec{Listeners: specs}); err != nil {
+		t.Fatalf("cannot apply spec: %s", err)
+	}
+
+	// Leave a connection in the accept queue of every listener,
+	// so each of them is drained until the timeout.
+	for _, s := range specs {
+		c, err := net.Dial("tcp4", s.Addr)
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		defer c.Close()
+	}
+
+	start := time.Now()
+	r.Close()
+	if d := time.Since(start); d >= 2*timeout {
+		t.Fatalf("draining took %s. Expecting less than %s for concurrent draining", d, 2*timeout)
+	}
+}
@@ -0,0 +1,60 @@
+package tcplisten
+
+import (
+	"net"
+)
+
+// ConnState is the state of an established TCP connection captured
+// by CheckpointConn.
+//
+// It contains only exported plain fields, so it may be passed between
+// processes with any encoding, e.g. encoding/gob.
+type ConnState struct {
+	// LocalAddr and RemoteAddr are the endpoints of the connection.
+	LocalAddr  *net.TCPAddr
+	RemoteAddr *net.TCPAddr
+
+	// SendSeq is the sequence number of the first byte in SendQueue.
+	SendSeq uint32
+
+	// RecvSeq is the sequence number of the first byte in RecvQueue.
+	RecvSeq uint32
+
+	// SendQueue contains the data written by the application, but not
+	// acknowledged by the peer yet.
+	SendQueue []byte
+
+	// RecvQueue contains the data received from the peer, but not read
+	// by the application yet.
+	RecvQueue []byte
+
+	// MSS is the maximum segment size negotiated with the peer.
+	MSS uint32
+
+	// SACK and Timestamps report whether the corresponding TCP options
+	// have been negotiated.
+	SACK       bool
+	Timestamps bool
+
+	// SendWScale and RecvWScale are the negotiated window scales.
+	// Window scaling is disabled if both are zero.
+	SendWScale uint8
+	RecvWScale uint8
+
+	// Timestamp is the current TCP timestamp of the connection.
+	Timestamp uint32
+
+	// Window contains the window state. It is nil if the kernel
+	// doesn't support TCP_REPAIR_WINDOW.
+	Window *RepairWindow
+}
+
+// RepairWindow is the window state of a repaired connection
+// (struct tcp_repair_window).
+type RepairWindow struct {
+	SndWl1    uint32
+	SndWnd    uint32
+	MaxWindow uint32
+	RcvWnd    uint32
+	RcvWup    uint32
+}
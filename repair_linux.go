@@ -0,0 +1,315 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	tcpRepair        = 19
+	tcpRepairQueue   = 20
+	tcpQueueSeq      = 21
+	tcpRepairOptions = 22
+	tcpTimestamp     = 24
+	tcpRepairWindow  = 29
+
+	tcpRecvQueue = 1
+	tcpSendQueue = 2
+
+	tcpoptMSS       = 2
+	tcpoptWindow    = 3
+	tcpoptSACKPerm  = 4
+	tcpoptTimestamp = 8
+
+	tcpiOptTimestamps = 1
+	tcpiOptSACK       = 2
+	tcpiOptWScale     = 4
+)
+
+type tcpRepairOpt struct {
+	code uint32
+	val  uint32
+}
+
+// CheckpointConn captures the state of the established connection,
+// so it may be restored with RestoreConn in another process.
+//
+// The connection is switched to TCP_REPAIR mode, where it doesn't
+// send or receive anything. Closing it in this mode doesn't notify
+// the peer, so c must be closed after the state has been handed over.
+//
+// It requires CAP_NET_ADMIN and is supported only on Linux.
+func CheckpointConn(c net.Conn) (*ConnState, error) {
+	local, ok := c.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return nil, fmt.Errorf("cannot checkpoint non-TCP connection %T", c)
+	}
+	remote, _ := c.RemoteAddr().(*net.TCPAddr)
+	st := &ConnState{
+		LocalAddr:  local,
+		RemoteAddr: remote,
+	}
+	err := withFd(c, func(fd uintptr) error {
+		return checkpointFd(int(fd), st)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func checkpointFd(fd int, st *ConnState) (err error) {
+	if err = syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpRepair, 1); err != nil {
+		return fmt.Errorf("cannot enable TCP_REPAIR: %s", err)
+	}
+	defer func() {
+		if err != nil {
+			syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpRepair, 0)
+		}
+	}()
+
+	var ti syscall.TCPInfo
+	if err = getTCPInfo(fd, &ti); err != nil {
+		return fmt.Errorf("cannot obtain TCP_INFO: %s", err)
+	}
+	if ti.State != 1 {
+		return fmt.Errorf("cannot checkpoint connection in state %d: it must be established", ti.State)
+	}
+	st.SACK = ti.Options&tcpiOptSACK != 0
+	st.Timestamps = ti.Options&tcpiOptTimestamps != 0
+	if ti.Options&tcpiOptWScale != 0 {
+		// snd_wscale and rcv_wscale are 4-bit fields following tcpi_options.
+		// The padding field holding them is named differently on some
+		// architectures, so it is accessed by offset.
+		b := *(*uint8)(unsafe.Pointer(uintptr(unsafe.Pointer(&ti.Options)) + 1))
+		if nativeEndian == binary.LittleEndian {
+			st.SendWScale, st.RecvWScale = b&0xf, b>>4
+		} else {
+			st.SendWScale, st.RecvWScale = b>>4, b&0xf
+		}
+	}
+	mss, err := syscall.GetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_MAXSEG)
+	if err != nil {
+		return fmt.Errorf("cannot obtain TCP_MAXSEG: %s", err)
+	}
+	st.MSS = uint32(mss)
+
+	if st.Timestamps {
+		ts, err := syscall.GetsockoptInt(fd, syscall.IPPROTO_TCP, tcpTimestamp)
+		if err != nil {
+			return fmt.Errorf("cannot obtain TCP_TIMESTAMP: %s", err)
+		}
+		st.Timestamp = uint32(ts)
+	}
+
+	if st.SendSeq, st.SendQueue, err = checkpointQueue(fd, tcpSendQueue); err != nil {
+		return err
+	}
+	if st.RecvSeq, st.RecvQueue, err = checkpointQueue(fd, tcpRecvQueue); err != nil {
+		return err
+	}
+
+	var w RepairWindow
+	l := uint32(unsafe.Sizeof(w))
+	if getsockopt(fd, syscall.IPPROTO_TCP, tcpRepairWindow, unsafe.Pointer(&w), &l) == nil {
+		st.Window = &w
+	}
+	return nil
+}
+
+// checkpointQueue returns the sequence number and the contents
+// of the given socket queue.
+func checkpointQueue(fd, queue int) (uint32, []byte, error) {
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpRepairQueue, queue); err != nil {
+		return 0, nil, fmt.Errorf("cannot select repair queue %d: %s", queue, err)
+	}
+	seq, err := syscall.GetsockoptInt(fd, syscall.IPPROTO_TCP, tcpQueueSeq)
+	if err != nil {
+		return 0, nil, fmt.Errorf("cannot obtain sequence of repair queue %d: %s", queue, err)
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		if err == syscall.EAGAIN {
+			return uint32(seq), nil, nil
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("cannot read repair queue %d: %s", queue, err)
+		}
+		if n < len(buf) {
+			// TCP_QUEUE_SEQ is the sequence number following the queue.
+			return uint32(seq) - uint32(n), buf[:n], nil
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// RestoreConn re-creates the connection checkpointed by CheckpointConn.
+//
+// The original connection must be closed before the restored one sends
+// anything, otherwise the peer sees two sockets with the same state.
+//
+// It requires CAP_NET_ADMIN and is supported only on Linux.
+func RestoreConn(st *ConnState) (net.Conn, error) {
+	if st.LocalAddr == nil || st.RemoteAddr == nil {
+		return nil, fmt.Errorf("cannot restore connection without addresses")
+	}
+	domain := syscall.AF_INET
+	if st.LocalAddr.IP.To4() == nil {
+		domain = syscall.AF_INET6
+	}
+	fd, err := newSocketCloexec(domain, syscall.SOCK_STREAM, syscall.IPPROTO_TCP)
+	if err != nil {
+		return nil, err
+	}
+	// connect(2) in repair mode doesn't block, so switch to blocking mode
+	// until the connection is restored.
+	if err = syscall.SetNonblock(fd, false); err == nil {
+		err = restoreFd(fd, domain, st)
+	}
+	if err == nil {
+		err = syscall.SetNonblock(fd, true)
+	}
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("tcplisten-restored.%s-%s", st.LocalAddr, st.RemoteAddr))
+	c, err := net.FileConn(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func restoreFd(fd, domain int, st *ConnState) error {
+	// TCP_REPAIR also forces address reuse, so the socket may be bound
+	// to the port of the listener which has accepted the connection.
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpRepair, 1); err != nil {
+		return fmt.Errorf("cannot enable TCP_REPAIR: %s", err)
+	}
+
+	for _, q := range [...]struct {
+		queue int
+		seq   uint32
+	}{
+		{tcpSendQueue, st.SendSeq},
+		{tcpRecvQueue, st.RecvSeq},
+	} {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpRepairQueue, q.queue); err != nil {
+			return fmt.Errorf("cannot select repair queue %d: %s", q.queue, err)
+		}
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpQueueSeq, int(q.seq)); err != nil {
+			return fmt.Errorf("cannot set sequence of repair queue %d: %s", q.queue, err)
+		}
+	}
+
+	local, err := tcpAddrToSockaddr(domain, st.LocalAddr)
+	if err != nil {
+		return err
+	}
+	remote, err := tcpAddrToSockaddr(domain, st.RemoteAddr)
+	if err != nil {
+		return err
+	}
+	if err = syscall.Bind(fd, local); err != nil {
+		return fmt.Errorf("cannot bind to %s: %s", st.LocalAddr, err)
+	}
+	if err = syscall.Connect(fd, remote); err != nil {
+		return fmt.Errorf("cannot connect to %s in repair mode: %s", st.RemoteAddr, err)
+	}
+
+	opts := []tcpRepairOpt{{tcpoptMSS, st.MSS}}
+	if st.SendWScale != 0 || st.RecvWScale != 0 {
+		opts = append(opts, tcpRepairOpt{tcpoptWindow, uint32(st.SendWScale) | uint32(st.RecvWScale)<<16})
+	}
+	if st.SACK {
+		opts = append(opts, tcpRepairOpt{tcpoptSACKPerm, 0})
+	}
+	if st.Timestamps {
+		opts = append(opts, tcpRepairOpt{tcpoptTimestamp, 0})
+	}
+	if err = setsockopt(fd, syscall.IPPROTO_TCP, tcpRepairOptions, unsafe.Pointer(&opts[0]), uint32(len(opts))*uint32(unsafe.Sizeof(opts[0]))); err != nil {
+		return fmt.Errorf("cannot set TCP_REPAIR_OPTIONS: %s", err)
+	}
+	if st.Timestamps {
+		if err = syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpTimestamp, int(st.Timestamp)); err != nil {
+			return fmt.Errorf("cannot set TCP_TIMESTAMP: %s", err)
+		}
+	}
+
+	if err = restoreQueue(fd, tcpSendQueue, st.SendQueue); err != nil {
+		return err
+	}
+	if err = restoreQueue(fd, tcpRecvQueue, st.RecvQueue); err != nil {
+		return err
+	}
+
+	if st.Window != nil {
+		w := *st.Window
+		if err = setsockopt(fd, syscall.IPPROTO_TCP, tcpRepairWindow, unsafe.Pointer(&w), uint32(unsafe.Sizeof(w))); err != nil {
+			return fmt.Errorf("cannot set TCP_REPAIR_WINDOW: %s", err)
+		}
+	}
+
+	if err = syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpRepair, 0); err != nil {
+		return fmt.Errorf("cannot disable TCP_REPAIR: %s", err)
+	}
+	return nil
+}
+
+// restoreQueue fills the given socket queue with data.
+func restoreQueue(fd, queue int, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, tcpRepairQueue, queue); err != nil {
+		return fmt.Errorf("cannot select repair queue %d: %s", queue, err)
+	}
+	for len(data) > 0 {
+		n, err := syscall.Write(fd, data)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot restore repair queue %d: %s", queue, err)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func tcpAddrToSockaddr(domain int, addr *net.TCPAddr) (syscall.Sockaddr, error) {
+	if domain == syscall.AF_INET {
+		ip := addr.IP.To4()
+		if ip == nil {
+			return nil, fmt.Errorf("cannot use %s in IPv4 connection", addr)
+		}
+		sa := &syscall.SockaddrInet4{Port: addr.Port}
+		copy(sa.Addr[:], ip)
+		return sa, nil
+	}
+	ip := addr.IP.To16()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %s", addr)
+	}
+	sa := &syscall.SockaddrInet6{Port: addr.Port}
+	copy(sa.Addr[:], ip)
+	if addr.Zone != "" {
+		zoneID, err := zoneIndex(addr.Zone)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve zone of %s: %s", addr, err)
+		}
+		sa.ZoneId = zoneID
+	}
+	return sa, nil
+}
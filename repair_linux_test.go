@@ -0,0 +1,71 @@
+package tcplisten
+
+import (
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCheckpointRestoreConn(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	client, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer client.Close()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+
+	// The unread data must migrate with the connection.
+	if _, err = client.Write([]byte("ping")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	st, err := CheckpointConn(c)
+	if err != nil {
+		c.Close()
+		if strings.Contains(err.Error(), syscall.EPERM.Error()) {
+			t.Skipf("TCP_REPAIR is unavailable: %s", err)
+		}
+		t.Fatalf("cannot checkpoint connection: %s", err)
+	}
+	c.Close()
+	if string(st.RecvQueue) != "ping" {
+		t.Fatalf("unexpected receive queue %q. Expecting %q", st.RecvQueue, "ping")
+	}
+
+	rc, err := RestoreConn(st)
+	if err != nil {
+		t.Fatalf("cannot restore connection: %s", err)
+	}
+	defer rc.Close()
+
+	buf := make([]byte, 4)
+	if _, err = io.ReadFull(rc, buf); err != nil {
+		t.Fatalf("cannot read from restored connection: %s", err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("unexpected data %q. Expecting %q", buf, "ping")
+	}
+	if _, err = rc.Write([]byte("pong")); err != nil {
+		t.Fatalf("cannot write to restored connection: %s", err)
+	}
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err = io.ReadFull(client, buf); err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if string(buf) != "pong" {
+		t.Fatalf("unexpected data %q. Expecting %q", buf, "pong")
+	}
+}
@@ -0,0 +1,27 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// CheckpointConn captures the state of the established connection.
+//
+// TCP_REPAIR exists only on Linux.
+func CheckpointConn(c net.Conn) (*ConnState, error) {
+	return nil, &UnsupportedError{
+		Op:     "CheckpointConn",
+		Reason: "TCP_REPAIR exists only on Linux",
+	}
+}
+
+// RestoreConn re-creates the connection checkpointed by CheckpointConn.
+//
+// TCP_REPAIR exists only on Linux.
+func RestoreConn(st *ConnState) (net.Conn, error) {
+	return nil, &UnsupportedError{
+		Op:     "RestoreConn",
+		Reason: "TCP_REPAIR exists only on Linux",
+	}
+}
@@ -0,0 +1,73 @@
+package tcplisten
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// resolveTCPAddr works like net.ResolveTCPAddr, but aborts resolving
+// the host name when ctx is done.
+//
+// Like net.ResolveTCPAddr, it prefers IPv4 addresses for the tcp network.
+func resolveTCPAddr(ctx context.Context, network, addr string) (*net.TCPAddr, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" || isIPLiteral(host) {
+		// Nothing to look up.
+		return net.ResolveTCPAddr(network, addr)
+	}
+	portNum, err := net.DefaultResolver.LookupPort(ctx, network, port)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var found *net.IPAddr
+	for i := range ips {
+		ip := &ips[i]
+		isV4 := ip.IP.To4() != nil
+		if network == "tcp4" && !isV4 || network == "tcp6" && isV4 {
+			continue
+		}
+		if found == nil || network == "tcp" && isV4 && found.IP.To4() == nil {
+			found = ip
+		}
+	}
+	if found == nil {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+	return &net.TCPAddr{IP: found.IP, Port: portNum, Zone: found.Zone}, nil
+}
+
+// isIPLiteral reports whether host is an IP address with an optional zone.
+func isIPLiteral(host string) bool {
+	for i := 0; i < len(host); i++ {
+		if host[i] == '%' {
+			host = host[:i]
+			break
+		}
+	}
+	return net.ParseIP(host) != nil
+}
+
+// zoneIndex returns the index of the interface named zone. Like the net
+// package, it accepts a numeric zone as the index if there is no interface
+// with such a name.
+func zoneIndex(zone string) (uint32, error) {
+	ifi, err := net.InterfaceByName(zone)
+	if err == nil {
+		return uint32(ifi.Index), nil
+	}
+	if n, perr := strconv.ParseUint(zone, 10, 32); perr == nil {
+		return uint32(n), nil
+	}
+	return 0, err
+}
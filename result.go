@@ -0,0 +1,78 @@
+package tcplisten
+
+import (
+	"errors"
+	"net"
+)
+
+// errOptionSkipped is returned by option appliers which silently ignore
+// the option on the current platform.
+var errOptionSkipped = errors.New("option skipped")
+
+// ListenResult is the listener created by NewListenerResult together
+// with the details of its creation.
+type ListenResult struct {
+	net.Listener
+
+	// AppliedOptions contains names of the socket options which have been
+	// successfully set on the listening socket, in the order of application.
+	//
+	// Options ignored on the current platform are omitted.
+	AppliedOptions []string
+
+	// Backlog is the backlog passed to listen(2).
+	//
+	// It is zero if the backlog isn't controlled by the package
+	// on the current platform.
+	Backlog int
+
+	// BoundAddr is the address the listener is bound to.
+	//
+	// It contains the actual port if the listener has been created
+	// for port 0.
+	BoundAddr *net.TCPAddr
+
+	// Override is the override set of ListenAll applied to the listener,
+	// i.e. OverrideTCP4 or OverrideTCP6. It is empty if no override
+	// has been applied.
+	Override string
+
+	// Tuning contains the settings derived by Config.AutoTune
+	// together with the reasons for choosing them.
+	Tuning []TuningDecision
+
+	// onSkip is called for options ignored on the current platform.
+	onSkip func(name string)
+}
+
+func (res *ListenResult) applied(name string) {
+	if !res.hasOption(name) {
+		res.AppliedOptions = append(res.AppliedOptions, name)
+	}
+}
+
+// hasOption reports whether the option has been applied.
+func (res *ListenResult) hasOption(name string) bool {
+	for _, o := range res.AppliedOptions {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// record registers the option as applied if err is nil.
+func (res *ListenResult) record(name string, err error) error {
+	switch err {
+	case nil:
+		res.applied(name)
+		return nil
+	case errOptionSkipped:
+		if res.onSkip != nil {
+			res.onSkip(name)
+		}
+		return nil
+	default:
+		return err
+	}
+}
@@ -0,0 +1,39 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestNewListenerResult(t *testing.T) {
+	res, err := NewListenerResult("tcp4", "127.0.0.1:0", Config{
+		ReusePort:   true,
+		DeferAccept: true,
+		NoDelay:     true,
+		Backlog:     32,
+	})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer res.Close()
+
+	expected := []string{"SO_REUSEADDR", "TCP_NODELAY", "SO_REUSEPORT", "TCP_DEFER_ACCEPT"}
+	if len(res.AppliedOptions) != len(expected) {
+		t.Fatalf("unexpected applied options %v. Expecting %v", res.AppliedOptions, expected)
+	}
+	for i, o := range expected {
+		if res.AppliedOptions[i] != o {
+			t.Fatalf("unexpected applied options %v. Expecting %v", res.AppliedOptions, expected)
+		}
+	}
+	if res.Backlog != 32 {
+		t.Fatalf("unexpected backlog %d. Expecting 32", res.Backlog)
+	}
+	if res.BoundAddr == nil || res.BoundAddr.Port == 0 {
+		t.Fatalf("unexpected bound address %v", res.BoundAddr)
+	}
+	if res.BoundAddr.String() != res.Addr().String() {
+		t.Fatalf("unexpected bound address %s. Expecting %s", res.BoundAddr, res.Addr())
+	}
+}
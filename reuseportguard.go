@@ -0,0 +1,58 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// checkReusePortGroup implements Config.ExclusiveReusePortGroup
+// for the socket about to be bound to sa.
+func (cfg *Config) checkReusePortGroup(sa syscall.Sockaddr, addr string, tr tracer) error {
+	port := sockaddrPort(sa)
+	if port == 0 {
+		// The kernel picks a port nobody listens on.
+		return nil
+	}
+	family := syscall.AF_INET
+	if _, ok := sa.(*syscall.SockaddrInet6); ok {
+		family = syscall.AF_INET6
+	}
+	members, err := reusePortMembers(&net.TCPAddr{IP: sockaddrIP(sa), Port: port}, family)
+	tr.trace(TraceRecord{Call: "sock_diag", Addr: addr, Err: err})
+	if err != nil {
+		loggerOrDefault(cfg.Logger).Printf("tcplisten: cannot check the SO_REUSEPORT group on %q, binding anyway: %s", addr, err)
+		return nil
+	}
+
+	foreign := foreignMembers(members, os.Getpid())
+	if len(foreign) == 0 {
+		return nil
+	}
+	ferr := &ForeignReusePortError{Addr: addr, Members: foreign}
+	if cfg.AllowForeignReusePort {
+		loggerOrDefault(cfg.Logger).Printf("%s, binding anyway", ferr)
+		return nil
+	}
+	return ferr
+}
+
+// foreignMembers returns the members which aren't held by the given process.
+func foreignMembers(members []GroupMember, pid int) []GroupMember {
+	var foreign []GroupMember
+	for _, m := range members {
+		own := false
+		for _, p := range m.PIDs {
+			if p == pid {
+				own = true
+				break
+			}
+		}
+		if !own {
+			foreign = append(foreign, m)
+		}
+	}
+	return foreign
+}
@@ -0,0 +1,67 @@
+package tcplisten
+
+// Classic BPF opcodes and offsets used by the reuseport programs.
+const (
+	bpfLdBAbs  = 0x30
+	bpfLdWAbs  = 0x20
+	bpfRshK    = 0x74
+	bpfMulK    = 0x24
+	bpfModK    = 0x94
+	bpfXorX    = 0xac
+	bpfJeqK    = 0x15
+	bpfJa      = 0x05
+	bpfTax     = 0x07
+	bpfRetA    = 0x16
+	bpfNetOff  = 0xFFF00000      // SKF_NET_OFF
+	bpfAdCPU   = 0xFFFFF000 + 36 // SKF_AD_OFF + SKF_AD_CPU
+	hashFactor = 0x9E3779B1
+)
+
+// ReusePortHashProgram returns a classic BPF program for
+// SO_ATTACH_REUSEPORT_CBPF selecting one of n sockets in a SO_REUSEPORT
+// group by a hash of the source address of the client.
+//
+// The hash covers only the address, so all the connections from a client
+// land on the same socket. IPv4 addresses are hashed as a big-endian
+// uint32 and IPv6 addresses as the XOR of their four words, with
+// the result multiplied by 0x9E3779B1, shifted right by 16 and taken
+// modulo n.
+func ReusePortHashProgram(n int) []RawInstruction {
+	return []RawInstruction{
+		// The version of the IP header.
+		{Op: bpfLdBAbs, K: bpfNetOff},
+		{Op: bpfRshK, K: 4},
+		{Op: bpfJeqK, Jt: 2, K: 6},
+
+		// The IPv4 source address.
+		{Op: bpfLdWAbs, K: bpfNetOff + 12},
+		{Op: bpfJa, K: 10},
+
+		// The IPv6 source address.
+		{Op: bpfLdWAbs, K: bpfNetOff + 8},
+		{Op: bpfTax},
+		{Op: bpfLdWAbs, K: bpfNetOff + 12},
+		{Op: bpfXorX},
+		{Op: bpfTax},
+		{Op: bpfLdWAbs, K: bpfNetOff + 16},
+		{Op: bpfXorX},
+		{Op: bpfTax},
+		{Op: bpfLdWAbs, K: bpfNetOff + 20},
+		{Op: bpfXorX},
+
+		{Op: bpfMulK, K: hashFactor},
+		{Op: bpfRshK, K: 16},
+		{Op: bpfModK, K: uint32(n)},
+		{Op: bpfRetA},
+	}
+}
+
+// reusePortCPUProgram returns a program selecting one of n sockets
+// by the index of the current CPU modulo n.
+func reusePortCPUProgram(n int) []RawInstruction {
+	return []RawInstruction{
+		{Op: bpfLdWAbs, K: bpfAdCPU},
+		{Op: bpfModK, K: uint32(n)},
+		{Op: bpfRetA},
+	}
+}
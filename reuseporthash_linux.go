@@ -0,0 +1,54 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+const soAttachReusePortCBPF = 51
+
+// AttachReusePortHashBalance attaches ReusePortHashProgram to the SO_REUSEPORT
+// group of the listeners, so every client is steered to the same listener
+// for deterministic client-to-listener affinity, e.g. for stateful
+// per-client processing.
+//
+// The listeners must be all the members of the group in the order they have
+// been created, e.g. the ones returned by NewShardGroup, since the kernel
+// indexes the group members by the order they have started listening.
+// Closing a member moves the last member to its index, so the affinity
+// changes then. The kernel falls back to the default selection if the group
+// has fewer members than listeners.
+//
+// Linux 4.5 or newer is required.
+func AttachReusePortHashBalance(listeners []net.Listener) error {
+	if len(listeners) == 0 {
+		return fmt.Errorf("cannot attach reuseport program to an empty group")
+	}
+	addr := listeners[0].Addr().String()
+	for _, ln := range listeners[1:] {
+		if ln.Addr().String() != addr {
+			return fmt.Errorf("cannot attach reuseport program: %s and %s aren't in the same group", addr, ln.Addr())
+		}
+	}
+
+	return attachReusePortProgram(listeners[0], ReusePortHashProgram(len(listeners)))
+}
+
+// attachReusePortProgram attaches the program to the SO_REUSEPORT group
+// of the listener.
+func attachReusePortProgram(ln net.Listener, prog []RawInstruction) error {
+	fprog := syscall.SockFprog{
+		Len:    uint16(len(prog)),
+		Filter: (*syscall.SockFilter)(unsafe.Pointer(&prog[0])),
+	}
+	return withFd(ln, func(fd uintptr) error {
+		if err := setsockopt(int(fd), syscall.SOL_SOCKET, soAttachReusePortCBPF, unsafe.Pointer(&fprog), uint32(unsafe.Sizeof(fprog))); err != nil {
+			return fmt.Errorf("cannot attach reuseport program: %s", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,104 @@
+package tcplisten
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAttachReusePortHashBalance(t *testing.T) {
+	const shards = 4
+	lns, port, err := NewShardGroup("tcp4", "127.0.0.1:0", shards, Config{})
+	if err != nil {
+		t.Fatalf("cannot create shard group: %s", err)
+	}
+	for _, ln := range lns {
+		defer ln.Close()
+		// Fail instead of hanging if the connection is steered elsewhere.
+		ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	}
+	if err = AttachReusePortHashBalance(lns); err != nil {
+		t.Fatalf("cannot attach program: %s", err)
+	}
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	for i := 1; i <= 16; i++ {
+		src := net.IPv4(127, 0, 0, byte(i)).To4()
+		want := int(binary.BigEndian.Uint32(src)*hashFactor>>16) % shards
+		for j := 0; j < 2; j++ {
+			d := net.Dialer{LocalAddr: &net.TCPAddr{IP: src}}
+			c, err := d.Dial("tcp4", addr)
+			if err != nil {
+				t.Fatalf("cannot dial from %s: %s", src, err)
+			}
+			lport := c.LocalAddr().(*net.TCPAddr).Port
+			sc, err := lns[want].Accept()
+			if err != nil {
+				t.Fatalf("cannot accept: %s", err)
+			}
+			if rport := sc.RemoteAddr().(*net.TCPAddr).Port; rport != lport {
+				t.Fatalf("connection from %s:%d has been accepted instead of %s:%d by listener %d", sc.RemoteAddr(), rport, src, lport, want)
+			}
+			sc.Close()
+			c.Close()
+		}
+	}
+}
+
+func TestShardGroupConsistentRouting(t *testing.T) {
+	for _, r := range []Routing{RouteBySourceIP, RouteByCPU} {
+		testShardGroupRouting(t, r)
+	}
+}
+
+func testShardGroupRouting(t *testing.T, r Routing) {
+	const shards = 3
+	lns, port, err := NewShardGroup("tcp4", "127.0.0.1:0", shards, Config{}, WithConsistentRouting(r))
+	if err != nil {
+		t.Fatalf("cannot create shard group: %s", err)
+	}
+	for _, ln := range lns {
+		defer ln.Close()
+	}
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	for i := 1; i <= 8; i++ {
+		src := net.IPv4(127, 0, 0, byte(i))
+		prev := -1
+		for j := 0; j < 4; j++ {
+			d := net.Dialer{LocalAddr: &net.TCPAddr{IP: src}}
+			c, err := d.Dial("tcp4", addr)
+			if err != nil {
+				t.Fatalf("cannot dial from %s: %s", src, err)
+			}
+			shard := queuedShard(t, lns)
+			sc, err := lns[shard].Accept()
+			if err != nil {
+				t.Fatalf("cannot accept: %s", err)
+			}
+			sc.Close()
+			c.Close()
+			if r == RouteBySourceIP && prev >= 0 && shard != prev {
+				t.Fatalf("connection #%d from %s has been routed to shard %d instead of %d", j, src, shard, prev)
+			}
+			prev = shard
+		}
+	}
+}
+
+// queuedShard returns the index of the listener with a queued connection.
+func queuedShard(t *testing.T, lns []net.Listener) int {
+	for i, ln := range lns {
+		n, _, err := AcceptQueueLen(ln)
+		if err != nil {
+			t.Fatalf("cannot obtain accept queue length: %s", err)
+		}
+		if n > 0 {
+			return i
+		}
+	}
+	t.Fatalf("no shard has queued the connection")
+	return -1
+}
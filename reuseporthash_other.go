@@ -0,0 +1,25 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// AttachReusePortHashBalance makes the SO_REUSEPORT group of the listeners
+// select the listener by a hash of the client address.
+//
+// It is supported only on Linux.
+func AttachReusePortHashBalance(listeners []net.Listener) error {
+	return &UnsupportedError{
+		Op:     "AttachReusePortHashBalance",
+		Reason: "SO_ATTACH_REUSEPORT_CBPF is supported only on Linux",
+	}
+}
+
+func attachReusePortProgram(ln net.Listener, prog []RawInstruction) error {
+	return &UnsupportedError{
+		Op:     "WithConsistentRouting",
+		Reason: "SO_ATTACH_REUSEPORT_CBPF is supported only on Linux",
+	}
+}
@@ -0,0 +1,21 @@
+// +build freebsd
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+const soReusePortLB = 0x00010000
+
+func init() {
+	optionSockopts[OptionReusePortLB] = sockopt{syscall.SOL_SOCKET, soReusePortLB}
+}
+
+func enableReusePortLB(fd int, tr tracer) error {
+	if err := tr.setOption(fd, OptionReusePortLB, 1); err != nil {
+		return fmt.Errorf("cannot enable SO_REUSEPORT_LB: %s", err)
+	}
+	return nil
+}
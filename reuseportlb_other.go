@@ -0,0 +1,11 @@
+// +build !freebsd,!windows,!plan9
+
+package tcplisten
+
+import (
+	"fmt"
+)
+
+func enableReusePortLB(fd int, tr tracer) error {
+	return fmt.Errorf("cannot enable SO_REUSEPORT_LB: it exists only on FreeBSD: %w", ErrUnsupportedOption)
+}
@@ -0,0 +1,78 @@
+package tcplisten
+
+import (
+	"fmt"
+)
+
+// ServiceClass is the traffic class of the accepted connections, which
+// influences how the local network stack and the network queue their
+// packets.
+//
+// It is mapped to SO_NET_SERVICE_TYPE on macOS and to the DSCP bits
+// of IP_TOS or IPV6_TCLASS together with SO_PRIORITY on Linux.
+type ServiceClass int
+
+const (
+	// ServiceClassDefault leaves the traffic class at the system default.
+	ServiceClassDefault ServiceClass = iota
+
+	// ServiceClassBestEffort is for regular traffic.
+	ServiceClassBestEffort
+
+	// ServiceClassBackground is for bulk transfers which may be delayed
+	// in favor of other traffic, e.g. backups.
+	ServiceClassBackground
+
+	// ServiceClassSignaling is for small latency-sensitive control
+	// messages, e.g. call setup.
+	ServiceClassSignaling
+
+	// ServiceClassVideo is for interactive video.
+	ServiceClassVideo
+
+	// ServiceClassVoice is for interactive voice.
+	ServiceClassVoice
+
+	serviceClassCount
+)
+
+// serviceClassParams are the platform values of a ServiceClass.
+type serviceClassParams struct {
+	// netServiceType is NET_SERVICE_TYPE_* on macOS.
+	netServiceType int
+
+	// dscp is the DSCP code point, which is shifted into the upper
+	// six bits of IP_TOS and IPV6_TCLASS on Linux.
+	dscp int
+
+	// priority is SO_PRIORITY on Linux, i.e. TC_PRIO_*.
+	priority int
+}
+
+// serviceClasses maps every class except ServiceClassDefault to
+// the platform values. The DSCP code points follow the ones macOS uses
+// for the corresponding NET_SERVICE_TYPE.
+var serviceClasses = [serviceClassCount]serviceClassParams{
+	ServiceClassBestEffort: {netServiceType: 0, dscp: 0, priority: 0},  // NET_SERVICE_TYPE_BE, CS0, TC_PRIO_BESTEFFORT
+	ServiceClassBackground: {netServiceType: 1, dscp: 8, priority: 2},  // NET_SERVICE_TYPE_BK, CS1, TC_PRIO_BULK
+	ServiceClassSignaling:  {netServiceType: 2, dscp: 40, priority: 6}, // NET_SERVICE_TYPE_SIG, CS5, TC_PRIO_INTERACTIVE
+	ServiceClassVideo:      {netServiceType: 3, dscp: 34, priority: 4}, // NET_SERVICE_TYPE_VI, AF41, TC_PRIO_INTERACTIVE_BULK
+	ServiceClassVoice:      {netServiceType: 4, dscp: 46, priority: 6}, // NET_SERVICE_TYPE_VO, EF, TC_PRIO_INTERACTIVE
+}
+
+// params returns the platform values of the class and false for
+// ServiceClassDefault and invalid classes.
+func (c ServiceClass) params() (serviceClassParams, bool) {
+	if c <= ServiceClassDefault || c >= serviceClassCount {
+		return serviceClassParams{}, false
+	}
+	return serviceClasses[c], true
+}
+
+// validate returns an error for classes other than the defined ones.
+func (c ServiceClass) validate() error {
+	if c < ServiceClassDefault || c >= serviceClassCount {
+		return fmt.Errorf("invalid ServiceClass %d", int(c))
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+// +build darwin
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+const soNetServiceType = 0x1116
+
+// setServiceClass sets SO_NET_SERVICE_TYPE, which is inherited
+// by the accepted connections.
+func setServiceClass(fd int, sa syscall.Sockaddr, c ServiceClass, tr tracer, res *ListenResult) error {
+	p, ok := c.params()
+	if !ok {
+		return nil
+	}
+	if err := tr.setsockoptInt(fd, syscall.SOL_SOCKET, soNetServiceType, "SO_NET_SERVICE_TYPE", p.netServiceType); err != nil {
+		return fmt.Errorf("cannot set SO_NET_SERVICE_TYPE to %d: %s", p.netServiceType, err)
+	}
+	res.applied("SO_NET_SERVICE_TYPE")
+	return nil
+}
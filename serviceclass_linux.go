@@ -0,0 +1,31 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setServiceClass sets the DSCP bits of IP_TOS or IPV6_TCLASS depending
+// on the family of sa and SO_PRIORITY for the class.
+func setServiceClass(fd int, sa syscall.Sockaddr, c ServiceClass, tr tracer, res *ListenResult) error {
+	p, ok := c.params()
+	if !ok {
+		return nil
+	}
+	level, opt, option := syscall.IPPROTO_IP, syscall.IP_TOS, "IP_TOS"
+	if _, isV6 := sa.(*syscall.SockaddrInet6); isV6 {
+		level, opt, option = syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, "IPV6_TCLASS"
+	}
+	if err := tr.setsockoptInt(fd, level, opt, option, p.dscp<<2); err != nil {
+		return fmt.Errorf("cannot set %s to DSCP %d: %s", option, p.dscp, err)
+	}
+	res.applied(option)
+
+	if err := tr.setsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_PRIORITY, "SO_PRIORITY", p.priority); err != nil {
+		return fmt.Errorf("cannot set SO_PRIORITY to %d: %s", p.priority, err)
+	}
+	res.applied("SO_PRIORITY")
+	return nil
+}
@@ -0,0 +1,53 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestServiceClassInherited(t *testing.T) {
+	for _, tc := range []struct {
+		network string
+		addr    string
+		level   int
+		opt     int
+	}{
+		{"tcp4", "127.0.0.1:0", syscall.IPPROTO_IP, syscall.IP_TOS},
+		{"tcp6", "[::1]:0", syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS},
+	} {
+		ln, err := NewListener(tc.network, tc.addr, Config{ServiceClass: ServiceClassVideo})
+		if err != nil {
+			t.Fatalf("cannot create %s listener: %s", tc.network, err)
+		}
+		var prio int
+		err = withFd(ln, func(fd uintptr) error {
+			var err error
+			prio, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_PRIORITY)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("cannot obtain SO_PRIORITY of %s listener: %s", tc.network, err)
+		}
+		if prio != 4 {
+			t.Fatalf("unexpected SO_PRIORITY %d of %s listener. Expecting 4", prio, tc.network)
+		}
+
+		c := acceptDialed(t, ln, ln.Addr().String())
+		var tos int
+		err = withFd(c, func(fd uintptr) error {
+			var err error
+			tos, err = syscall.GetsockoptInt(int(fd), tc.level, tc.opt)
+			return err
+		})
+		c.Close()
+		ln.Close()
+		if err != nil {
+			t.Fatalf("cannot obtain traffic class of %s connection: %s", tc.network, err)
+		}
+		if tos != 34<<2 {
+			t.Fatalf("unexpected traffic class %d of %s connection. Expecting %d", tos, tc.network, 34<<2)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+// +build !linux,!darwin,!windows,!plan9
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func setServiceClass(fd int, sa syscall.Sockaddr, c ServiceClass, tr tracer, res *ListenResult) error {
+	return fmt.Errorf("cannot set ServiceClass: it is supported only on Linux and macOS: %w", ErrUnsupportedOption)
+}
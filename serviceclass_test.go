@@ -0,0 +1,45 @@
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestServiceClassParams(t *testing.T) {
+	for _, tc := range []struct {
+		c              ServiceClass
+		netServiceType int
+		dscp           int
+		priority       int
+	}{
+		{ServiceClassBestEffort, 0, 0, 0},
+		{ServiceClassBackground, 1, 8, 2},
+		{ServiceClassSignaling, 2, 40, 6},
+		{ServiceClassVideo, 3, 34, 4},
+		{ServiceClassVoice, 4, 46, 6},
+	} {
+		p, ok := tc.c.params()
+		if !ok {
+			t.Fatalf("missing params for ServiceClass %d", tc.c)
+		}
+		if p.netServiceType != tc.netServiceType || p.dscp != tc.dscp || p.priority != tc.priority {
+			t.Fatalf("unexpected params %+v for ServiceClass %d", p, tc.c)
+		}
+	}
+	if _, ok := ServiceClassDefault.params(); ok {
+		t.Fatalf("ServiceClassDefault mustn't have params")
+	}
+}
+
+func TestValidateServiceClass(t *testing.T) {
+	for _, c := range []ServiceClass{-1, serviceClassCount, 100} {
+		if err := (Config{ServiceClass: c}).Validate(); err == nil {
+			t.Fatalf("expecting error for ServiceClass %d", c)
+		}
+		if _, err := NewListener("tcp4", "127.0.0.1:0", Config{ServiceClass: c}); err == nil {
+			t.Fatalf("expecting error for ServiceClass %d", c)
+		}
+	}
+	if err := (Config{ServiceClass: ServiceClassVoice}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
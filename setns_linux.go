@@ -0,0 +1,9 @@
+// +build linux,!amd64,!386
+
+package tcplisten
+
+import (
+	"syscall"
+)
+
+const sysSetns = syscall.SYS_SETNS
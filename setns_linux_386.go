@@ -0,0 +1,5 @@
+package tcplisten
+
+// sysSetns is the setns(2) syscall number, which is missing
+// in the syscall package for linux/386.
+const sysSetns = 346
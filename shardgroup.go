@@ -0,0 +1,275 @@
+package tcplisten
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// shardGroupAttempts is the number of attempts NewShardGroup makes
+// for creating a group on an ephemeral port.
+const shardGroupAttempts = 5
+
+// Routing selects the shard of a group for incoming connections.
+type Routing int
+
+const (
+	// RouteBySourceIP routes all the connections from a client to the same
+	// shard by a hash of the client address. See ReusePortHashProgram.
+	RouteBySourceIP Routing = iota + 1
+
+	// RouteByCPU routes connections to the shard with the index of the CPU
+	// which has received the SYN modulo the number of shards, so the SYN
+	// is handled on the same CPU as the accepting goroutine if the shards
+	// are served by goroutines locked to the corresponding CPUs.
+	RouteByCPU
+)
+
+func (r Routing) program(shards int) []RawInstruction {
+	if r == RouteByCPU {
+		return reusePortCPUProgram(shards)
+	}
+	return ReusePortHashProgram(shards)
+}
+
+// ShardGroupOption changes the behavior of NewShardGroup.
+type ShardGroupOption func(*shardGroupConfig)
+
+type shardGroupConfig struct {
+	routing Routing
+	mode    ShardMode
+}
+
+// ShardMode is the way the shards of a group share the address.
+type ShardMode int
+
+const (
+	// ShardModeAuto selects ShardModeReusePort on the platforms where
+	// SO_REUSEPORT distributes connections among the listeners, i.e. Linux,
+	// DragonFly and FreeBSD with Config.ReusePortLB, and ShardModeSharedFd
+	// elsewhere.
+	ShardModeAuto ShardMode = iota
+
+	// ShardModeReusePort creates a listening socket per shard with
+	// SO_REUSEPORT, so the kernel distributes connections among them.
+	ShardModeReusePort
+
+	// ShardModeSharedFd creates a single listening socket and duplicates
+	// its descriptor for every shard, so the shards accept from the same
+	// queue. The socket is closed when all the shards are closed.
+	//
+	// The runtime poller watches every descriptor separately, so a new
+	// connection would wake a goroutine blocked in Accept on every shard,
+	// all of them but one failing with EAGAIN. To avoid the thundering herd,
+	// the shards take turns: only one of them waits in the poller at a time,
+	// while the others wait for it to accept a connection. Accept throughput
+	// is limited by the single accept queue anyway.
+	//
+	// It isn't supported on Windows and Plan 9.
+	ShardModeSharedFd
+)
+
+// WithShardMode overrides the way the shards of the group share the address.
+func WithShardMode(m ShardMode) ShardGroupOption {
+	return func(sc *shardGroupConfig) {
+		sc.mode = m
+	}
+}
+
+// reusePortBalances reports whether SO_REUSEPORT with cfg distributes
+// connections among the listeners on the current platform.
+func reusePortBalances(cfg *Config) bool {
+	switch runtime.GOOS {
+	case "linux", "dragonfly":
+		return true
+	case "freebsd":
+		return cfg.ReusePortLB
+	}
+	return false
+}
+
+// WithConsistentRouting makes NewShardGroup attach a SO_ATTACH_REUSEPORT_CBPF
+// program routing connections to the shards with r.
+//
+// The program relies on the group size, so the group must stay intact:
+// closing a shard makes the kernel move the last shard to its index.
+// It is supported only on Linux 4.5 and newer.
+func WithConsistentRouting(r Routing) ShardGroupOption {
+	return func(sc *shardGroupConfig) {
+		sc.routing = r
+	}
+}
+
+// NewShardGroup returns the given number of listeners sharing addr
+// with SO_REUSEPORT, so the kernel distributes incoming connections
+// among them. cfg.ReusePort is forced to true unless cfg.ReusePortLB is set.
+//
+// On the platforms where SO_REUSEPORT doesn't distribute connections,
+// e.g. macOS, the listeners share a single socket instead.
+// See ShardMode for details.
+//
+// If the port in addr is 0, the first listener is bound to a port chosen
+// by the kernel and the rest of the listeners are created on that port.
+// The whole group is re-created on a new port if another process grabs
+// the port in the meantime, up to a few times.
+//
+// The port the group is bound to is returned alongside the listeners.
+// Use WithConsistentRouting for routing connections to the shards
+// deterministically.
+//
+// It isn't supported on Windows, which has no SO_REUSEPORT.
+func NewShardGroup(network, addr string, shards int, cfg Config, opts ...ShardGroupOption) ([]net.Listener, int, error) {
+	var sc shardGroupConfig
+	for _, opt := range opts {
+		opt(&sc)
+	}
+	if shards <= 0 {
+		return nil, 0, fmt.Errorf("cannot create shard group with %d shards", shards)
+	}
+	if runtime.GOOS == "windows" {
+		return nil, 0, fmt.Errorf("cannot create shard group: SO_REUSEPORT doesn't exist on Windows: %w", ErrUnsupportedOption)
+	}
+	if err := checkSpecs([]ListenSpec{{Network: network, Addr: addr}}); err != nil {
+		return nil, 0, err
+	}
+	if sc.mode == ShardModeAuto {
+		sc.mode = ShardModeSharedFd
+		if reusePortBalances(&cfg) || sc.routing != 0 || runtime.GOOS == "plan9" {
+			sc.mode = ShardModeReusePort
+		}
+	}
+	if sc.mode == ShardModeSharedFd {
+		if sc.routing != 0 {
+			return nil, 0, errors.New("cannot route connections to the shards sharing a socket")
+		}
+		return newSharedShardGroup(network, addr, shards, cfg)
+	}
+	host, sport, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !cfg.ReusePortLB {
+		cfg.ReusePort = true
+	}
+	ephemeral := sport == "0" || sport == ""
+
+	for attempt := 1; ; attempt++ {
+		lns, port, err := newShardGroup(network, addr, host, shards, cfg, &sc)
+		if err == nil {
+			return lns, port, nil
+		}
+		if !ephemeral || len(lns) == 0 || attempt == shardGroupAttempts {
+			return nil, 0, err
+		}
+	}
+}
+
+// newShardGroup creates the group. It returns the listeners created
+// before the failure, already closed, together with the error.
+func newShardGroup(network, addr, host string, shards int, cfg Config, sc *shardGroupConfig) ([]net.Listener, int, error) {
+	first, err := NewListener(network, addr, cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	if sc.routing != 0 {
+		// The program is attached before the other shards join the group,
+		// so no connection is routed without it.
+		if err = attachReusePortProgram(first, sc.routing.program(shards)); err != nil {
+			first.Close()
+			return nil, 0, err
+		}
+	}
+	port := first.Addr().(*net.TCPAddr).Port
+	lns := []net.Listener{first}
+	addr = net.JoinHostPort(host, strconv.Itoa(port))
+	for len(lns) < shards {
+		ln, err := NewListener(network, addr, cfg)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return lns, 0, fmt.Errorf("cannot create shard #%d on %q: %w", len(lns), addr, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, port, nil
+}
+
+// newSharedShardGroup creates the shards sharing a single socket.
+func newSharedShardGroup(network, addr string, shards int, cfg Config) ([]net.Listener, int, error) {
+	ln, err := NewListener(network, addr, cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	token := make(chan struct{}, 1)
+	token <- struct{}{}
+	lns := []net.Listener{newSharedShard(ln, token)}
+	for len(lns) < shards {
+		dup, err := dupListener(ln)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, 0, fmt.Errorf("cannot create shard #%d on %q: %w", len(lns), addr, err)
+		}
+		dup = cfg.wrapListener(dup)
+		lns = append(lns, newSharedShard(dup, token))
+	}
+	return lns, ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// sharedShard is a shard sharing the socket with the other shards
+// of the group. The shards pass the token around, so only the holder
+// waits for connections in the runtime poller.
+type sharedShard struct {
+	net.Listener
+
+	token     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSharedShard(ln net.Listener, token chan struct{}) *sharedShard {
+	return &sharedShard{
+		Listener: ln,
+		token:    token,
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *sharedShard) Accept() (net.Conn, error) {
+	select {
+	case <-s.token:
+	case <-s.done:
+		return nil, ErrListenerClosed
+	}
+	c, err := s.Listener.Accept()
+	s.token <- struct{}{}
+	if err != nil {
+		select {
+		case <-s.done:
+			return nil, ErrListenerClosed
+		default:
+		}
+	}
+	return c, err
+}
+
+// Close closes the descriptor of the shard. The socket is closed
+// when all the shards are closed.
+func (s *sharedShard) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.Listener.Close()
+	})
+	return err
+}
+
+func (s *sharedShard) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(s.Listener)
+}
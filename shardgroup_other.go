@@ -0,0 +1,14 @@
+// +build windows plan9
+
+package tcplisten
+
+import (
+	"net"
+)
+
+func dupListener(ln net.Listener) (net.Listener, error) {
+	return nil, &UnsupportedError{
+		Op:     "ShardModeSharedFd",
+		Reason: "listening sockets cannot be duplicated",
+	}
+}
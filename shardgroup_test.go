@@ -0,0 +1,140 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewShardGroup(t *testing.T) {
+	lns, port, err := NewShardGroup("tcp4", "127.0.0.1:0", 4, Config{})
+	if err != nil {
+		t.Fatalf("cannot create shard group: %s", err)
+	}
+	if len(lns) != 4 {
+		t.Fatalf("unexpected number of shards %d. Expecting 4", len(lns))
+	}
+	if port == 0 {
+		t.Fatalf("expecting non-zero port")
+	}
+	for i, ln := range lns {
+		defer ln.Close()
+		if p := ln.Addr().(*net.TCPAddr).Port; p != port {
+			t.Fatalf("unexpected port %d of shard #%d. Expecting %d", p, i, port)
+		}
+	}
+
+	if _, _, err = NewShardGroup("tcp4", "127.0.0.1:0", 0, Config{}); err == nil {
+		t.Fatalf("expecting error for zero shards")
+	}
+}
+
+func TestShardGroupSharedFd(t *testing.T) {
+	lns, port, err := NewShardGroup("tcp4", "127.0.0.1:0", 4, Config{}, WithShardMode(ShardModeSharedFd))
+	if err != nil {
+		t.Fatalf("cannot create shard group: %s", err)
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	accepted := make(chan int, 100)
+	var wg sync.WaitGroup
+	for i, ln := range lns {
+		wg.Add(1)
+		go func(i int, ln net.Listener) {
+			defer wg.Done()
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					if err != ErrListenerClosed {
+						t.Errorf("unexpected error: %s", err)
+					}
+					return
+				}
+				c.Close()
+				accepted <- i
+			}
+		}(i, ln)
+	}
+
+	dial := func() int {
+		c, err := net.Dial("tcp4", addr)
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		defer c.Close()
+		select {
+		case i := <-accepted:
+			return i
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the connection hasn't been accepted")
+		}
+		return -1
+	}
+	for i := 0; i < 20; i++ {
+		dial()
+	}
+
+	// The socket stays open until the last shard is closed.
+	lns[0].Close()
+	for i := 0; i < 10; i++ {
+		if shard := dial(); shard == 0 {
+			t.Fatalf("the connection has been accepted by the closed shard")
+		}
+	}
+	for _, ln := range lns[1:] {
+		ln.Close()
+	}
+	wg.Wait()
+	if c, err := net.Dial("tcp4", addr); err == nil {
+		c.Close()
+		t.Fatalf("expecting error when dialing the closed group")
+	}
+}
+
+func BenchmarkShardGroup(b *testing.B) {
+	for _, mode := range []ShardMode{ShardModeReusePort, ShardModeSharedFd} {
+		name := "ReusePort"
+		if mode == ShardModeSharedFd {
+			name = "SharedFd"
+		}
+		b.Run(name, func(b *testing.B) {
+			lns, port, err := NewShardGroup("tcp4", "127.0.0.1:0", 4, Config{}, WithShardMode(mode))
+			if err != nil {
+				b.Fatalf("cannot create shard group: %s", err)
+			}
+			for _, ln := range lns {
+				go func(ln net.Listener) {
+					for {
+						c, err := ln.Accept()
+						if err != nil {
+							return
+						}
+						c.Close()
+					}
+				}(ln)
+			}
+			addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				buf := make([]byte, 1)
+				for pb.Next() {
+					c, err := net.Dial("tcp4", addr)
+					if err != nil {
+						b.Fatalf("cannot dial: %s", err)
+					}
+					// Wait for the connection to be accepted and closed.
+					c.Read(buf)
+					c.Close()
+				}
+			})
+			b.StopTimer()
+			for _, ln := range lns {
+				ln.Close()
+			}
+		})
+	}
+}
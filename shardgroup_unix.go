@@ -0,0 +1,34 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// dupListener returns a listener for a duplicate of the descriptor of ln.
+func dupListener(ln net.Listener) (net.Listener, error) {
+	var fd int
+	err := withFd(ln, func(lfd uintptr) error {
+		var err error
+		syscall.ForkLock.RLock()
+		fd, err = syscall.Dup(int(lfd))
+		if err == nil {
+			syscall.CloseOnExec(fd)
+		}
+		syscall.ForkLock.RUnlock()
+		if err != nil {
+			return fmt.Errorf("cannot duplicate listening socket: %s", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), fileNamePrefix+"shard."+ln.Addr().String())
+	defer f.Close()
+	return net.FileListener(f)
+}
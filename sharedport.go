@@ -0,0 +1,73 @@
+package tcplisten
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"syscall"
+)
+
+// NewListenerSharedPort returns a listener on addr whose port may be shared
+// with outgoing connections dialed by SharedPortDialer, e.g. for NAT hole
+// punching, where the listener must accept connections on the source port
+// of an outgoing connection.
+//
+// The port may be bound by the listener and the dialed connections in any
+// order. All the sockets sharing the port must have SO_REUSEADDR and
+// SO_REUSEPORT enabled before bind, so cfg.ReusePort is forced to true.
+// Linux additionally requires all the sockets to belong to the same user.
+//
+// Windows has no SO_REUSEPORT, so the listener and the dialed sockets rely
+// on SO_REUSEADDR, which permits binding to a port in use by a non-exclusive
+// socket. It is enabled before cfg.Control is called there.
+func NewListenerSharedPort(network, addr string, cfg Config) (net.Listener, error) {
+	if runtime.GOOS != "windows" {
+		cfg.ReusePort = true
+		return NewListener(network, addr, cfg)
+	}
+	control := cfg.Control
+	cfg.Control = func(network, address string, fd uintptr) error {
+		if err := enableSharedPort(fd); err != nil {
+			return err
+		}
+		if control != nil {
+			return control(network, address, fd)
+		}
+		return nil
+	}
+	return NewListener(network, addr, cfg)
+}
+
+// SharedPortDialer returns a dialer whose connections are bound to laddr,
+// which may be the address of a listener created with NewListenerSharedPort.
+func SharedPortDialer(laddr *net.TCPAddr) *net.Dialer {
+	return &net.Dialer{
+		LocalAddr: laddr,
+		Control: func(network, address string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) {
+				err = enableSharedPort(fd)
+			}); cerr != nil {
+				return cerr
+			}
+			return err
+		},
+	}
+}
+
+func enableSharedPort(fd uintptr) error {
+	for _, o := range []Option{OptionReuseAddr, OptionReusePort} {
+		so, err := lookupOption(o)
+		if err != nil {
+			if o == OptionReusePort && errors.Is(err, ErrUnsupportedOption) {
+				continue
+			}
+			return err
+		}
+		if err = so.set(fd, 1); err != nil {
+			return fmt.Errorf("cannot enable %s: %s", o, err)
+		}
+	}
+	return nil
+}
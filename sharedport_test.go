@@ -0,0 +1,64 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"net"
+	"testing"
+)
+
+func TestNewListenerSharedPort(t *testing.T) {
+	ln, err := NewListenerSharedPort("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	laddr := ln.Addr().(*net.TCPAddr)
+
+	peer, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create peer listener: %s", err)
+	}
+	defer peer.Close()
+
+	c, err := SharedPortDialer(laddr).Dial("tcp4", peer.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial from the listener port: %s", err)
+	}
+	defer c.Close()
+	if port := c.LocalAddr().(*net.TCPAddr).Port; port != laddr.Port {
+		t.Fatalf("unexpected source port %d. Expecting %d", port, laddr.Port)
+	}
+
+	// The listener still accepts connections on the shared port.
+	pc, err := net.Dial("tcp4", laddr.String())
+	if err != nil {
+		t.Fatalf("cannot dial listener: %s", err)
+	}
+	defer pc.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	sc.Close()
+}
+
+func TestNewListenerSharedPortAfterDial(t *testing.T) {
+	peer, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create peer listener: %s", err)
+	}
+	defer peer.Close()
+
+	c, err := SharedPortDialer(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}).Dial("tcp4", peer.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+
+	ln, err := NewListenerSharedPort("tcp4", c.LocalAddr().String(), Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener on the source port: %s", err)
+	}
+	ln.Close()
+}
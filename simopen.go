@@ -0,0 +1,20 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+)
+
+// NewSimultaneousOpenListener is meant to return a listener whose socket
+// may also be used for TCP simultaneous open.
+//
+// This isn't possible on any supported platform: a socket either listens
+// or connects, and connect(2) on a listening socket fails. The function
+// always returns an error wrapping ErrUnsupportedOption.
+//
+// NAT traversal schemes should instead use a listener with Config.ReusePort
+// and dial from the same local port with SO_REUSEADDR and SO_REUSEPORT
+// set on the dialing socket, e.g. via net.Dialer.Control.
+func NewSimultaneousOpenListener(network, addr string, cfg Config) (net.Listener, error) {
+	return nil, fmt.Errorf("cannot listen and connect on the same socket for %q: %w", addr, ErrUnsupportedOption)
+}
@@ -0,0 +1,268 @@
+package tcplisten
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultSniffTimeout is the default time Sniffer waits for the first bytes
+// of a connection before passing it to the default route.
+const DefaultSniffTimeout = time.Second
+
+// sniffLen is the maximum number of bytes peeked for routing a connection.
+const sniffLen = 64
+
+// SniffRoute describes a protocol served by a Sniffer route.
+type SniffRoute struct {
+	// Match must report whether the first bytes of a connection belong
+	// to the route.
+	//
+	// Match may be called multiple times with growing prefixes,
+	// so it must return false if b is too short to decide.
+	Match func(b []byte) bool
+}
+
+// MatchTLS matches TLS connections by the handshake record header.
+func MatchTLS(b []byte) bool {
+	return len(b) >= 3 && b[0] == 0x16 && b[1] == 0x03 && b[2] <= 0x04
+}
+
+var httpMethods = [][]byte{
+	[]byte("GET "),
+	[]byte("HEAD "),
+	[]byte("POST "),
+	[]byte("PUT "),
+	[]byte("DELETE "),
+	[]byte("CONNECT "),
+	[]byte("OPTIONS "),
+	[]byte("TRACE "),
+	[]byte("PATCH "),
+	[]byte("PRI "),
+}
+
+// MatchHTTP matches plaintext HTTP connections by the request method.
+func MatchHTTP(b []byte) bool {
+	for _, m := range httpMethods {
+		if bytes.HasPrefix(b, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPrefix returns a matcher for connections starting with any
+// of the given prefixes.
+func MatchPrefix(prefixes ...string) func(b []byte) bool {
+	return func(b []byte) bool {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(b, []byte(p)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Sniffer routes connections accepted from a listener by their first bytes.
+//
+// The bytes are inspected with MSG_PEEK, so they aren't consumed and
+// the routed connections are passed as is, without replay wrappers.
+//
+// Use SniffListener for creating Sniffer.
+type Sniffer struct {
+	// Timeout is the maximum duration to wait for the first bytes
+	// of a connection. Connections which don't send anything matching
+	// the routes during Timeout are passed to the default route.
+	//
+	// DefaultSniffTimeout is used by default.
+	Timeout time.Duration
+
+	ln     net.Listener
+	routes []*sniffRoute
+	def    *sniffRoute
+}
+
+// SniffListener returns Sniffer routing connections accepted from ln
+// to the given routes.
+//
+// Accepted connections are dispatched to listeners returned
+// from Sniffer.Route, which correspond to routes. Connections matching
+// no route, silent connections and connections which cannot be
+// inspected are dispatched to Sniffer.Default.
+//
+// Sniffer.Serve must be called for accepting connections.
+func SniffListener(ln net.Listener, routes ...SniffRoute) *Sniffer {
+	s := &Sniffer{
+		Timeout: DefaultSniffTimeout,
+		ln:      ln,
+	}
+	for _, r := range routes {
+		s.routes = append(s.routes, newSniffRoute(ln, r.Match))
+	}
+	s.def = newSniffRoute(ln, nil)
+	return s
+}
+
+// MuxListener returns Sniffer without routes for ln. The routes are
+// registered with Sniffer.Match, similar to cmux:
+//
+//	mux := tcplisten.MuxListener(ln)
+//	tlsLn := mux.Match(tcplisten.MatchTLS)
+//	httpLn := mux.Match(tcplisten.MatchHTTP)
+//	go mux.Serve()
+//
+// Connections matching no route are dispatched to Sniffer.Default.
+func MuxListener(ln net.Listener) *Sniffer {
+	return SniffListener(ln)
+}
+
+// Match registers a route for connections whose first bytes satisfy match
+// and returns the listener for it. See SniffRoute.Match for the matcher
+// requirements. Routes are tried in the order of registration,
+// following the routes passed to SniffListener.
+//
+// Match must be called before Serve.
+func (s *Sniffer) Match(match func(b []byte) bool) net.Listener {
+	r := newSniffRoute(s.ln, match)
+	s.routes = append(s.routes, r)
+	return r
+}
+
+// Route returns the listener for the i-th route passed to SniffListener.
+func (s *Sniffer) Route(i int) net.Listener {
+	return s.routes[i]
+}
+
+// Default returns the listener for connections matching no route.
+func (s *Sniffer) Default() net.Listener {
+	return s.def
+}
+
+// Serve accepts connections and dispatches them to routes until
+// the underlying listener fails or is closed.
+//
+// Route listeners are closed when Serve returns.
+func (s *Sniffer) Serve() error {
+	defer s.closeRoutes()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(5 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.dispatch(c)
+	}
+}
+
+// Close closes the underlying listener and all the route listeners.
+func (s *Sniffer) Close() error {
+	err := s.ln.Close()
+	s.closeRoutes()
+	return err
+}
+
+func (s *Sniffer) closeRoutes() {
+	for _, r := range s.routes {
+		r.Close()
+	}
+	s.def.Close()
+}
+
+func (s *Sniffer) dispatch(c net.Conn) {
+	r := s.classify(c)
+	select {
+	case r.ch <- c:
+	case <-r.done:
+		c.Close()
+	}
+}
+
+func (s *Sniffer) classify(c net.Conn) *sniffRoute {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSniffTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	if err := c.SetReadDeadline(deadline); err != nil {
+		return s.def
+	}
+	defer c.SetReadDeadline(time.Time{})
+
+	var buf [sniffLen]byte
+	prev := 0
+	delay := time.Millisecond
+	for {
+		n, err := peek(c, buf[:])
+		if err != nil || n == 0 {
+			return s.def
+		}
+		for _, r := range s.routes {
+			if r.match(buf[:n]) {
+				return r
+			}
+		}
+		if n == len(buf) {
+			return s.def
+		}
+		if n == prev {
+			// Peeking returns immediately while there are unread bytes,
+			// so wait for the rest of the prefix.
+			if time.Now().Add(delay).After(deadline) {
+				return s.def
+			}
+			time.Sleep(delay)
+			if delay < 20*time.Millisecond {
+				delay *= 2
+			}
+		}
+		prev = n
+	}
+}
+
+// sniffRoute is a listener facade for connections dispatched by Sniffer.
+type sniffRoute struct {
+	ln        net.Listener
+	match     func(b []byte) bool
+	ch        chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSniffRoute(ln net.Listener, match func(b []byte) bool) *sniffRoute {
+	return &sniffRoute{
+		ln:    ln,
+		match: match,
+		ch:    make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (r *sniffRoute) Accept() (net.Conn, error) {
+	select {
+	case c := <-r.ch:
+		return c, nil
+	case <-r.done:
+		return nil, ErrListenerClosed
+	}
+}
+
+func (r *sniffRoute) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+	return nil
+}
+
+func (r *sniffRoute) Addr() net.Addr {
+	return r.ln.Addr()
+}
+
+func (r *sniffRoute) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(r.ln)
+}
@@ -0,0 +1,96 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSniffListener(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	s := SniffListener(ln, SniffRoute{Match: MatchTLS}, SniffRoute{Match: MatchHTTP})
+	s.Timeout = 100 * time.Millisecond
+	serveCh := make(chan error, 1)
+	go func() {
+		serveCh <- s.Serve()
+	}()
+
+	testSniffRoute(t, ln.Addr().String(), s.Route(0), "\x16\x03\x01\x00\x05hello")
+	testSniffRoute(t, ln.Addr().String(), s.Route(1), "GET / HTTP/1.1\r\n\r\n")
+	testSniffRoute(t, ln.Addr().String(), s.Default(), "SSH-2.0-OpenSSH\r\n")
+	testSniffRoute(t, ln.Addr().String(), s.Default(), "")
+
+	if err = s.Close(); err != nil {
+		t.Fatalf("unexpected error when closing sniffer: %s", err)
+	}
+	select {
+	case <-serveCh:
+	case <-time.After(time.Second):
+		t.Fatalf("timeout when waiting for Serve to return")
+	}
+	if _, err = s.Route(0).Accept(); err != ErrListenerClosed {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrListenerClosed)
+	}
+}
+
+func TestMuxListener(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	mux := MuxListener(ln)
+	mux.Timeout = 100 * time.Millisecond
+	tlsLn := mux.Match(MatchTLS)
+	pingLn := mux.Match(MatchPrefix("PING"))
+	go mux.Serve()
+	defer mux.Close()
+
+	testSniffRoute(t, ln.Addr().String(), pingLn, "PING\r\n")
+	testSniffRoute(t, ln.Addr().String(), tlsLn, "\x16\x03\x03\x00\x05hello")
+	testSniffRoute(t, ln.Addr().String(), mux.Default(), "GET / HTTP/1.1\r\n\r\n")
+}
+
+func testSniffRoute(t *testing.T, addr string, route net.Listener, req string) {
+	c, err := net.Dial("tcp4", addr)
+	if err != nil {
+		t.Fatalf("cannot dial %s: %s", addr, err)
+	}
+	defer c.Close()
+	if _, err = c.Write([]byte(req)); err != nil {
+		t.Fatalf("cannot write request: %s", err)
+	}
+
+	ch := make(chan net.Conn, 1)
+	go func() {
+		sc, err := route.Accept()
+		if err != nil {
+			t.Errorf("cannot accept connection for %q: %s", req, err)
+		}
+		ch <- sc
+	}()
+	var sc net.Conn
+	select {
+	case sc = <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("timeout when waiting for connection with %q", req)
+	}
+	if sc == nil {
+		t.FailNow()
+	}
+	defer sc.Close()
+
+	// The sniffed bytes must be still readable.
+	buf := make([]byte, len(req))
+	if _, err = io.ReadFull(sc, buf); err != nil {
+		t.Fatalf("cannot read request %q: %s", req, err)
+	}
+	if string(buf) != req {
+		t.Fatalf("unexpected request %q. Expecting %q", buf, req)
+	}
+}
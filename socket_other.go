@@ -1,5 +1,5 @@
 // +build !darwin
-// +build !windows
+// +build !windows,!plan9
 
 package tcplisten
 
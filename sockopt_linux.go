@@ -0,0 +1,54 @@
+// +build linux,!386
+
+package tcplisten
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// getsockopt calls getsockopt(2) with an arbitrary option buffer.
+func getsockopt(fd, level, opt int, p unsafe.Pointer, l *uint32) error {
+	_, _, e := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), uintptr(level), uintptr(opt), uintptr(p), uintptr(unsafe.Pointer(l)), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// setsockopt calls setsockopt(2) with an arbitrary option buffer.
+func setsockopt(fd, level, opt int, p unsafe.Pointer, l uint32) error {
+	_, _, e := syscall.Syscall6(syscall.SYS_SETSOCKOPT, uintptr(fd), uintptr(level), uintptr(opt), uintptr(p), uintptr(l), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// bind calls bind(2) with an arbitrary socket address,
+// e.g. the ones unknown to the syscall package.
+func bind(fd int, sa unsafe.Pointer, l uint32) error {
+	_, _, e := syscall.Syscall(syscall.SYS_BIND, uintptr(fd), uintptr(sa), uintptr(l))
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// getsockname calls getsockname(2) with an arbitrary socket address buffer.
+func getsockname(fd int, sa unsafe.Pointer, l *uint32) error {
+	_, _, e := syscall.Syscall(syscall.SYS_GETSOCKNAME, uintptr(fd), uintptr(sa), uintptr(unsafe.Pointer(l)))
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// accept4 calls accept4(2) with an arbitrary socket address buffer.
+func accept4(fd int, sa unsafe.Pointer, l *uint32, flags int) (int, error) {
+	nfd, _, e := syscall.Syscall6(syscall.SYS_ACCEPT4, uintptr(fd), uintptr(sa), uintptr(unsafe.Pointer(l)), uintptr(flags), 0, 0)
+	if e != 0 {
+		return -1, e
+	}
+	return int(nfd), nil
+}
@@ -0,0 +1,70 @@
+// +build linux,386
+
+package tcplisten
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysBind        = 2
+	sysGetsockname = 6
+	sysSetsockopt  = 14
+	sysGetsockopt  = 15
+	sysAccept4     = 18
+)
+
+// getsockopt calls getsockopt(2) with an arbitrary option buffer.
+//
+// linux/386 has no dedicated getsockopt syscall on older kernels,
+// so socketcall(2) is used.
+func getsockopt(fd, level, opt int, p unsafe.Pointer, l *uint32) error {
+	args := [5]uintptr{uintptr(fd), uintptr(level), uintptr(opt), uintptr(p), uintptr(unsafe.Pointer(l))}
+	_, _, e := syscall.Syscall(syscall.SYS_SOCKETCALL, sysGetsockopt, uintptr(unsafe.Pointer(&args[0])), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// setsockopt calls setsockopt(2) with an arbitrary option buffer.
+func setsockopt(fd, level, opt int, p unsafe.Pointer, l uint32) error {
+	args := [5]uintptr{uintptr(fd), uintptr(level), uintptr(opt), uintptr(p), uintptr(l)}
+	_, _, e := syscall.Syscall(syscall.SYS_SOCKETCALL, sysSetsockopt, uintptr(unsafe.Pointer(&args[0])), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// bind calls bind(2) with an arbitrary socket address,
+// e.g. the ones unknown to the syscall package.
+func bind(fd int, sa unsafe.Pointer, l uint32) error {
+	args := [3]uintptr{uintptr(fd), uintptr(sa), uintptr(l)}
+	_, _, e := syscall.Syscall(syscall.SYS_SOCKETCALL, sysBind, uintptr(unsafe.Pointer(&args[0])), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// getsockname calls getsockname(2) with an arbitrary socket address buffer.
+func getsockname(fd int, sa unsafe.Pointer, l *uint32) error {
+	args := [3]uintptr{uintptr(fd), uintptr(sa), uintptr(unsafe.Pointer(l))}
+	_, _, e := syscall.Syscall(syscall.SYS_SOCKETCALL, sysGetsockname, uintptr(unsafe.Pointer(&args[0])), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
+
+// accept4 calls accept4(2) with an arbitrary socket address buffer.
+func accept4(fd int, sa unsafe.Pointer, l *uint32, flags int) (int, error) {
+	args := [4]uintptr{uintptr(fd), uintptr(sa), uintptr(unsafe.Pointer(l)), uintptr(flags)}
+	nfd, _, e := syscall.Syscall(syscall.SYS_SOCKETCALL, sysAccept4, uintptr(unsafe.Pointer(&args[0])), 0)
+	if e != 0 {
+		return -1, e
+	}
+	return int(nfd), nil
+}
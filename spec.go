@@ -0,0 +1,115 @@
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ListenSpec is the network and the address of a listener created
+// by NewListenerGroup.
+type ListenSpec struct {
+	Network string
+	Addr    string
+}
+
+// AddrFamilyError is returned when the host of the address is an IP literal
+// of the family other than the one of the network, e.g. tcp4 with [::1]:80.
+type AddrFamilyError struct {
+	Network string
+	Addr    string
+}
+
+func (e *AddrFamilyError) Error() string {
+	if e.Network == "tcp4" {
+		return fmt.Sprintf("cannot listen on %q with tcp4: it is an IPv6 address, use tcp6 or tcp", e.Addr)
+	}
+	return fmt.Sprintf("cannot listen on %q with tcp6: it is an IPv4 address, use tcp4 or tcp", e.Addr)
+}
+
+// SpecErrors contains an error for every invalid spec passed to a listener
+// group constructor, so all of them may be fixed at once.
+type SpecErrors []error
+
+func (e SpecErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d invalid listener specs: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// checkAddrFamily returns *AddrFamilyError if the host of addr is an IP
+// literal which cannot be bound with network. Host names are resolved
+// later for the family of network, so they aren't checked.
+func checkAddrFamily(network, addr string) error {
+	if network != "tcp4" && network != "tcp6" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	if i := strings.LastIndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || (ip.To4() != nil) == (network == "tcp4") {
+		return nil
+	}
+	return &AddrFamilyError{
+		Network: network,
+		Addr:    addr,
+	}
+}
+
+// checkSpecs checks the network and the address family of every spec
+// and returns SpecErrors listing all the invalid ones.
+func checkSpecs(specs []ListenSpec) error {
+	var errs SpecErrors
+	for _, s := range specs {
+		var err error
+		switch s.Network {
+		case "tcp", "tcp4", "tcp6":
+			err = checkAddrFamily(s.Network, s.Addr)
+		default:
+			err = fmt.Errorf("cannot listen on %q: unsupported network %q", s.Addr, s.Network)
+		}
+		if err == nil {
+			if _, _, err = net.SplitHostPort(s.Addr); err != nil {
+				err = fmt.Errorf("cannot listen on %q: %s", s.Addr, err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// NewListenerGroup returns a listener for every spec, e.g. for serving
+// the same handler on a few addresses.
+//
+// All the specs are checked before creating any listener, and SpecErrors
+// listing every invalid spec is returned if some of them are invalid.
+// All the listeners are closed if any of them cannot be created.
+func NewListenerGroup(specs []ListenSpec, cfg Config) ([]net.Listener, error) {
+	if err := checkSpecs(specs); err != nil {
+		return nil, err
+	}
+	lns := make([]net.Listener, 0, len(specs))
+	for _, s := range specs {
+		ln, err := NewListener(s.Network, s.Addr, cfg)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
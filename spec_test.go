@@ -0,0 +1,53 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestNewListenerGroup(t *testing.T) {
+	lns, err := NewListenerGroup([]ListenSpec{
+		{Network: "tcp4", Addr: "127.0.0.1:0"},
+		{Network: "tcp6", Addr: "[::1]:0"},
+	}, Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener group: %s", err)
+	}
+	for _, ln := range lns {
+		ln.Close()
+	}
+
+	_, err = NewListenerGroup([]ListenSpec{
+		{Network: "tcp4", Addr: "[::1]:0"},
+		{Network: "tcp", Addr: "127.0.0.1:0"},
+		{Network: "tcp6", Addr: "127.0.0.1:0"},
+		{Network: "udp", Addr: ":0"},
+	}, Config{})
+	errs, ok := err.(SpecErrors)
+	if !ok {
+		t.Fatalf("unexpected error %v. Expecting SpecErrors", err)
+	}
+	if len(errs) != 3 {
+		t.Fatalf("unexpected number of errors %d. Expecting 3: %s", len(errs), err)
+	}
+	for i, network := range []string{"tcp4", "tcp6"} {
+		fe, ok := errs[i].(*AddrFamilyError)
+		if !ok {
+			t.Fatalf("unexpected error %v. Expecting *AddrFamilyError", errs[i])
+		}
+		if fe.Network != network {
+			t.Fatalf("unexpected network %q. Expecting %q", fe.Network, network)
+		}
+	}
+}
+
+func TestNewListenerAddrFamily(t *testing.T) {
+	_, err := NewListener("tcp4", "[::1]:0", Config{})
+	if _, ok := err.(*AddrFamilyError); !ok {
+		t.Fatalf("unexpected error %v. Expecting *AddrFamilyError", err)
+	}
+	if _, _, err = NewShardGroup("tcp6", "127.0.0.1:0", 2, Config{}); err == nil {
+		t.Fatalf("expecting error for IPv4 address with tcp6")
+	}
+}
@@ -0,0 +1,138 @@
+package tcplisten
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultSpliceBufferSize is the default size of the buffer used by Splice
+// for each direction.
+const DefaultSpliceBufferSize = 64 * 1024
+
+// SpliceOption changes the behavior of Splice.
+type SpliceOption func(*spliceConfig)
+
+type spliceConfig struct {
+	bufferSize int
+}
+
+// SpliceBufferSize sets the size of the buffer used for each direction.
+//
+// On Linux it is the capacity of the pipe the data is spliced through,
+// which is rounded up by the kernel to a power of two pages and is limited
+// by fs.pipe-max-size. Elsewhere it is the size of the io.Copy buffer.
+func SpliceBufferSize(n int) SpliceOption {
+	return func(cfg *spliceConfig) {
+		cfg.bufferSize = n
+	}
+}
+
+// Splice copies data between a and b in both directions until both reach
+// EOF, an error occurs or ctx is done. It returns the number of bytes
+// copied from a to b and from b to a.
+//
+// On Linux the data is moved with splice(2) through a pipe without copying
+// it to the user space if both connections expose their file descriptors
+// with SyscallConn. Otherwise, e.g. for TLS connections, io.Copy is used.
+//
+// When one side reaches EOF, the write side of the other one is shut down
+// if it has CloseWrite method, so the half-close is propagated to the peer.
+//
+// On error or when ctx is done, the deadlines of a and b are set to the past
+// in order to stop copying, and are cleared before Splice returns. ctx.Err()
+// is returned in the latter case. Splice doesn't close a and b.
+func Splice(ctx context.Context, a, b net.Conn, opts ...SpliceOption) (abBytes, baBytes int64, err error) {
+	cfg := spliceConfig{
+		bufferSize: DefaultSpliceBufferSize,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.bufferSize <= 0 {
+		cfg.bufferSize = DefaultSpliceBufferSize
+	}
+
+	s := &splicer{
+		a:    a,
+		b:    b,
+		done: make(chan struct{}),
+	}
+	go s.watch(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		abBytes = s.copy(b, a, &cfg)
+	}()
+	go func() {
+		defer wg.Done()
+		baBytes = s.copy(a, b, &cfg)
+	}()
+	wg.Wait()
+	close(s.done)
+
+	s.mu.Lock()
+	err = s.err
+	stopped := s.stopped
+	s.mu.Unlock()
+	if stopped {
+		a.SetDeadline(time.Time{})
+		b.SetDeadline(time.Time{})
+	}
+	return abBytes, baBytes, err
+}
+
+// splicer stops both directions of Splice on the first error.
+type splicer struct {
+	a, b net.Conn
+	done chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	stopped bool
+}
+
+func (s *splicer) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		s.stop(ctx.Err())
+	case <-s.done:
+	}
+}
+
+// stop records err if it is the first one and interrupts the pending
+// reads and writes. The errors caused by the interruption are ignored.
+func (s *splicer) stop(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	s.err = err
+	past := time.Unix(1, 0)
+	s.a.SetDeadline(past)
+	s.b.SetDeadline(past)
+}
+
+// copy copies src to dst and shuts down the write side of dst on EOF.
+func (s *splicer) copy(dst, src net.Conn, cfg *spliceConfig) int64 {
+	n, ok, err := spliceConn(dst, src, cfg)
+	if !ok {
+		n, err = io.CopyBuffer(dst, src, make([]byte, cfg.bufferSize))
+	}
+	if err != nil {
+		s.stop(err)
+		return n
+	}
+	if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+		if err = cw.CloseWrite(); err != nil {
+			s.stop(err)
+		}
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+)
+
+const (
+	spliceFMove     = 0x1
+	spliceFNonblock = 0x2
+	fSetPipeSz      = 0x407
+)
+
+// spliceConn moves data from src to dst with splice(2) through a pipe
+// until EOF. It returns false if either connection doesn't expose its
+// file descriptor.
+func spliceConn(dst, src net.Conn, cfg *spliceConfig) (int64, bool, error) {
+	dstRC, err := rawConn(dst)
+	if err != nil {
+		return 0, false, nil
+	}
+	srcRC, err := rawConn(src)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	var p [2]int
+	if err = syscall.Pipe2(p[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		return 0, false, nil
+	}
+	defer syscall.Close(p[0])
+	defer syscall.Close(p[1])
+	// The default pipe capacity is kept if the size exceeds
+	// fs.pipe-max-size.
+	syscall.Syscall(syscall.SYS_FCNTL, uintptr(p[1]), fSetPipeSz, uintptr(cfg.bufferSize))
+
+	var written int64
+	for {
+		var (
+			n    int
+			serr error
+		)
+		if err = srcRC.Read(func(fd uintptr) bool {
+			n, serr = splice(int(fd), p[1], cfg.bufferSize)
+			return serr != syscall.EAGAIN
+		}); err != nil {
+			return written, true, err
+		}
+		if serr == syscall.EINVAL && written == 0 {
+			// src doesn't support splice, e.g. it is a unix socket.
+			return 0, false, nil
+		}
+		if serr != nil {
+			return written, true, fmt.Errorf("cannot splice from %s: %s", src.RemoteAddr(), serr)
+		}
+		if n == 0 {
+			return written, true, nil
+		}
+
+		for pending := n; pending > 0; {
+			if err = dstRC.Write(func(fd uintptr) bool {
+				n, serr = splice(p[0], int(fd), pending)
+				return serr != syscall.EAGAIN
+			}); err != nil {
+				return written, true, err
+			}
+			if serr != nil {
+				return written, true, fmt.Errorf("cannot splice to %s: %s", dst.RemoteAddr(), serr)
+			}
+			if n == 0 {
+				return written, true, io.ErrShortWrite
+			}
+			pending -= n
+			written += int64(n)
+		}
+	}
+}
+
+// splice moves up to n bytes from rfd to wfd without blocking.
+func splice(rfd, wfd, n int) (int, error) {
+	m, err := syscall.Splice(rfd, nil, wfd, nil, n, spliceFMove|spliceFNonblock)
+	return int(m), err
+}
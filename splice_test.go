@@ -0,0 +1,159 @@
+package tcplisten
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestConnPair returns the client and the server sides of a TCP connection.
+func newTestConnPair(t *testing.T) (net.Conn, net.Conn) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	sc, err := ln.Accept()
+	if err != nil {
+		c.Close()
+		t.Fatalf("cannot accept: %s", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		sc.Close()
+	})
+	return c, sc
+}
+
+// opaqueConn hides the file descriptor of the connection.
+type opaqueConn struct {
+	net.Conn
+}
+
+func (c opaqueConn) CloseWrite() error {
+	return c.Conn.(*net.TCPConn).CloseWrite()
+}
+
+func TestSplice(t *testing.T) {
+	testSplice(t, func(c net.Conn) net.Conn { return c })
+}
+
+func TestSpliceFallback(t *testing.T) {
+	testSplice(t, func(c net.Conn) net.Conn { return opaqueConn{c} })
+}
+
+// singleConnListener returns c from Accept.
+type singleConnListener struct {
+	net.Listener
+	c net.Conn
+}
+
+func (ln *singleConnListener) Accept() (net.Conn, error) {
+	return ln.c, nil
+}
+
+func TestSpliceTracked(t *testing.T) {
+	testSplice(t, func(c net.Conn) net.Conn {
+		ln := TrackConns(&singleConnListener{c: c})
+		tc, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept: %s", err)
+		}
+		// The half-close must be propagated through the wrapper.
+		if _, ok := tc.(interface{ CloseWrite() error }); !ok {
+			t.Fatalf("the tracked connection %T has no CloseWrite", tc)
+		}
+		return tc
+	})
+}
+
+func testSplice(t *testing.T, wrap func(net.Conn) net.Conn) {
+	client, a := newTestConnPair(t)
+	b, upstream := newTestConnPair(t)
+
+	type result struct {
+		ab, ba int64
+		err    error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		var r result
+		r.ab, r.ba, r.err = Splice(context.Background(), wrap(a), wrap(b), SpliceBufferSize(4096))
+		ch <- r
+	}()
+
+	req := make([]byte, 100000)
+	for i := range req {
+		req[i] = byte(i)
+	}
+	go func() {
+		client.Write(req)
+		client.(*net.TCPConn).CloseWrite()
+	}()
+	data, err := ioutil.ReadAll(upstream)
+	if err != nil {
+		t.Fatalf("cannot read request: %s", err)
+	}
+	if string(data) != string(req) {
+		t.Fatalf("unexpected request of %d bytes. Expecting %d bytes", len(data), len(req))
+	}
+
+	// The client still reads after its half-close.
+	if _, err = upstream.Write([]byte("response")); err != nil {
+		t.Fatalf("cannot write response: %s", err)
+	}
+	upstream.(*net.TCPConn).CloseWrite()
+	data, err = ioutil.ReadAll(client)
+	if err != nil {
+		t.Fatalf("cannot read response: %s", err)
+	}
+	if string(data) != "response" {
+		t.Fatalf("unexpected response %q. Expecting %q", data, "response")
+	}
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			t.Fatalf("unexpected error: %s", r.err)
+		}
+		if r.ab != int64(len(req)) || r.ba != int64(len("response")) {
+			t.Fatalf("unexpected byte counts %d, %d. Expecting %d, %d", r.ab, r.ba, len(req), len("response"))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Splice hasn't returned after both sides reached EOF")
+	}
+}
+
+func TestSpliceCancel(t *testing.T) {
+	_, a := newTestConnPair(t)
+	b, _ := newTestConnPair(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan error, 1)
+	go func() {
+		_, _, err := Splice(ctx, a, b)
+		ch <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-ch:
+		if err != context.Canceled {
+			t.Fatalf("unexpected error %v. Expecting %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Splice hasn't returned after cancellation")
+	}
+
+	// The deadlines are cleared, so the connections are usable.
+	if _, err := a.Write([]byte("x")); err != nil {
+		t.Fatalf("cannot write after cancellation: %s", err)
+	}
+}
@@ -0,0 +1,17 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// getTCPInfo reads TCP_INFO for the given socket into ti.
+//
+// ti is passed by the caller, so it may stay on the stack
+// on hot paths, e.g. for every accepted connection.
+func getTCPInfo(fd int, ti *syscall.TCPInfo) error {
+	l := uint32(syscall.SizeofTCPInfo)
+	return getsockopt(fd, syscall.IPPROTO_TCP, syscall.TCP_INFO, unsafe.Pointer(ti), &l)
+}
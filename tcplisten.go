@@ -1,4 +1,5 @@
 // +build linux darwin dragonfly freebsd netbsd openbsd rumprun !windows
+// +build !plan9
 
 // Package tcplisten provides customizable TCP net.Listener with various
 // performance-related options:
@@ -24,142 +25,626 @@
 package tcplisten
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"os"
+	"runtime"
+	"strconv"
 	"syscall"
 )
 
-// Config provides options to enable on the returned listener.
-type Config struct {
-	// ReusePort enables SO_REUSEPORT.
-	ReusePort bool
+// NewListener returns TCP listener with options set in the Config.
+//
+// The function may be called many times for creating distinct listeners
+// with the given config.
+//
+// The tcp4, tcp6 and unix networks are supported. The address
+// of the unix network is the path of the socket file, or a name
+// in the abstract namespace starting with @ on Linux. The TCP options
+// cannot be set for unix sockets.
+func NewListener(network, addr string, cfg Config) (net.Listener, error) {
+	return NewListenerContext(context.Background(), network, addr, cfg)
+}
 
-	// DeferAccept enables TCP_DEFER_ACCEPT.
-	DeferAccept bool
+// NewListenerContext works like NewListener, but aborts resolving the host
+// name in addr and setting up the socket when ctx is done. The context
+// error is returned then, and the socket is closed.
+//
+// Cancelling ctx after NewListenerContext returns has no effect
+// on the listener.
+func NewListenerContext(ctx context.Context, network, addr string, cfg Config) (net.Listener, error) {
+	res, err := newListenerResult(ctx, network, addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return res.Listener, nil
+}
 
-	// FastOpen enables TCP_FASTOPEN.
-	FastOpen bool
+// NewListenerResult works like NewListener, but also reports what has
+// actually been done for creating the listener.
+func NewListenerResult(network, addr string, cfg Config) (*ListenResult, error) {
+	return newListenerResult(context.Background(), network, addr, cfg)
+}
 
-	// NoDelay enables TCP_NODELAY.
-	NoDelay bool
+func newListenerResult(ctx context.Context, network, addr string, cfg Config) (*ListenResult, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	sa, soType, err := listenSockaddr(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if soType == syscall.AF_UNIX {
+		err = cfg.checkUnix(addr)
+	} else {
+		err = cfg.checkLoopback(sockaddrIP(sa), addr)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// QuickACK enables TCP_QUICKACK.
-	QuickACK bool
+	var lock *os.File
+	if cfg.SingletonLock != "" {
+		if lock, err = acquireLock(cfg.SingletonLock); err != nil {
+			return nil, err
+		}
+	}
 
-	// Backlog is the maximum number of pending TCP connections the listener
-	// may queue before passing them to Accept.
-	// See man 2 listen for details.
-	//
-	// By default system-level backlog value is used.
-	Backlog int
+	var tuning *autoTuning
+	if cfg.AutoTune {
+		tuning = cfg.applyAutoTune(readMachineProfile(&cfg))
+	}
+
+	res, err := newListener(ctx, network, addr, sa, soType, &cfg)
+	if err == nil && tuning != nil {
+		res.Tuning = tuning.decisions
+		if err = tuning.setBuffers(res, tracer(cfg.Trace)); err != nil {
+			res.Close()
+		}
+	}
+	if err != nil {
+		if lock != nil {
+			lock.Close()
+		}
+		return nil, err
+	}
+
+	res.Listener = cfg.wrapListener(res.Listener)
+	if lock != nil {
+		res.Listener = &lockedListener{
+			Listener: res.Listener,
+			lock:     lock,
+		}
+	}
+	if cfg.Register {
+		register(network, &cfg, res)
+	}
+	return res, nil
 }
 
-// NewListener returns TCP listener with options set in the Config.
+// ApplyConfig applies the options from cfg to the listener created
+// elsewhere, e.g. the one inherited via socket activation.
 //
-// The function may be called many times for creating distinct listeners
-// with the given config.
+// Only the options which may be changed on a listening socket are applied,
+// i.e. DeferAccept, FastOpen, NoDelay, QuickACK, InitialRTO,
+// MaxPacingRate and HardwareTimestamping.
+// ReusePort, ReusePortLB, V6Only, FlowLabel, ServiceClass, Transparent,
+// KeepAlive, DisableRecvAutotune, ReceiveBufferSize, SendBufferSize,
+// Backlog, Control, PostListen and SingletonLock are ignored.
+func ApplyConfig(ln net.Listener, cfg Config) error {
+	return withFd(ln, func(fd uintptr) error {
+		return cfg.setOptions(int(fd), tracer(cfg.Trace), &ListenResult{})
+	})
+}
+
+// Explain describes what NewListener would do for creating the listener
+// on addr with cfg, one step per line, without binding anything.
 //
-// Only tcp4 and tcp6 networks are supported.
-func NewListener(network, addr string, cfg Config) (net.Listener, error) {
-	sa, soType, err := getSockaddr(network, addr)
+// Only IP literals are accepted in addr, since the host may resolve
+// to different addresses by the time the listener is created.
+//
+// The options are applied to a temporary unbound socket in the same order
+// as NewListener applies them, so the plan shows the options which would
+// be skipped or rejected on the current platform. If NewListener would fail,
+// the plan ends with the failed step and the error is returned together
+// with the plan.
+func (cfg Config) Explain(network, addr string) (string, error) {
+	if network != "unix" {
+		if err := checkLiteralAddr(addr); err != nil {
+			return "", err
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return "", err
+	}
+	sa, soType, err := listenSockaddr(context.Background(), network, addr)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	ip := sockaddrIP(sa)
+	if soType == syscall.AF_UNIX {
+		err = cfg.checkUnix(addr)
+	} else {
+		err = cfg.checkLoopback(ip, addr)
 	}
+	if err != nil {
+		return "", err
+	}
+
+	var e explainer
+	if cfg.SingletonLock != "" {
+		e.addf("flock(%s)", cfg.SingletonLock)
+	}
+	family, proto := "AF_INET", "IPPROTO_TCP"
+	switch soType {
+	case syscall.AF_INET6:
+		family = "AF_INET6"
+	case syscall.AF_UNIX:
+		family, proto = "AF_UNIX", "0"
+	}
+	e.addf("socket(%s, SOCK_STREAM, %s)", family, proto)
+	fd, err := newSocketCloexec(soType, syscall.SOCK_STREAM, socketProto(soType))
+	if err != nil {
+		return e.fail(err)
+	}
+	defer syscall.Close(fd)
+
+	cfg.Trace = e.trace
+	res := &ListenResult{onSkip: e.skip}
+	if err = cfg.setupOptions(fd, sa, addr, tracer(cfg.Trace), res); err != nil {
+		return e.fail(err)
+	}
+
+	if cfg.Control != nil {
+		e.addf("Control(%s, %s, fd)", sockaddrNetwork(sa), addr)
+	}
+	if soType == syscall.AF_UNIX {
+		if path := socketPath(sa); path != "" && cfg.UnlinkBeforeBind {
+			e.addf("unlink(%s) if it is a socket refusing connections", path)
+		}
+		e.addf("bind(%s)", addr)
+		if path := socketPath(sa); path != "" {
+			if cfg.UnlinkStale {
+				e.addf("on EADDRINUSE: unlink(%s) if it is a socket refusing connections, then bind(%s)", path, addr)
+			}
+			if cfg.UnixMode != 0 {
+				e.addf("chmod(%s, %#o)", path, cfg.UnixMode.Perm())
+			}
+			if o := cfg.UnixOwner; o != nil {
+				e.addf("chown(%s, %d, %d)", path, o.UID, o.GID)
+			}
+		}
+	} else {
+		_, port, _ := net.SplitHostPort(addr)
+		e.addf("bind(%s)", net.JoinHostPort(ip.String(), port))
+	}
+	backlog, err := cfg.listenBacklog()
+	if err != nil {
+		return e.fail(err)
+	}
+	if cfg.Backlog > 0 {
+		e.addf("listen(%d)", backlog)
+	} else {
+		e.addf("listen(%d) with the system-wide maximum backlog", backlog)
+	}
+	if cfg.PostListen != nil {
+		e.addf("PostListen(fd)")
+	}
+	return e.String(), nil
+}
 
-	fd, err := newSocketCloexec(soType, syscall.SOCK_STREAM, syscall.IPPROTO_TCP)
+// fileNamePrefix is the prefix of the names of listener files.
+// It is computed once, since fmt.Sprintf is relatively expensive
+// for services creating thousands of listeners.
+var fileNamePrefix = "reuseport." + strconv.Itoa(os.Getpid()) + "."
+
+func newListener(ctx context.Context, network, addr string, sa syscall.Sockaddr, soType int, cfg *Config) (*ListenResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fd, err := newSocketCloexec(soType, syscall.SOCK_STREAM, socketProto(soType))
 	if err != nil {
 		return nil, err
 	}
 
-	if err = cfg.fdSetup(fd, sa, addr); err != nil {
+	res := &ListenResult{
+		// Avoid growing the slice for every applied option.
+		AppliedOptions: make([]string, 0, 8),
+	}
+	if err = cfg.fdSetup(fd, sa, addr, res); err == nil {
+		// The context may be cancelled while the options are applied.
+		err = ctx.Err()
+	}
+	if err != nil {
 		syscall.Close(fd)
 		return nil, err
 	}
 
-	name := fmt.Sprintf("reuseport.%d.%s.%s", os.Getpid(), network, addr)
+	name := fileNamePrefix + network + "." + addr
 	file := os.NewFile(uintptr(fd), name)
 	ln, err := net.FileListener(file)
 	if err != nil {
 		file.Close()
+		removeSocketFile(sa)
 		return nil, err
 	}
 
 	if err = file.Close(); err != nil {
 		ln.Close()
+		removeSocketFile(sa)
 		return nil, err
 	}
+	if ul, ok := ln.(*net.UnixListener); ok {
+		// Remove the socket file on Close as net.Listen does.
+		ul.SetUnlinkOnClose(socketPath(sa) != "")
+	}
+
+	res.Listener = ln
+	res.BoundAddr, _ = ln.Addr().(*net.TCPAddr)
+
+	if cfg.LogInspectHint && res.BoundAddr != nil {
+		loggerOrDefault(cfg.Logger).Printf("tcplisten: inspect the listener on %s with `%s`", res.BoundAddr, inspectCommand(res.BoundAddr.Port))
+	}
+	if cfg.DeferAccept && !cfg.DeferUntilData && !res.hasOption(OptionDeferAccept.String()) {
+		loggerOrDefault(cfg.Logger).Printf("tcplisten: DeferAccept isn't supported on %s, enable DeferUntilData for the listener on %s", runtime.GOOS, ln.Addr())
+	}
 
-	return ln, nil
+	return res, nil
 }
 
-func (cfg *Config) fdSetup(fd int, sa syscall.Sockaddr, addr string) error {
-	var err error
+func (cfg *Config) fdSetup(fd int, sa syscall.Sockaddr, addr string, res *ListenResult) (err error) {
+	tr := tracer(cfg.Trace)
+	if err = cfg.setupOptions(fd, sa, addr, tr, res); err != nil {
+		return err
+	}
 
-	if err = syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
-		return fmt.Errorf("cannot enable SO_REUSEADDR: %s", err)
+	if cfg.Control != nil {
+		if err = cfg.Control(sockaddrNetwork(sa), addr, uintptr(fd)); err != nil {
+			return fmt.Errorf("cannot run Control hook on %q: %w", addr, err)
+		}
+	}
+
+	path := socketPath(sa)
+	if path != "" && cfg.UnlinkBeforeBind {
+		if _, err = unlinkStaleSocket(path, tr); err != nil {
+			return err
+		}
+	}
+	err = syscall.Bind(fd, sa)
+	tr.trace(TraceRecord{Call: "bind", Addr: addr, Err: err})
+	if err == syscall.EADDRINUSE && path != "" && cfg.UnlinkStale {
+		var removed bool
+		if removed, err = unlinkStaleSocket(path, tr); err != nil {
+			return err
+		}
+		err = syscall.EADDRINUSE
+		if removed {
+			err = syscall.Bind(fd, sa)
+			tr.trace(TraceRecord{Call: "bind", Addr: addr, Err: err})
+		}
+	}
+	if err == syscall.EACCES {
+		if port := sockaddrPort(sa); isPrivilegedPort(port) {
+			return &PrivilegedPortError{Addr: addr, Port: port, Err: err}
+		}
+	}
+	if err == syscall.EADDRINUSE && path != "" {
+		if cfg.UnlinkBeforeBind || cfg.UnlinkStale {
+			return fmt.Errorf("cannot bind to %q: %s; the socket file is served by a live listener", addr, err)
+		}
+		return fmt.Errorf("cannot bind to %q: %s; enable UnlinkStale or UnlinkBeforeBind for removing the stale socket file", addr, err)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot bind to %q: %s", addr, err)
+	}
+	if path != "" {
+		defer func() {
+			if err != nil {
+				removeSocketFile(sa)
+			}
+		}()
+		// Clients cannot connect before listen, so they never see
+		// the socket with the default mode.
+		if err = cfg.setSocketFileMode(path, tr); err != nil {
+			return err
+		}
+	}
+
+	backlog, err := cfg.listenBacklog()
+	if err != nil {
+		return err
+	}
+	err = syscall.Listen(fd, backlog)
+	tr.trace(TraceRecord{Call: "listen", Value: backlog, Err: err})
+	if err != nil {
+		return fmt.Errorf("cannot listen on %q: %s", addr, err)
+	}
+	res.Backlog = backlog
+
+	if cfg.PostListen != nil {
+		if err = cfg.PostListen(uintptr(fd)); err != nil {
+			return fmt.Errorf("cannot run PostListen hook on %q: %w", addr, err)
+		}
+	}
+
+	return nil
+}
+
+// listenBacklog returns the backlog to pass to listen(2).
+func (cfg *Config) listenBacklog() (int, error) {
+	if cfg.Backlog > 0 {
+		return cfg.Backlog, nil
+	}
+	backlog, err := soMaxConn()
+	if err != nil {
+		return 0, fmt.Errorf("cannot determine backlog to pass to listen(2): %s", err)
+	}
+	return backlog, nil
+}
+
+// setupOptions sets the options which must be set before bind.
+func (cfg *Config) setupOptions(fd int, sa syscall.Sockaddr, addr string, tr tracer, res *ListenResult) error {
+	_, isV6 := sa.(*syscall.SockaddrInet6)
+	_, isUnix := sa.(*syscall.SockaddrUnix)
+	steps := make([]optionStep, 0, 8)
+
+	if !cfg.DisableReuseAddr && !isUnix {
+		steps = append(steps, optionStep{OptionReuseAddr.String(), func() error {
+			if err := tr.setOption(fd, OptionReuseAddr, 1); err != nil {
+				return fmt.Errorf("cannot enable SO_REUSEADDR: %s", err)
+			}
+			res.applied(OptionReuseAddr.String())
+			return nil
+		}})
 	}
 
 	// This should disable Nagle's algorithm in all accepted sockets by default.
 	// Users may enable it with net.TCPConn.SetNoDelay(false).
-	if err = syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_NODELAY, 1); err != nil {
-		return fmt.Errorf("cannot disable Nagle's algorithm: %s", err)
+	if !isUnix {
+		steps = append(steps, optionStep{OptionNoDelay.String(), func() error {
+			if err := tr.setOption(fd, OptionNoDelay, 1); err != nil {
+				return fmt.Errorf("cannot disable Nagle's algorithm: %s", err)
+			}
+			res.applied(OptionNoDelay.String())
+			return nil
+		}})
 	}
 
 	if cfg.ReusePort {
-		if err = syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, soReusePort, 1); err != nil {
-			return fmt.Errorf("cannot enable SO_REUSEPORT: %s", err)
-		}
+		steps = append(steps, optionStep{OptionReusePort.String(), func() error {
+			if cfg.ExclusiveReusePortGroup {
+				if err := cfg.checkReusePortGroup(sa, addr, tr); err != nil {
+					return err
+				}
+			}
+			if err := tr.setOption(fd, OptionReusePort, 1); err != nil {
+				return fmt.Errorf("cannot enable SO_REUSEPORT: %s", err)
+			}
+			res.applied(OptionReusePort.String())
+			return nil
+		}})
+	}
+
+	if cfg.ReusePortLB {
+		steps = append(steps, optionStep{OptionReusePortLB.String(), func() error {
+			if err := enableReusePortLB(fd, tr); err != nil {
+				return err
+			}
+			res.applied(OptionReusePortLB.String())
+			return nil
+		}})
+	}
+
+	if v, ok := cfg.V6Only.sockoptValue(); ok && isV6 {
+		steps = append(steps, optionStep{OptionV6Only.String(), func() error {
+			if err := tr.setOption(fd, OptionV6Only, v); err != nil {
+				return fmt.Errorf("cannot set IPV6_V6ONLY: %s", err)
+			}
+			res.applied(OptionV6Only.String())
+			return nil
+		}})
+	}
+
+	if cfg.DisableRecvAutotune {
+		steps = append(steps, optionStep{"DisableRecvAutotune", func() error {
+			// SO_RCVBUF must be set before listen, since the window scale
+			// offered to clients is derived from it.
+			if err := pinRecvBuffer(fd, tr); err != nil {
+				return err
+			}
+			res.applied("SO_RCVBUF")
+			return nil
+		}})
+	}
+
+	if cfg.Transparent {
+		steps = append(steps, optionStep{"Transparent", func() error {
+			option, err := enableTransparent(fd, sa, tr)
+			if err != nil {
+				return err
+			}
+			res.applied(option)
+			return nil
+		}})
+	}
+
+	if cfg.ServiceClass != ServiceClassDefault {
+		steps = append(steps, optionStep{"ServiceClass", func() error {
+			return setServiceClass(fd, sa, cfg.ServiceClass, tr, res)
+		}})
+	}
+
+	if cfg.ReceiveBufferSize > 0 {
+		steps = append(steps, optionStep{"ReceiveBufferSize", func() error {
+			if err := tr.setsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, "SO_RCVBUF", cfg.ReceiveBufferSize); err != nil {
+				return fmt.Errorf("cannot set SO_RCVBUF to %d: %s", cfg.ReceiveBufferSize, err)
+			}
+			res.applied("SO_RCVBUF")
+			return nil
+		}})
+	}
+
+	if cfg.SendBufferSize > 0 {
+		steps = append(steps, optionStep{"SendBufferSize", func() error {
+			if err := tr.setsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_SNDBUF, "SO_SNDBUF", cfg.SendBufferSize); err != nil {
+				return fmt.Errorf("cannot set SO_SNDBUF to %d: %s", cfg.SendBufferSize, err)
+			}
+			res.applied("SO_SNDBUF")
+			return nil
+		}})
+	}
+
+	if cfg.FlowLabel != FlowLabelDefault {
+		steps = append(steps, optionStep{"FlowLabel", func() error {
+			if !isV6 {
+				return fmt.Errorf("cannot set FlowLabel on IPv4 listener %q", addr)
+			}
+			option, err := setFlowLabel(fd, cfg.FlowLabel, tr)
+			if err != nil {
+				return err
+			}
+			res.applied(option)
+			return nil
+		}})
+	}
+
+	if ka := cfg.listenerKeepAlive(); ka != nil {
+		steps = append(steps, optionStep{"KeepAlive", func() error {
+			if err := setKeepAlive(uintptr(fd), ka, tr); err != nil {
+				return err
+			}
+			res.applied("SO_KEEPALIVE")
+			return nil
+		}})
 	}
 
+	steps = cfg.appendOptionSteps(steps, fd, tr, res)
+	if err := cfg.applyOptionSteps(steps); err != nil {
+		return err
+	}
+
+	// Options may leave a pending error on the socket even if setsockopt
+	// has succeeded. Report it instead of failing in bind or accept.
+	return socketError(fd, tr)
+}
+
+// setOptions sets the options which may be changed after bind.
+func (cfg *Config) setOptions(fd int, tr tracer, res *ListenResult) error {
+	return cfg.applyOptionSteps(cfg.appendOptionSteps(nil, fd, tr, res))
+}
+
+// appendOptionSteps appends the steps setting the options which may
+// be changed after bind.
+func (cfg *Config) appendOptionSteps(steps []optionStep, fd int, tr tracer, res *ListenResult) []optionStep {
 	if cfg.DeferAccept {
-		if err = enableDeferAccept(fd); err != nil {
-			return err
-		}
+		steps = append(steps, optionStep{OptionDeferAccept.String(), func() error {
+			return res.record(OptionDeferAccept.String(), enableDeferAccept(fd, tr))
+		}})
 	}
 
 	if cfg.FastOpen {
-		if err = enableFastOpen(fd); err != nil {
-			return err
-		}
+		steps = append(steps, optionStep{OptionFastOpen.String(), func() error {
+			return res.record(OptionFastOpen.String(), enableFastOpen(fd, tr))
+		}})
 	}
 
 	if cfg.NoDelay {
-		if err = enableNoDelay(fd); err != nil {
-			return err
-		}
+		steps = append(steps, optionStep{OptionNoDelay.String(), func() error {
+			return res.record(OptionNoDelay.String(), enableNoDelay(fd, tr))
+		}})
 	}
 
 	if cfg.QuickACK {
-		if err = enableQuickAck(fd); err != nil {
-			return err
-		}
+		steps = append(steps, optionStep{OptionQuickACK.String(), func() error {
+			return res.record(OptionQuickACK.String(), enableQuickAck(fd, tr))
+		}})
 	}
 
-	if err = syscall.Bind(fd, sa); err != nil {
-		return fmt.Errorf("cannot bind to %q: %s", addr, err)
+	if cfg.InitialRTO > 0 {
+		steps = append(steps, optionStep{"InitialRTO", func() error {
+			return res.record("TCP_INITIAL_RTO", setInitialRTO(fd, cfg.InitialRTO))
+		}})
 	}
 
-	backlog := cfg.Backlog
-	if backlog <= 0 {
-		if backlog, err = soMaxConn(); err != nil {
-			return fmt.Errorf("cannot determine backlog to pass to listen(2): %s", err)
-		}
+	if cfg.MaxPacingRate > 0 {
+		steps = append(steps, optionStep{OptionMaxPacingRate.String(), func() error {
+			return res.record(OptionMaxPacingRate.String(), setMaxPacingRate(uintptr(fd), cfg.MaxPacingRate, tr))
+		}})
 	}
-	if err = syscall.Listen(fd, backlog); err != nil {
-		return fmt.Errorf("cannot listen on %q: %s", addr, err)
+
+	if cfg.HardwareTimestamping {
+		steps = append(steps, optionStep{OptionTimestamping.String(), func() error {
+			return res.record(OptionTimestamping.String(), enableTimestamping(fd, tr))
+		}})
+	}
+
+	return steps
+}
+
+// socketError returns the pending error of the socket if any.
+// Reading SO_ERROR clears the error.
+func socketError(fd int, tr tracer) error {
+	v, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_ERROR)
+	tr.trace(TraceRecord{
+		Call:   "getsockopt",
+		Level:  syscall.SOL_SOCKET,
+		Option: "SO_ERROR",
+		Value:  v,
+		Err:    err,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot obtain SO_ERROR: %s", err)
+	}
+	if v != 0 {
+		return fmt.Errorf("socket has pending error: %s", syscall.Errno(v))
 	}
+	return nil
+}
 
+func sockaddrIP(sa syscall.Sockaddr) net.IP {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return net.IP(sa.Addr[:])
+	case *syscall.SockaddrInet6:
+		return net.IP(sa.Addr[:])
+	}
 	return nil
 }
 
-func getSockaddr(network, addr string) (sa syscall.Sockaddr, soType int, err error) {
+// sockaddrNetwork returns the network of sa for Config.Control.
+func sockaddrNetwork(sa syscall.Sockaddr) string {
+	switch sa.(type) {
+	case *syscall.SockaddrInet4:
+		return "tcp4"
+	case *syscall.SockaddrInet6:
+		return "tcp6"
+	case *syscall.SockaddrUnix:
+		return "unix"
+	}
+	return ""
+}
+
+func sockaddrPort(sa syscall.Sockaddr) int {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return sa.Port
+	case *syscall.SockaddrInet6:
+		return sa.Port
+	}
+	return 0
+}
+
+func getSockaddr(ctx context.Context, network, addr string) (sa syscall.Sockaddr, soType int, err error) {
 	if network != "tcp" && network != "tcp4" && network != "tcp6" {
 		return nil, -1, errors.New("only tcp4 and tcp6 network is supported")
 	}
+	if err = checkAddrFamily(network, addr); err != nil {
+		return nil, -1, err
+	}
 
-	tcpAddr, err := net.ResolveTCPAddr(network, addr)
+	tcpAddr, err := resolveTCPAddr(ctx, network, addr)
 	if err != nil {
 		return nil, -1, err
 	}
@@ -175,11 +660,9 @@ func getSockaddr(network, addr string) (sa syscall.Sockaddr, soType int, err err
 		sa6.Port = tcpAddr.Port
 		copy(sa6.Addr[:], tcpAddr.IP.To16())
 		if tcpAddr.Zone != "" {
-			ifi, err := net.InterfaceByName(tcpAddr.Zone)
-			if err != nil {
+			if sa6.ZoneId, err = zoneIndex(tcpAddr.Zone); err != nil {
 				return nil, -1, err
 			}
-			sa6.ZoneId = uint32(ifi.Index)
 		}
 		return &sa6, syscall.AF_INET6, nil
 	default:
@@ -4,26 +4,49 @@
 package tcplisten
 
 import (
+	"fmt"
+	"runtime"
 	"syscall"
+	"time"
 )
 
 const soReusePort = syscall.SO_REUSEPORT
 
-func enableDeferAccept(fd int) error {
+var optionSockopts = map[Option]sockopt{
+	OptionNoDelay:   {syscall.IPPROTO_TCP, syscall.TCP_NODELAY},
+	OptionReuseAddr: {syscall.SOL_SOCKET, syscall.SO_REUSEADDR},
+	OptionReusePort: {syscall.SOL_SOCKET, soReusePort},
+	OptionV6Only:    {syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY},
+}
+
+func enableDeferAccept(fd int, tr tracer) error {
 	// TODO: implement SO_ACCEPTFILTER:dataready here
-	return nil
+	return errOptionSkipped
 }
 
-func enableFastOpen(fd int) error {
+func enableFastOpen(fd int, tr tracer) error {
 	// TODO: implement TCP_FASTOPEN when it will be ready
-	return nil
+	return errOptionSkipped
 }
-func enableNoDelay(fd int) error {
+func enableNoDelay(fd int, tr tracer) error {
+	// TCP_NODELAY is always enabled by fdSetup.
 	return nil
 }
 
-func enableQuickAck(fd int) error {
-	return nil
+func enableQuickAck(fd int, tr tracer) error {
+	return errOptionSkipped
+}
+
+func setInitialRTO(fd int, d time.Duration) error {
+	// The BSDs expose only system-wide sysctls for the initial RTO.
+	return fmt.Errorf("cannot set initial RTO: it is a system-wide setting: %w", ErrUnsupportedOption)
+}
+
+func inspectCommand(port int) string {
+	if runtime.GOOS == "freebsd" {
+		return fmt.Sprintf("sockstat -46 -l -p %d", port)
+	}
+	return fmt.Sprintf("lsof -nP -iTCP:%d -sTCP:LISTEN", port)
 }
 
 func soMaxConn() (int, error) {
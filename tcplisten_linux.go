@@ -4,11 +4,11 @@ package tcplisten
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 const (
@@ -16,38 +16,62 @@ const (
 	tcpFastOpen = 0x17
 )
 
-func enableDeferAccept(fd int) error {
-	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_DEFER_ACCEPT, 1); err != nil {
+var optionSockopts = map[Option]sockopt{
+	OptionDeferAccept:   {syscall.IPPROTO_TCP, syscall.TCP_DEFER_ACCEPT},
+	OptionFastOpen:      {syscall.IPPROTO_TCP, tcpFastOpen},
+	OptionNoDelay:       {syscall.IPPROTO_TCP, syscall.TCP_NODELAY},
+	OptionQuickACK:      {syscall.IPPROTO_TCP, syscall.TCP_QUICKACK},
+	OptionReuseAddr:     {syscall.SOL_SOCKET, syscall.SO_REUSEADDR},
+	OptionReusePort:     {syscall.SOL_SOCKET, soReusePort},
+	OptionV6Only:        {syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY},
+	OptionMaxPacingRate: {syscall.SOL_SOCKET, soMaxPacingRate},
+	OptionTimestamping:  {syscall.SOL_SOCKET, syscall.SO_TIMESTAMPING},
+}
+
+func enableDeferAccept(fd int, tr tracer) error {
+	if err := tr.setOption(fd, OptionDeferAccept, 1); err != nil {
 		return fmt.Errorf("cannot enable TCP_DEFER_ACCEPT: %s", err)
 	}
 	return nil
 }
 
-func enableFastOpen(fd int) error {
-	if err := syscall.SetsockoptInt(fd, syscall.SOL_TCP, tcpFastOpen, fastOpenQlen); err != nil {
+func enableFastOpen(fd int, tr tracer) error {
+	if err := tr.setOption(fd, OptionFastOpen, fastOpenQlen); err != nil {
 		return fmt.Errorf("cannot enable TCP_FASTOPEN(qlen=%d): %s", fastOpenQlen, err)
 	}
 	return nil
 }
 
-func enableNoDelay(fd int) error {
-	if err := syscall.SetsockoptInt(fd, syscall.SOL_TCP, syscall.TCP_NODELAY, 1); err != nil {
+func enableNoDelay(fd int, tr tracer) error {
+	if err := tr.setOption(fd, OptionNoDelay, 1); err != nil {
 		return fmt.Errorf("cannot enable TCP_NODELAY: %s", err)
 	}
 	return nil
 }
 
-func enableQuickAck(fd int) error {
-	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_QUICKACK, 1); err != nil {
+func enableQuickAck(fd int, tr tracer) error {
+	if err := tr.setOption(fd, OptionQuickACK, 1); err != nil {
 		return fmt.Errorf("cannot enable TCP_QUICKACK: %s", err)
 	}
 	return nil
 }
 
+func setInitialRTO(fd int, d time.Duration) error {
+	// The initial RTO is a route attribute on Linux.
+	return fmt.Errorf("cannot set initial RTO: it is configured per route with `ip route change ... rto_min`: %w", ErrUnsupportedOption)
+}
+
 const fastOpenQlen = 16 * 1024
 
+func inspectCommand(port int) string {
+	return fmt.Sprintf("ss -tlnpe 'sport = :%d'", port)
+}
+
 func soMaxConn() (int, error) {
-	data, err := ioutil.ReadFile(soMaxConnFilePath)
+	// The file is read into a stack buffer instead of ioutil.ReadFile,
+	// since soMaxConn is called for every listener.
+	var buf [32]byte
+	size, err := readSmallFile(soMaxConnFilePath, buf[:])
 	if err != nil {
 		// This error may trigger on travis build. Just use SOMAXCONN
 		if os.IsNotExist(err) {
@@ -55,7 +79,7 @@ func soMaxConn() (int, error) {
 		}
 		return -1, err
 	}
-	s := strings.TrimSpace(string(data))
+	s := strings.TrimSpace(string(buf[:size]))
 	n, err := strconv.Atoi(s)
 	if err != nil || n <= 0 {
 		return -1, fmt.Errorf("cannot parse somaxconn %q read from %s: %s", s, soMaxConnFilePath, err)
@@ -70,6 +94,20 @@ func soMaxConn() (int, error) {
 	return n, nil
 }
 
+// readSmallFile reads the file into buf and returns the number of bytes read.
+func readSmallFile(path string, buf []byte) (int, error) {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer syscall.Close(fd)
+	n, err := syscall.Read(fd, buf)
+	if err != nil {
+		return 0, &os.PathError{Op: "read", Path: path, Err: err}
+	}
+	return n, nil
+}
+
 func kernelVersion() (major int, minor int) {
 	var uname syscall.Utsname
 	if err := syscall.Uname(&uname); err != nil {
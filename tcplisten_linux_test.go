@@ -0,0 +1,172 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSocketError(t *testing.T) {
+	// Find a closed UDP port, so datagrams sent to it are refused
+	// with ICMP port unreachable delivered after send returns.
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create UDP socket: %s", err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	pc.Close()
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		t.Fatalf("cannot create socket: %s", err)
+	}
+	defer syscall.Close(fd)
+	if err = syscall.Connect(fd, &syscall.SockaddrInet4{Port: port, Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("cannot connect: %s", err)
+	}
+	if _, err = syscall.Write(fd, []byte("x")); err != nil {
+		t.Fatalf("cannot send datagram: %s", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		err = socketError(fd, nil)
+		if err != nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err == nil || !strings.Contains(err.Error(), syscall.ECONNREFUSED.Error()) {
+		t.Fatalf("unexpected error %v. Expecting pending %v", err, syscall.ECONNREFUSED)
+	}
+	if err = socketError(fd, nil); err != nil {
+		t.Fatalf("pending error hasn't been cleared: %s", err)
+	}
+}
+
+func TestListenerNonblocking(t *testing.T) {
+	const n = 100
+
+	threads := pprof.Lookup("threadcreate")
+	before := threads.Count()
+
+	var wg sync.WaitGroup
+	lns := make([]net.Listener, 0, n)
+	defer func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+		wg.Wait()
+	}()
+	for i := 0; i < n; i++ {
+		ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		lns = append(lns, ln)
+
+		err = withFd(ln, func(fd uintptr) error {
+			flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+			if errno != 0 {
+				return errno
+			}
+			if flags&syscall.O_NONBLOCK == 0 {
+				t.Errorf("listener fd %d is in blocking mode", fd)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("cannot obtain listener fd flags: %s", err)
+		}
+
+		// Blocking Accept would occupy an OS thread per listener.
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ln.Accept()
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if created := threads.Count() - before; created > n/4 {
+		t.Fatalf("%d OS threads have been created for %d listeners blocked in Accept", created, n)
+	}
+}
+
+func TestGetSockaddrZone(t *testing.T) {
+	testGetSockaddrZone(t, "[fe80::1%1]:80", 1)
+
+	ifi, err := net.InterfaceByName("eth0")
+	if err != nil {
+		t.Skipf("cannot find eth0: %s", err)
+	}
+	testGetSockaddrZone(t, "[fe80::1%eth0]:80", uint32(ifi.Index))
+}
+
+func testGetSockaddrZone(t *testing.T, addr string, zoneID uint32) {
+	sa, _, err := getSockaddr(context.Background(), "tcp6", addr)
+	if err != nil {
+		t.Fatalf("cannot resolve %q: %s", addr, err)
+	}
+	sa6, ok := sa.(*syscall.SockaddrInet6)
+	if !ok {
+		t.Fatalf("unexpected sockaddr %T for %q", sa, addr)
+	}
+	if sa6.ZoneId != zoneID {
+		t.Fatalf("unexpected zone id %d for %q. Expecting %d", sa6.ZoneId, addr, zoneID)
+	}
+}
+
+func TestNewListenerContext(t *testing.T) {
+	// Initialize the poller, so its fds aren't counted as leaked.
+	ln, err := NewListenerContext(context.Background(), "tcp4", "localhost:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	if !ln.Addr().(*net.TCPAddr).IP.IsLoopback() {
+		t.Fatalf("unexpected address %s. Expecting a loopback address", ln.Addr())
+	}
+	ln.Close()
+	fds := openFds(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, addr := range []string{"127.0.0.1:0", "localhost:0"} {
+		if _, err = NewListenerContext(ctx, "tcp4", addr, Config{}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected error %v for %q. Expecting %v", err, addr, context.Canceled)
+		}
+	}
+
+	// Cancel the context while the socket is set up.
+	ctx, cancel = context.WithCancel(context.Background())
+	_, err = NewListenerContext(ctx, "tcp4", "127.0.0.1:0", Config{
+		Trace: func(r TraceRecord) {
+			cancel()
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error %v. Expecting %v", err, context.Canceled)
+	}
+
+	if n := openFds(t); n != fds {
+		t.Fatalf("unexpected number of open fds %d. Expecting %d", n, fds)
+	}
+}
+
+// openFds returns the number of open fds of the process.
+func openFds(t *testing.T) int {
+	fis, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatalf("cannot read open fds: %s", err)
+	}
+	return len(fis)
+}
@@ -0,0 +1,192 @@
+// +build plan9
+
+package tcplisten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+)
+
+// NewListener returns TCP listener with options set in the Config.
+//
+// Plan 9 has no socket options, so NewListener returns an error wrapping
+// ErrUnsupportedOption if any socket-level option is set.
+//
+// Only tcp4 and tcp6 networks are supported.
+func NewListener(network, addr string, cfg Config) (net.Listener, error) {
+	return NewListenerContext(context.Background(), network, addr, cfg)
+}
+
+// NewListenerContext works like NewListener, but aborts resolving the host
+// name in addr and creating the listener when ctx is done.
+//
+// Cancelling ctx after NewListenerContext returns has no effect
+// on the listener.
+func NewListenerContext(ctx context.Context, network, addr string, cfg Config) (net.Listener, error) {
+	res, err := newListenerResult(ctx, network, addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return res.Listener, nil
+}
+
+// NewListenerResult works like NewListener, but also reports what has
+// actually been done for creating the listener.
+//
+// No options are applied on Plan 9, so AppliedOptions is always empty.
+func NewListenerResult(network, addr string, cfg Config) (*ListenResult, error) {
+	return newListenerResult(context.Background(), network, addr, cfg)
+}
+
+func newListenerResult(ctx context.Context, network, addr string, cfg Config) (*ListenResult, error) {
+	if err := cfg.checkSupported(); err != nil {
+		return nil, err
+	}
+	switch network {
+	case "tcp4", "tcp6":
+	default:
+		return nil, errors.New("only tcp4 and tcp6 network is supported")
+	}
+	laddr, err := cfg.resolveLoopback(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	res := &ListenResult{Listener: ln}
+	res.BoundAddr, _ = ln.Addr().(*net.TCPAddr)
+
+	if cfg.LogInspectHint && res.BoundAddr != nil {
+		loggerOrDefault(cfg.Logger).Printf("tcplisten: inspect the listener on %s with `%s`", res.BoundAddr, inspectCommand(res.BoundAddr.Port))
+	}
+	res.Listener = cfg.wrapListener(res.Listener)
+	if cfg.Register {
+		register(network, &cfg, res)
+	}
+	return res, nil
+}
+
+// ApplyConfig returns an error wrapping ErrUnsupportedOption if any
+// of the options applicable to an existing listener is set in cfg.
+//
+// The options which may be set only when creating the listener,
+// e.g. ReusePort, V6Only, Backlog or Control, are ignored the same way
+// as on the other platforms.
+func ApplyConfig(ln net.Listener, cfg Config) error {
+	return cfg.checkFields(plan9ApplyIgnoredFields)
+}
+
+// Explain describes what NewListener would do for creating the listener
+// on addr with cfg without binding anything.
+//
+// Only IP literals are accepted in addr. Plan 9 has no socket options,
+// so the plan consists of a single net.Listen call.
+func (cfg Config) Explain(network, addr string) (string, error) {
+	if err := checkLiteralAddr(addr); err != nil {
+		return "", err
+	}
+	var e explainer
+	if err := cfg.checkSupported(); err != nil {
+		return e.fail(err)
+	}
+	switch network {
+	case "tcp4", "tcp6":
+	default:
+		return "", errors.New("only tcp4 and tcp6 network is supported")
+	}
+	laddr, err := cfg.resolveLoopback(context.Background(), network, addr)
+	if err != nil {
+		return "", err
+	}
+	e.addf("net.Listen(%s, %s)", network, laddr)
+	return e.String(), nil
+}
+
+// plan9Fields are the Config fields supported on Plan 9, since they are
+// implemented without socket options.
+var plan9Fields = map[string]bool{
+	"DeferUntilData":    true,
+	"UnmapV4":           true,
+	"AcceptReadTimeout": true,
+	"OptionOrder":       true,
+	"Trace":             true,
+	"Logger":            true,
+	"LogInspectHint":    true,
+	"Register":          true,
+	"LoopbackOnly":      true,
+}
+
+// plan9ApplyIgnoredFields are the Config fields ignored by ApplyConfig.
+var plan9ApplyIgnoredFields = map[string]bool{
+	"ReusePort":               true,
+	"DisableReuseAddr":        true,
+	"UnlinkBeforeBind":        true,
+	"ExclusiveAddrUse":        true,
+	"ExclusiveReusePortGroup": true,
+	"AllowForeignReusePort":   true,
+	"ReusePortLB":             true,
+	"Backlog":                 true,
+	"ReceiveBufferSize":       true,
+	"SendBufferSize":          true,
+	"V6Only":                  true,
+	"FlowLabel":               true,
+	"Transparent":             true,
+	"ServiceClass":            true,
+	"DisableRecvAutotune":     true,
+	"KeepAliveConfig":         true,
+	"KeepAlive":               true,
+	"KeepAliveIdle":           true,
+	"KeepAliveInterval":       true,
+	"KeepAliveCount":          true,
+	"Control":                 true,
+	"PostListen":              true,
+	"SingletonLock":           true,
+	"AutoTune":                true,
+}
+
+func (cfg *Config) checkSupported() error {
+	return cfg.checkFields(nil)
+}
+
+// checkFields returns an error for the first set field of cfg, which
+// is neither supported on Plan 9 nor in skip.
+//
+// All the fields are checked, so the fields added to Config later
+// are reported as unsupported until they are added to plan9Fields.
+func (cfg *Config) checkFields(skip map[string]bool) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if plan9Fields[name] || skip[name] || !isFieldSet(v.Field(i)) {
+			continue
+		}
+		return fmt.Errorf("cannot enable %s: %w", name, ErrUnsupportedOption)
+	}
+	return nil
+}
+
+// isFieldSet reports whether the Config field is set. Non-positive
+// numbers, e.g. Backlog or InitialRTO, mean the default.
+func isFieldSet(f reflect.Value) bool {
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int() > 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return f.Uint() > 0
+	}
+	return !f.IsZero()
+}
+
+func inspectCommand(port int) string {
+	return fmt.Sprintf("netstat -n | grep '!%d '", port)
+}
+
+func soMaxConn() (int, error) {
+	return -1, ErrUnsupportedOption
+}
@@ -1,10 +1,12 @@
 package tcplisten
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -54,8 +56,11 @@ func testConfig(t *testing.T, cfg Config) {
 
 func testConfigV(t *testing.T, cfg Config, network, addr string) {
 	const requestsCount = 1000
+	if cfg.ReusePort && runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT doesn't exist on Windows")
+	}
 	var serversCount = 1
-	if cfg.ReusePort && runtime.GOOS != "windows" {
+	if cfg.ReusePort {
 		serversCount = 10
 	}
 	doneCh := make(chan struct{}, serversCount)
@@ -90,7 +95,7 @@ func testConfigV(t *testing.T, cfg Config, network, addr string) {
 		ch := make(chan struct{})
 		go func() {
 			if resp, err = ioutil.ReadAll(c); err != nil {
-				t.Fatalf("%d. unexpected error when reading response: %s", i, err)
+				t.Errorf("%d. unexpected error when reading response: %s", i, err)
 			}
 			close(ch)
 		}()
@@ -141,3 +146,176 @@ func serveEcho(t *testing.T, ln net.Listener) {
 		}
 	}
 }
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestConfigLogInspectHint(t *testing.T) {
+	var l testLogger
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{Logger: &l, LogInspectHint: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	if len(l.lines) != 1 {
+		t.Fatalf("unexpected log lines %q. Expecting a single line", l.lines)
+	}
+	port := fmt.Sprintf("%d", ln.Addr().(*net.TCPAddr).Port)
+	if !strings.Contains(l.lines[0], port) {
+		t.Fatalf("unexpected log line %q. Expecting it to mention port %s", l.lines[0], port)
+	}
+}
+
+func TestConfigPostListen(t *testing.T) {
+	var called uintptr
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{
+		PostListen: func(fd uintptr) error {
+			called = fd
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln.Close()
+	if called == 0 {
+		t.Fatalf("PostListen hasn't been called")
+	}
+
+	errHook := errors.New("hook error")
+	_, err = NewListener("tcp4", "127.0.0.1:0", Config{
+		PostListen: func(fd uintptr) error {
+			return errHook
+		},
+	})
+	if !errors.Is(err, errHook) {
+		t.Fatalf("unexpected error %v. Expecting %v", err, errHook)
+	}
+}
+
+func TestConfigInitialRTO(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{InitialRTO: 3 * time.Second})
+	if runtime.GOOS == "windows" {
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		ln.Close()
+		return
+	}
+	if !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrUnsupportedOption)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	var options []string
+	cfg := Config{
+		DeferAccept: true,
+		NoDelay:     true,
+		ReusePort:   true,
+		Backlog:     64,
+		Trace: func(r TraceRecord) {
+			if r.Err != nil {
+				t.Errorf("unexpected error in %+v", r)
+			}
+			options = append(options, r.Option)
+		},
+	}
+	err = ApplyConfig(ln, cfg)
+	if runtime.GOOS == "plan9" || runtime.GOOS == "windows" {
+		if !errors.Is(err, ErrUnsupportedOption) {
+			t.Fatalf("unexpected error %v. Expecting %v", err, ErrUnsupportedOption)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("cannot apply config: %s", err)
+	}
+	if runtime.GOOS != "linux" {
+		return
+	}
+	if strings.Join(options, ",") != "TCP_DEFER_ACCEPT,TCP_NODELAY" {
+		t.Fatalf("unexpected options applied: %q. Expecting TCP_DEFER_ACCEPT,TCP_NODELAY", options)
+	}
+}
+
+func TestConfigReusePortLB(t *testing.T) {
+	if _, err := NewListener("tcp4", "127.0.0.1:0", Config{ReusePort: true, ReusePortLB: true}); err == nil {
+		t.Fatalf("expecting error when both ReusePort and ReusePortLB are enabled")
+	}
+
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{ReusePortLB: true})
+	if runtime.GOOS == "freebsd" {
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		ln.Close()
+		return
+	}
+	if !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrUnsupportedOption)
+	}
+}
+
+func TestConfigDisableReuseAddr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT doesn't exist on Windows")
+	}
+	for _, disable := range []bool{false, true} {
+		ln, err := NewListener("tcp4", "127.0.0.1:0", Config{ReusePort: true, DisableReuseAddr: disable})
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		reuseAddr, err := GetOption(ln, OptionReuseAddr)
+		if err != nil {
+			t.Fatalf("cannot obtain SO_REUSEADDR: %s", err)
+		}
+		reusePort, err := GetOption(ln, OptionReusePort)
+		if err != nil {
+			t.Fatalf("cannot obtain SO_REUSEPORT: %s", err)
+		}
+		ln.Close()
+		if (reuseAddr != 0) == disable || reusePort == 0 {
+			t.Fatalf("unexpected SO_REUSEADDR=%d, SO_REUSEPORT=%d with DisableReuseAddr=%v", reuseAddr, reusePort, disable)
+		}
+	}
+}
+
+func TestConfigLoopbackOnly(t *testing.T) {
+	cfg := Config{LoopbackOnly: true}
+	for _, addr := range []string{":0", "0.0.0.0:0"} {
+		if _, err := NewListener("tcp4", addr, cfg); err == nil {
+			t.Fatalf("expecting error when listening on %q", addr)
+		}
+	}
+
+	ln, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln.Close()
+}
+
+func BenchmarkNewListener(b *testing.B) {
+	cfg := Config{ReusePort: runtime.GOOS != "windows"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ln, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+		if err != nil {
+			b.Fatalf("cannot create listener: %s", err)
+		}
+		ln.Close()
+	}
+}
@@ -24,34 +24,493 @@
 package tcplisten
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
 )
 
-// Config provides options to enable on the returned listener.
-type Config struct {
-	// ReusePort enables SO_REUSEPORT.
-	ReusePort bool
+// NewListener returns TCP listener with options set in the Config.
+//
+// The function may be called many times for creating distinct listeners
+// with the given config.
+//
+// Only tcp4 and tcp6 networks are supported.
+func NewListener(network, addr string, cfg Config) (net.Listener, error) {
+	return NewListenerContext(context.Background(), network, addr, cfg)
+}
 
-	// DeferAccept enables TCP_DEFER_ACCEPT.
-	DeferAccept bool
+// NewListenerContext works like NewListener, but aborts resolving the host
+// name in addr and setting up the socket when ctx is done. The context
+// error is returned then, and the socket is closed.
+//
+// Cancelling ctx after NewListenerContext returns has no effect
+// on the listener.
+func NewListenerContext(ctx context.Context, network, addr string, cfg Config) (net.Listener, error) {
+	res, err := newListenerResult(ctx, network, addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return res.Listener, nil
+}
+
+// NewListenerResult works like NewListener, but also reports what has
+// actually been done for creating the listener.
+//
+// The socket is created, bound and put into the listening state with
+// Winsock calls, so the options and the backlog are honored. It requires
+// Go 1.25 or newer, since earlier versions of the standard library cannot
+// turn a socket into net.Listener on Windows. The listener is created
+// by the standard library with earlier versions, so Backlog is ignored
+// and ListenResult.Backlog is zero then.
+func NewListenerResult(network, addr string, cfg Config) (*ListenResult, error) {
+	return newListenerResult(context.Background(), network, addr, cfg)
+}
+
+func newListenerResult(ctx context.Context, network, addr string, cfg Config) (*ListenResult, error) {
+	if err := cfg.validateWindows(); err != nil {
+		return nil, err
+	}
+	laddr, err := cfg.resolveLoopback(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	sa, lnet, dualStack, err := windowsSockaddr(ctx, network, laddr)
+	if err != nil {
+		return nil, err
+	}
 
-	// FastOpen enables TCP_FASTOPEN.
-	FastOpen bool
+	res := &ListenResult{}
+	var ln net.Listener
+	if fileListenerSupported {
+		ln, err = cfg.listenWinsock(sa, lnet, laddr, dualStack, res)
+	} else {
+		ln, err = cfg.listenStd(ctx, network, laddr, res)
+	}
+	if err == nil {
+		// The context may be cancelled while the options are applied.
+		if err = ctx.Err(); err != nil {
+			ln.Close()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PostListen != nil {
+		if err = withFd(ln, cfg.PostListen); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("cannot run PostListen hook on %q: %w", addr, err)
+		}
+	}
+	res.Listener = ln
+	res.BoundAddr, _ = ln.Addr().(*net.TCPAddr)
 
-	// Backlog is the maximum number of pending TCP connections the listener
-	// may queue before passing them to Accept.
-	// See man 2 listen for details.
-	//
-	// By default system-level backlog value is used.
-	Backlog int
+	if cfg.LogInspectHint && res.BoundAddr != nil {
+		loggerOrDefault(cfg.Logger).Printf("tcplisten: inspect the listener on %s with `%s`", res.BoundAddr, inspectCommand(res.BoundAddr.Port))
+	}
+	res.Listener = cfg.wrapListener(res.Listener)
+	if cfg.Register {
+		register(network, &cfg, res)
+	}
+	return res, nil
 }
 
-// NewListener returns TCP listener with options set in the Config.
+// validateWindows validates cfg and rejects the options unsupported
+// on Windows, which aren't applied by fdSetup.
+func (cfg *Config) validateWindows() error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	if cfg.ReusePort {
+		// SO_REUSEADDR is the closest option on Windows, but it allows
+		// any socket to take over the port, so it isn't set silently.
+		return fmt.Errorf("cannot enable SO_REUSEPORT: it doesn't exist on Windows: %w", ErrUnsupportedOption)
+	}
+	if cfg.ReusePortLB {
+		return fmt.Errorf("cannot enable SO_REUSEPORT_LB: it exists only on FreeBSD: %w", ErrUnsupportedOption)
+	}
+	if cfg.SingletonLock != "" {
+		return fmt.Errorf("cannot acquire lock file %q: %w", cfg.SingletonLock, ErrUnsupportedOption)
+	}
+	return nil
+}
+
+// listenWinsock creates the listener with Winsock calls. It requires
+// fileListenerSupported.
 //
-// The function may be called many times for creating distinct listeners
-// with the given config.
+// IPV6_V6ONLY is disabled on the dualStack socket unless cfg.V6Only
+// is set, since Windows enables it by default.
+func (cfg *Config) listenWinsock(sa syscall.Sockaddr, network, addr string, dualStack bool, res *ListenResult) (net.Listener, error) {
+	family := syscall.AF_INET
+	if network == "tcp6" {
+		family = syscall.AF_INET6
+	}
+	fd, err := wsaSocket(family)
+	if err != nil {
+		return nil, err
+	}
+	tr := tracer(cfg.Trace)
+	if dualStack && cfg.V6Only == V6OnlyDefault {
+		if err = tr.setOption(fd, OptionV6Only, 0); err != nil {
+			syscall.Closesocket(fd)
+			return nil, fmt.Errorf("cannot disable IPV6_V6ONLY: %s", err)
+		}
+	}
+	if err = cfg.fdSetup(fd, network, res); err != nil {
+		syscall.Closesocket(fd)
+		return nil, err
+	}
+	if cfg.Control != nil {
+		if err = cfg.Control(network, addr, uintptr(fd)); err != nil {
+			syscall.Closesocket(fd)
+			return nil, fmt.Errorf("cannot run Control hook on %q: %w", addr, err)
+		}
+	}
+
+	err = syscall.Bind(fd, sa)
+	tr.trace(TraceRecord{Call: "bind", Addr: addr, Err: err})
+	if err != nil {
+		syscall.Closesocket(fd)
+		return nil, fmt.Errorf("cannot bind to %q: %s", addr, err)
+	}
+	backlog := cfg.Backlog
+	if backlog <= 0 {
+		backlog = syscall.SOMAXCONN
+	}
+	err = syscall.Listen(fd, backlog)
+	tr.trace(TraceRecord{Call: "listen", Value: backlog, Err: err})
+	if err != nil {
+		syscall.Closesocket(fd)
+		return nil, fmt.Errorf("cannot listen on %q: %s", addr, err)
+	}
+	res.Backlog = backlog
+
+	// net.FileListener duplicates the socket, so the file is closed
+	// in any case.
+	file := os.NewFile(uintptr(fd), fileNamePrefix+network+"."+addr)
+	ln, err := net.FileListener(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create listener on %q: %w", addr, err)
+	}
+	return ln, nil
+}
+
+// listenStd creates the listener with the standard library, which binds
+// the socket after fdSetup and uses the maximum backlog, so Backlog
+// is ignored.
+func (cfg *Config) listenStd(ctx context.Context, network, addr string, res *ListenResult) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) {
+				err = cfg.fdSetup(syscall.Handle(fd), network, res)
+				if err == nil && cfg.Control != nil {
+					if err = cfg.Control(network, addr, fd); err != nil {
+						err = fmt.Errorf("cannot run Control hook on %q: %w", addr, err)
+					}
+				}
+			}); cerr != nil {
+				return cerr
+			}
+			return err
+		},
+	}
+	return lc.Listen(ctx, network, addr)
+}
+
+// wsaSocket creates a non-inheritable overlapped socket, as the standard
+// library does.
+func wsaSocket(family int) (syscall.Handle, error) {
+	r, _, err := procWSASocket.Call(uintptr(family), syscall.SOCK_STREAM, syscall.IPPROTO_TCP,
+		0, 0, wsaFlagOverlapped|wsaFlagNoHandleInherit)
+	if syscall.Handle(r) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, fmt.Errorf("cannot create socket: %s", err)
+	}
+	return syscall.Handle(r), nil
+}
+
+// fileNamePrefix is the prefix of the names of listener files.
+var fileNamePrefix = "reuseport." + strconv.Itoa(os.Getpid()) + "."
+
+// windowsSockaddr resolves addr and returns the socket address together
+// with the resolved network, i.e. tcp4 or tcp6. The tcp network
+// is resolved to tcp6 for the empty and the IPv6 wildcard host,
+// and dualStack is set then, so the caller makes the listener accept
+// both IPv4 and IPv6 connections as the standard library does.
+func windowsSockaddr(ctx context.Context, network, addr string) (sa syscall.Sockaddr, lnet string, dualStack bool, err error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, "", false, errors.New("only tcp4 and tcp6 network is supported")
+	}
+	if err := checkAddrFamily(network, addr); err != nil {
+		return nil, "", false, err
+	}
+	tcpAddr, err := resolveTCPAddr(ctx, network, addr)
+	if err != nil {
+		return nil, "", false, err
+	}
+	ip := tcpAddr.IP
+	if network == "tcp4" || network == "tcp" && ip != nil && ip.To4() != nil {
+		sa4 := &syscall.SockaddrInet4{Port: tcpAddr.Port}
+		if ip != nil {
+			copy(sa4.Addr[:], ip.To4())
+		}
+		return sa4, "tcp4", false, nil
+	}
+	sa6 := &syscall.SockaddrInet6{Port: tcpAddr.Port}
+	if ip != nil {
+		copy(sa6.Addr[:], ip.To16())
+	}
+	if tcpAddr.Zone != "" {
+		if sa6.ZoneId, err = zoneIndex(tcpAddr.Zone); err != nil {
+			return nil, "", false, fmt.Errorf("cannot find interface %q: %s", tcpAddr.Zone, err)
+		}
+	}
+	dualStack = network == "tcp" && (ip == nil || ip.IsUnspecified())
+	return sa6, "tcp6", dualStack, nil
+}
+
+// ApplyConfig applies the options from cfg to the listener created
+// elsewhere, e.g. the one inherited from the parent process.
 //
+// Only NoDelay and InitialRTO may be changed on a listening socket
+// on Windows. ApplyConfig returns UnsupportedError if DeferAccept,
+// FastOpen, QuickACK, MaxPacingRate or HardwareTimestamping is set,
+// which are applied on the other platforms. The options which may be set
+// only when creating the listener are ignored the same way as on
+// the other platforms.
+func ApplyConfig(ln net.Listener, cfg Config) error {
+	var opt string
+	switch {
+	case cfg.DeferAccept:
+		opt = "DeferAccept"
+	case cfg.FastOpen:
+		opt = "FastOpen"
+	case cfg.QuickACK:
+		opt = "QuickACK"
+	case cfg.MaxPacingRate > 0:
+		opt = "MaxPacingRate"
+	case cfg.HardwareTimestamping:
+		opt = "HardwareTimestamping"
+	}
+	if opt != "" {
+		return &UnsupportedError{
+			Op:     "ApplyConfig with " + opt,
+			Reason: "the option cannot be set on a listening socket",
+		}
+	}
+	if !cfg.NoDelay && cfg.InitialRTO <= 0 {
+		return nil
+	}
+	tr := tracer(cfg.Trace)
+	return withFd(ln, func(fd uintptr) error {
+		if cfg.NoDelay {
+			if err := tr.setOption(syscall.Handle(fd), OptionNoDelay, 1); err != nil {
+				return fmt.Errorf("cannot enable TCP_NODELAY: %s", err)
+			}
+		}
+		if cfg.InitialRTO > 0 {
+			return setInitialRTO(syscall.Handle(fd), cfg.InitialRTO, tr)
+		}
+		return nil
+	})
+}
+
+// Explain describes what NewListener would do for creating the listener
+// on addr with cfg, one step per line, without binding anything.
+//
+// Only IP literals are accepted in addr, since the host may resolve
+// to different addresses by the time the listener is created.
 // Only tcp4 and tcp6 networks are supported.
-func NewListener(network, addr string, cfg Config) (net.Listener, error) {
-	return net.Listen(network, addr)
+//
+// The options are applied to a temporary unbound socket in the same order
+// as NewListener applies them, so the plan shows the options which would
+// be rejected on Windows. If NewListener would fail, the plan ends with
+// the failed step and the error is returned together with the plan.
+func (cfg Config) Explain(network, addr string) (string, error) {
+	if err := checkLiteralAddr(addr); err != nil {
+		return "", err
+	}
+	family := syscall.AF_INET
+	switch network {
+	case "tcp4":
+	case "tcp6":
+		family = syscall.AF_INET6
+	default:
+		return "", fmt.Errorf("cannot explain listening on %q: only tcp4 and tcp6 networks are supported", network)
+	}
+	if err := cfg.validateWindows(); err != nil {
+		return "", err
+	}
+	laddr, err := cfg.resolveLoopback(context.Background(), network, addr)
+	if err != nil {
+		return "", err
+	}
+
+	var e explainer
+	if family == syscall.AF_INET6 {
+		e.addf("socket(AF_INET6, SOCK_STREAM, IPPROTO_TCP)")
+	} else {
+		e.addf("socket(AF_INET, SOCK_STREAM, IPPROTO_TCP)")
+	}
+	fd, err := wsaSocket(family)
+	if err != nil {
+		return e.fail(err)
+	}
+	defer syscall.Closesocket(fd)
+
+	cfg.Trace = e.trace
+	if err = cfg.fdSetup(fd, network, &ListenResult{}); err != nil {
+		return e.fail(err)
+	}
+	if cfg.Control != nil {
+		e.addf("Control(%s, %s, fd)", network, laddr)
+	}
+	e.addf("bind(%s)", laddr)
+	if cfg.Backlog > 0 && fileListenerSupported {
+		e.addf("listen(%d)", cfg.Backlog)
+	} else {
+		e.addf("listen(%d) with the system-wide maximum backlog", syscall.SOMAXCONN)
+	}
+	if cfg.PostListen != nil {
+		e.addf("PostListen(fd)")
+	}
+	return e.String(), nil
+}
+
+// fdSetup is called before bind with the resolved network,
+// i.e. tcp4 or tcp6.
+func (cfg *Config) fdSetup(fd syscall.Handle, network string, res *ListenResult) error {
+	tr := tracer(cfg.Trace)
+
+	if cfg.ExclusiveAddrUse {
+		if err := tr.setsockoptInt(fd, syscall.SOL_SOCKET, soExclusiveAddrUse, "SO_EXCLUSIVEADDRUSE", 1); err != nil {
+			return fmt.Errorf("cannot enable SO_EXCLUSIVEADDRUSE: %s", err)
+		}
+		res.applied("SO_EXCLUSIVEADDRUSE")
+	}
+
+	if cfg.NoDelay {
+		if err := tr.setOption(fd, OptionNoDelay, 1); err != nil {
+			return fmt.Errorf("cannot enable TCP_NODELAY: %s", err)
+		}
+		res.applied(OptionNoDelay.String())
+	}
+
+	if cfg.ReceiveBufferSize > 0 {
+		if err := tr.setsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, "SO_RCVBUF", cfg.ReceiveBufferSize); err != nil {
+			return fmt.Errorf("cannot set SO_RCVBUF to %d: %s", cfg.ReceiveBufferSize, err)
+		}
+		res.applied("SO_RCVBUF")
+	}
+
+	if cfg.SendBufferSize > 0 {
+		if err := tr.setsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_SNDBUF, "SO_SNDBUF", cfg.SendBufferSize); err != nil {
+			return fmt.Errorf("cannot set SO_SNDBUF to %d: %s", cfg.SendBufferSize, err)
+		}
+		res.applied("SO_SNDBUF")
+	}
+
+	if ka := cfg.listenerKeepAlive(); ka != nil {
+		if err := setKeepAlive(uintptr(fd), ka, tr); err != nil {
+			return err
+		}
+		res.applied("SO_KEEPALIVE")
+	}
+
+	if cfg.InitialRTO > 0 {
+		if err := setInitialRTO(fd, cfg.InitialRTO, tr); err != nil {
+			return err
+		}
+		res.applied("SIO_TCP_INITIAL_RTO")
+	}
+
+	if cfg.FlowLabel != FlowLabelDefault {
+		return fmt.Errorf("cannot set FlowLabel: it is supported only on Linux: %w", ErrUnsupportedOption)
+	}
+
+	if cfg.ServiceClass != ServiceClassDefault {
+		return fmt.Errorf("cannot set ServiceClass: it is supported only on Linux and macOS: %w", ErrUnsupportedOption)
+	}
+
+	if cfg.DisableRecvAutotune {
+		return fmt.Errorf("cannot disable receive buffer autotuning: %w", ErrUnsupportedOption)
+	}
+
+	if cfg.Transparent {
+		return fmt.Errorf("cannot enable Transparent: it is supported only on Linux and OpenBSD: %w", ErrUnsupportedOption)
+	}
+
+	if cfg.MaxPacingRate > 0 {
+		if err := setMaxPacingRate(uintptr(fd), cfg.MaxPacingRate, tr); err != nil {
+			return err
+		}
+	}
+
+	if cfg.HardwareTimestamping {
+		return fmt.Errorf("cannot enable SO_TIMESTAMPING: it exists only on Linux: %w", ErrUnsupportedOption)
+	}
+
+	// IPV6_V6ONLY is set last, so it overrides the default set
+	// by net.ListenConfig before calling Control with Go versions older
+	// than 1.25, and the dual-stack default set by listenWinsock.
+	if v, ok := cfg.V6Only.sockoptValue(); ok && network == "tcp6" {
+		if err := tr.setOption(fd, OptionV6Only, v); err != nil {
+			return fmt.Errorf("cannot set IPV6_V6ONLY: %s", err)
+		}
+		res.applied(OptionV6Only.String())
+	}
+	return nil
+}
+
+// soExclusiveAddrUse is SO_EXCLUSIVEADDRUSE, i.e. ~SO_REUSEADDR.
+const soExclusiveAddrUse = ^syscall.SO_REUSEADDR
+
+// sioTCPInitialRTO is _WSAIOW(IOC_VENDOR, 17).
+const sioTCPInitialRTO = 0x98000011
+
+// tcpInitialRTOParameters is TCP_INITIAL_RTO_PARAMETERS.
+type tcpInitialRTOParameters struct {
+	Rtt                   uint16
+	MaxSynRetransmissions uint8
+}
+
+// tcpInitialRTODefaultMaxSynRetransmissions keeps the system default
+// for the number of SYN retransmissions.
+const tcpInitialRTODefaultMaxSynRetransmissions = 0xFE
+
+func setInitialRTO(fd syscall.Handle, d time.Duration, tr tracer) error {
+	ms := d / time.Millisecond
+	if ms <= 0 || ms > 0xFFFE {
+		return fmt.Errorf("cannot set initial RTO to %s: it must be in the range [1ms..65534ms]", d)
+	}
+	params := tcpInitialRTOParameters{
+		Rtt:                   uint16(ms),
+		MaxSynRetransmissions: tcpInitialRTODefaultMaxSynRetransmissions,
+	}
+	var n uint32
+	err := syscall.WSAIoctl(fd, sioTCPInitialRTO, (*byte)(unsafe.Pointer(&params)), uint32(unsafe.Sizeof(params)), nil, 0, &n, nil, 0)
+	tr.trace(TraceRecord{
+		Call:   "WSAIoctl",
+		Option: "SIO_TCP_INITIAL_RTO",
+		Value:  int(ms) * 1000,
+		Err:    err,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot set SIO_TCP_INITIAL_RTO: %s", err)
+	}
+	return nil
+}
+
+func inspectCommand(port int) string {
+	return fmt.Sprintf("netstat -ano -p tcp | findstr :%d", port)
+}
+
+func soMaxConn() (int, error) {
+	return syscall.SOMAXCONN, nil
 }
@@ -0,0 +1,126 @@
+// +build windows
+
+package tcplisten
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestNewListenerWinsockOptions(t *testing.T) {
+	cfg := Config{
+		ExclusiveAddrUse:  true,
+		NoDelay:           true,
+		Backlog:           32,
+		ReceiveBufferSize: 256 << 10,
+		SendBufferSize:    128 << 10,
+	}
+	res, err := NewListenerResult("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer res.Close()
+
+	backlog := cfg.Backlog
+	if !fileListenerSupported {
+		backlog = 0
+	}
+	if res.Backlog != backlog {
+		t.Fatalf("unexpected backlog %d. Expecting %d", res.Backlog, backlog)
+	}
+	for _, tc := range []struct {
+		name string
+		so   sockopt
+		want int
+	}{
+		{"SO_EXCLUSIVEADDRUSE", sockopt{syscall.SOL_SOCKET, soExclusiveAddrUse}, 1},
+		{"TCP_NODELAY", sockopt{syscall.IPPROTO_TCP, syscall.TCP_NODELAY}, 1},
+		{"SO_RCVBUF", sockopt{syscall.SOL_SOCKET, syscall.SO_RCVBUF}, cfg.ReceiveBufferSize},
+		{"SO_SNDBUF", sockopt{syscall.SOL_SOCKET, syscall.SO_SNDBUF}, cfg.SendBufferSize},
+	} {
+		if !res.hasOption(tc.name) {
+			t.Fatalf("%s hasn't been applied. Applied options: %v", tc.name, res.AppliedOptions)
+		}
+		var v int
+		if err = withFd(res.Listener, func(fd uintptr) error {
+			v, err = tc.so.get(fd)
+			return err
+		}); err != nil {
+			t.Fatalf("cannot obtain %s: %s", tc.name, err)
+		}
+		if v != tc.want {
+			t.Fatalf("unexpected %s value %d. Expecting %d", tc.name, v, tc.want)
+		}
+	}
+
+	// SO_EXCLUSIVEADDRUSE prevents binding the port with SO_REUSEADDR.
+	reuseAddr := func(network, address string, fd uintptr) error {
+		return syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	}
+	if ln, err := NewListener("tcp4", res.Addr().String(), Config{Control: reuseAddr}); err == nil {
+		ln.Close()
+		t.Fatalf("expecting error when binding %s taken with SO_EXCLUSIVEADDRUSE", res.Addr())
+	}
+
+	c, err := net.Dial("tcp4", res.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	sc, err := res.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	sc.Close()
+}
+
+func TestConfigReusePortWindows(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{ReusePort: true})
+	if err == nil {
+		ln.Close()
+	}
+	if !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrUnsupportedOption)
+	}
+}
+
+func TestNewListenerDualStack(t *testing.T) {
+	ln, err := NewListener("tcp", ":0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	c, err := net.Dial("tcp4", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("cannot dial the wildcard tcp listener over IPv4: %s", err)
+	}
+	defer c.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	sc.Close()
+}
+
+func TestApplyConfigWindows(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	if err = ApplyConfig(ln, Config{NoDelay: true}); err != nil {
+		t.Fatalf("cannot apply NoDelay: %s", err)
+	}
+	if v, err := GetOption(ln, OptionNoDelay); err != nil || v == 0 {
+		t.Fatalf("unexpected TCP_NODELAY value %d, error %v. Expecting it to be enabled", v, err)
+	}
+	if err = ApplyConfig(ln, Config{QuickACK: true}); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrUnsupportedOption)
+	}
+}
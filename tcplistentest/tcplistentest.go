@@ -0,0 +1,95 @@
+// Package tcplistentest provides helpers for testing code which uses
+// listeners created by tcplisten.
+package tcplistentest
+
+import (
+	"errors"
+	"flag"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/xenking/tcplisten"
+)
+
+var ipv6 = flag.Bool("tcplistentest.ipv6", false, "if true, local listeners are created on [::1] instead of 127.0.0.1")
+
+// NewLocalListener returns a listener with the options from cfg
+// on a free loopback port, together with the address it is bound to.
+//
+// The listener is created on 127.0.0.1, or on [::1] if the
+// -tcplistentest.ipv6 flag is set. It is closed when the test finishes.
+//
+// The test is skipped if an option needs privileges the test lacks
+// or isn't supported on the current platform.
+func NewLocalListener(t testing.TB, cfg tcplisten.Config) (net.Listener, *net.TCPAddr) {
+	t.Helper()
+	network, addr := "tcp4", "127.0.0.1:0"
+	if *ipv6 {
+		network, addr = "tcp6", "[::1]:0"
+	}
+	ln, err := tcplisten.NewListener(network, addr, cfg)
+	if err != nil {
+		switch {
+		case isPermission(err):
+			t.Skipf("skipping test: the listener options need privileges: %s", err)
+		case errors.Is(err, tcplisten.ErrUnsupportedOption):
+			t.Skipf("skipping test: the listener options aren't supported: %s", err)
+		}
+		t.Fatalf("cannot create listener on %s: %s", addr, err)
+	}
+	t.Cleanup(func() {
+		ln.Close()
+	})
+	return ln, ln.Addr().(*net.TCPAddr)
+}
+
+// Pair returns a connected pair of connections. The server connection
+// is accepted from a listener created by NewLocalListener with cfg,
+// so it inherits the options of the listening socket.
+//
+// Both connections are closed when the test finishes. Pair cannot be used
+// with cfg.DeferAccept, since the client sends nothing.
+func Pair(t testing.TB, cfg tcplisten.Config) (client, server net.Conn) {
+	t.Helper()
+	ln, addr := NewLocalListener(t, cfg)
+
+	type acceptResult struct {
+		c   net.Conn
+		err error
+	}
+	ch := make(chan acceptResult, 1)
+	go func() {
+		c, err := ln.Accept()
+		ch <- acceptResult{c, err}
+	}()
+
+	client, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("cannot dial %s: %s", addr, err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+	})
+	r := <-ch
+	if r.err != nil {
+		t.Fatalf("cannot accept connection on %s: %s", addr, r.err)
+	}
+	t.Cleanup(func() {
+		r.c.Close()
+	})
+	return client, r.c
+}
+
+// isPermission reports whether err is caused by insufficient privileges.
+//
+// Socket option errors are reported by tcplisten as text,
+// so the message is checked too.
+func isPermission(err error) bool {
+	if errors.Is(err, os.ErrPermission) {
+		return true
+	}
+	s := err.Error()
+	return strings.Contains(s, "operation not permitted") || strings.Contains(s, "permission denied")
+}
@@ -0,0 +1,52 @@
+package tcplistentest
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/xenking/tcplisten"
+)
+
+func TestNewLocalListener(t *testing.T) {
+	ln, addr := NewLocalListener(t, tcplisten.Config{})
+	if !addr.IP.IsLoopback() || addr.Port == 0 {
+		t.Fatalf("unexpected listener address %s. Expecting loopback address with non-zero port", addr)
+	}
+	if ln.Addr().String() != addr.String() {
+		t.Fatalf("unexpected address %s. Expecting %s", addr, ln.Addr())
+	}
+}
+
+func TestPair(t *testing.T) {
+	client, server := Pair(t, tcplisten.Config{NoDelay: true})
+	if client.LocalAddr().String() != server.RemoteAddr().String() {
+		t.Fatalf("unexpected server peer %s. Expecting %s", server.RemoteAddr(), client.LocalAddr())
+	}
+
+	go client.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(server, buf); err != nil {
+		t.Fatalf("cannot read from server connection: %s", err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("unexpected data %q. Expecting %q", buf, "ping")
+	}
+}
+
+func TestIsPermission(t *testing.T) {
+	for _, err := range []error{
+		syscall.EPERM,
+		&net.OpError{Op: "listen", Err: syscall.EACCES},
+		errors.New("cannot set flow label: operation not permitted"),
+	} {
+		if !isPermission(err) {
+			t.Fatalf("expecting %q to be a permission error", err)
+		}
+	}
+	if isPermission(errors.New("address already in use")) {
+		t.Fatalf("unexpected permission error")
+	}
+}
@@ -0,0 +1,90 @@
+package tcplisten
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ThrottledListener returns a listener limiting the rate of Accept calls
+// with a token bucket, protecting the rest of the program from starvation
+// during connection floods.
+//
+// Up to maxBurst connections are accepted without delay, and a token
+// for one more connection is added every refill. Connections waiting
+// for a token stay in the accept queue of the listening socket.
+func ThrottledListener(ln net.Listener, maxBurst int, refill time.Duration) net.Listener {
+	if maxBurst < 1 {
+		maxBurst = 1
+	}
+	return &throttledListener{
+		Listener: ln,
+		maxBurst: float64(maxBurst),
+		refill:   refill,
+		tokens:   float64(maxBurst),
+		last:     time.Now(),
+		done:     make(chan struct{}),
+	}
+}
+
+type throttledListener struct {
+	net.Listener
+	maxBurst float64
+	refill   time.Duration
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// reserve takes a token and returns the time to wait until it is available.
+func (ln *throttledListener) reserve() time.Duration {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	now := time.Now()
+	if ln.refill > 0 {
+		ln.tokens += float64(now.Sub(ln.last)) / float64(ln.refill)
+	} else {
+		ln.tokens = ln.maxBurst
+	}
+	if ln.tokens > ln.maxBurst {
+		ln.tokens = ln.maxBurst
+	}
+	ln.last = now
+
+	ln.tokens--
+	if ln.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-ln.tokens * float64(ln.refill))
+}
+
+func (ln *throttledListener) Accept() (net.Conn, error) {
+	if d := ln.reserve(); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ln.done:
+			t.Stop()
+			return nil, ErrListenerClosed
+		}
+	}
+	return ln.Listener.Accept()
+}
+
+func (ln *throttledListener) Close() error {
+	err := ln.Listener.Close()
+	ln.closeOnce.Do(func() {
+		close(ln.done)
+	})
+	return err
+}
+
+func (ln *throttledListener) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(ln.Listener)
+}
@@ -0,0 +1,50 @@
+package tcplisten
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestThrottledListener(t *testing.T) {
+	tln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	const refill = 100 * time.Millisecond
+	ln := ThrottledListener(tln, 3, refill)
+	defer ln.Close()
+
+	for i := 0; i < 5; i++ {
+		c, err := net.Dial("tcp4", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot dial: %s", err)
+		}
+		defer c.Close()
+	}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept: %s", err)
+		}
+		c.Close()
+		if i == 2 {
+			if d := time.Since(start); d > refill/2 {
+				t.Fatalf("the burst has taken %s. Expecting no delay", d)
+			}
+		}
+	}
+	if d := time.Since(start); d < 2*refill-refill/4 {
+		t.Fatalf("accepting 5 connections has taken %s. Expecting at least %s", d, 2*refill)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ln.Close()
+	}()
+	if _, err = ln.Accept(); err == nil {
+		t.Fatalf("expecting error after Close")
+	}
+}
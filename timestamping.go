@@ -0,0 +1,24 @@
+package tcplisten
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoTimestamps is returned by ReadTxTimestamps if no timestamps
+// of sent data are queued on the connection.
+var ErrNoTimestamps = errors.New("tcplisten: no timestamps are queued")
+
+// Timestamps are the kernel timestamps of received or sent data
+// reported for connections accepted from listeners with
+// Config.HardwareTimestamping.
+type Timestamps struct {
+	// Software is the time the kernel has received the data
+	// or passed it to the NIC.
+	Software time.Time
+
+	// Hardware is the time the NIC has received or sent the data.
+	// It is zero unless hardware timestamping is supported
+	// and enabled on the interface.
+	Hardware time.Time
+}
@@ -0,0 +1,131 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// SOF_TIMESTAMPING_* flags from linux/net_tstamp.h.
+const (
+	sofTimestampingTxHardware  = 1 << 0
+	sofTimestampingTxSoftware  = 1 << 1
+	sofTimestampingRxHardware  = 1 << 2
+	sofTimestampingRxSoftware  = 1 << 3
+	sofTimestampingSoftware    = 1 << 4
+	sofTimestampingRawHardware = 1 << 6
+	sofTimestampingOptTSOnly   = 1 << 11
+
+	// timestampingFlags requests hardware and software timestamps
+	// of received and sent data. Timestamps of sent data are queued
+	// without the data itself.
+	timestampingFlags = sofTimestampingTxHardware | sofTimestampingTxSoftware |
+		sofTimestampingRxHardware | sofTimestampingRxSoftware |
+		sofTimestampingSoftware | sofTimestampingRawHardware | sofTimestampingOptTSOnly
+)
+
+func enableTimestamping(fd int, tr tracer) error {
+	if err := tr.setOption(fd, OptionTimestamping, timestampingFlags); err != nil {
+		return fmt.Errorf("cannot enable SO_TIMESTAMPING: %s", err)
+	}
+	return nil
+}
+
+// ReadTimestamped works like c.Read, but also returns the timestamps
+// of the last segment read.
+//
+// The timestamps are zero if c has been accepted from a listener
+// without Config.HardwareTimestamping.
+func ReadTimestamped(c net.Conn, b []byte) (int, Timestamps, error) {
+	rc, err := rawConn(c)
+	if err != nil {
+		return 0, Timestamps{}, err
+	}
+	var (
+		n, oobn int
+		rerr    error
+		oob     [128]byte
+	)
+	err = rc.Read(func(fd uintptr) bool {
+		n, oobn, _, _, rerr = syscall.Recvmsg(int(fd), b, oob[:], 0)
+		return rerr != syscall.EAGAIN
+	})
+	if err != nil {
+		return 0, Timestamps{}, err
+	}
+	if rerr != nil {
+		return 0, Timestamps{}, &net.OpError{Op: "read", Net: "tcp", Addr: c.RemoteAddr(), Err: os.NewSyscallError("recvmsg", rerr)}
+	}
+	ts, err := parseTimestamps(oob[:oobn])
+	if err != nil {
+		return n, Timestamps{}, err
+	}
+	if n == 0 && len(b) > 0 {
+		return 0, ts, io.EOF
+	}
+	return n, ts, nil
+}
+
+// ReadTxTimestamps returns the timestamps of the next sent data queued
+// on the error queue of c. It doesn't block and returns ErrNoTimestamps
+// if nothing is queued.
+//
+// The kernel queues timestamps for the last byte of every write
+// on connections accepted from a listener with Config.HardwareTimestamping.
+// The queue is limited by the receive buffer, so the timestamps
+// which aren't read in time are dropped.
+func ReadTxTimestamps(c net.Conn) (Timestamps, error) {
+	var (
+		ts  Timestamps
+		oob [256]byte
+	)
+	err := withFd(c, func(fd uintptr) error {
+		_, oobn, _, _, err := syscall.Recvmsg(int(fd), nil, oob[:], syscall.MSG_ERRQUEUE|syscall.MSG_DONTWAIT)
+		if err == syscall.EAGAIN {
+			return ErrNoTimestamps
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read error queue: %s", err)
+		}
+		ts, err = parseTimestamps(oob[:oobn])
+		return err
+	})
+	return ts, err
+}
+
+// parseTimestamps extracts SCM_TIMESTAMPING from the control messages.
+func parseTimestamps(oob []byte) (Timestamps, error) {
+	var ts Timestamps
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return ts, fmt.Errorf("cannot parse control messages: %s", err)
+	}
+	for _, m := range msgs {
+		if m.Header.Level != syscall.SOL_SOCKET || m.Header.Type != syscall.SO_TIMESTAMPING {
+			continue
+		}
+		// struct scm_timestamping contains the software timestamp,
+		// a deprecated one and the raw hardware timestamp.
+		var tss [3]syscall.Timespec
+		if len(m.Data) < int(unsafe.Sizeof(tss)) {
+			continue
+		}
+		copy((*[unsafe.Sizeof(tss)]byte)(unsafe.Pointer(&tss))[:], m.Data)
+		ts.Software = timespecTime(tss[0])
+		ts.Hardware = timespecTime(tss[2])
+	}
+	return ts, nil
+}
+
+func timespecTime(ts syscall.Timespec) time.Time {
+	if ts.Sec == 0 && ts.Nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts.Unix())
+}
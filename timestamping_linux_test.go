@@ -0,0 +1,76 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConfigHardwareTimestamping(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{HardwareTimestamping: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	c, err := net.Dial("tcp4", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial: %s", err)
+	}
+	defer c.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept: %s", err)
+	}
+	defer sc.Close()
+
+	// The kernel enables RX timestamps asynchronously after the first
+	// socket asks for them, so the first segments may come without them.
+	var ts Timestamps
+	buf := make([]byte, 16)
+	for i := 0; i < 50 && ts.Software.IsZero(); i++ {
+		start := time.Now()
+		if _, err = c.Write([]byte("ping")); err != nil {
+			t.Fatalf("cannot write: %s", err)
+		}
+		var n int
+		n, ts, err = ReadTimestamped(sc, buf)
+		if err != nil {
+			t.Fatalf("cannot read: %s", err)
+		}
+		if string(buf[:n]) != "ping" {
+			t.Fatalf("unexpected data %q. Expecting %q", buf[:n], "ping")
+		}
+		if ts.Software.IsZero() {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		if ts.Software.Before(start.Add(-time.Second)) || ts.Software.After(time.Now().Add(time.Second)) {
+			t.Fatalf("unexpected software RX timestamp %s. Expecting around %s", ts.Software, start)
+		}
+	}
+	if ts.Software.IsZero() {
+		t.Fatalf("missing software RX timestamp")
+	}
+
+	if _, err = ReadTxTimestamps(sc); err != ErrNoTimestamps {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrNoTimestamps)
+	}
+	if _, err = sc.Write([]byte("pong")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	for i := 0; i < 50; i++ {
+		if ts, err = ReadTxTimestamps(sc); err != ErrNoTimestamps {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("cannot read TX timestamps: %s", err)
+	}
+	if ts.Software.IsZero() {
+		t.Fatalf("missing software TX timestamp")
+	}
+}
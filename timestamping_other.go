@@ -0,0 +1,34 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+)
+
+func enableTimestamping(fd int, tr tracer) error {
+	return fmt.Errorf("cannot enable SO_TIMESTAMPING: it exists only on Linux: %w", ErrUnsupportedOption)
+}
+
+// ReadTimestamped works like c.Read, but also returns the timestamps
+// of the last segment read.
+//
+// It is supported only on Linux.
+func ReadTimestamped(c net.Conn, b []byte) (int, Timestamps, error) {
+	return 0, Timestamps{}, &UnsupportedError{
+		Op:     "ReadTimestamped",
+		Reason: "SO_TIMESTAMPING exists only on Linux",
+	}
+}
+
+// ReadTxTimestamps returns the timestamps of the next sent data queued
+// on the error queue of c.
+//
+// It is supported only on Linux.
+func ReadTxTimestamps(c net.Conn) (Timestamps, error) {
+	return Timestamps{}, &UnsupportedError{
+		Op:     "ReadTxTimestamps",
+		Reason: "SO_TIMESTAMPING exists only on Linux",
+	}
+}
@@ -0,0 +1,14 @@
+package tcplisten
+
+import (
+	"errors"
+)
+
+// ErrNotTransparent is returned by OriginalDst for connections
+// which haven't been accepted on a transparent listener.
+var ErrNotTransparent = errors.New("tcplisten: connection hasn't been accepted on a transparent listener")
+
+// ErrNotRedirected is returned by NetfilterOriginalDst for connections
+// which haven't been redirected by netfilter. Their original destination
+// is the local address of the connection.
+var ErrNotRedirected = errors.New("tcplisten: connection hasn't been redirected by netfilter")
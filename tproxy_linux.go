@@ -0,0 +1,148 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ipTransparent   = 19
+	ipv6Transparent = 75
+	soOriginalDst   = 80
+)
+
+// enableTransparent enables IP_TRANSPARENT or IPV6_TRANSPARENT
+// depending on the family of sa and returns the name of the option.
+func enableTransparent(fd int, sa syscall.Sockaddr, tr tracer) (string, error) {
+	level, opt, option := syscall.SOL_IP, ipTransparent, "IP_TRANSPARENT"
+	if _, ok := sa.(*syscall.SockaddrInet6); ok {
+		level, opt, option = syscall.SOL_IPV6, ipv6Transparent, "IPV6_TRANSPARENT"
+	}
+	err := tr.setsockoptInt(fd, level, opt, option, 1)
+	if err == syscall.EPERM {
+		return "", &MissingCapabilityError{
+			Option:     option,
+			Capability: "CAP_NET_ADMIN",
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot enable %s: %s", option, err)
+	}
+	return option, nil
+}
+
+// OriginalDst returns the destination the client has dialed for
+// the connection accepted on a transparent (TPROXY) listener.
+//
+// TPROXY delivers connections to the listener without rewriting them,
+// so the original destination is the local address of the accepted
+// socket as reported by getsockname(2). IPv4-mapped IPv6 addresses
+// are returned as IPv4 addresses.
+//
+// The listener must have IP_TRANSPARENT or IPV6_TRANSPARENT enabled,
+// e.g. via Config.Transparent. The option is inherited by the accepted
+// connections, so OriginalDst returns ErrNotTransparent if it isn't set
+// on c. Otherwise the returned address would be the proxy's own address.
+//
+// Use NetfilterOriginalDst for connections redirected with iptables
+// REDIRECT instead of TPROXY.
+func OriginalDst(c net.Conn) (*net.TCPAddr, error) {
+	var addr *net.TCPAddr
+	err := withFd(c, func(fd uintptr) error {
+		sa, err := syscall.Getsockname(int(fd))
+		if err != nil {
+			return fmt.Errorf("cannot obtain local address: %s", err)
+		}
+		level, opt, option := syscall.SOL_IP, ipTransparent, "IP_TRANSPARENT"
+		if _, ok := sa.(*syscall.SockaddrInet6); ok {
+			level, opt, option = syscall.SOL_IPV6, ipv6Transparent, "IPV6_TRANSPARENT"
+		}
+		v, err := syscall.GetsockoptInt(int(fd), level, opt)
+		if err != nil {
+			return fmt.Errorf("cannot obtain %s: %s", option, err)
+		}
+		if v == 0 {
+			return ErrNotTransparent
+		}
+		if addr = sockaddrToTCPAddr(sa); addr == nil {
+			return fmt.Errorf("cannot obtain original destination of non-TCP connection %T", c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		addr.IP = ip4
+		addr.Zone = ""
+	}
+	return addr, nil
+}
+
+// NetfilterOriginalDst returns the destination the client has dialed for
+// the connection redirected with iptables REDIRECT or DNAT.
+//
+// The address is obtained from conntrack with SO_ORIGINAL_DST
+// (IP6T_SO_ORIGINAL_DST for IPv6). ErrNotRedirected is returned
+// for connections without the NAT entry, so the caller may fall back
+// to the local address of the connection.
+func NetfilterOriginalDst(c net.Conn) (*net.TCPAddr, error) {
+	var addr *net.TCPAddr
+	err := withFd(c, func(fd uintptr) error {
+		sa, err := syscall.Getsockname(int(fd))
+		if err != nil {
+			return fmt.Errorf("cannot obtain local address: %s", err)
+		}
+		local := sockaddrToTCPAddr(sa)
+		if local == nil {
+			return fmt.Errorf("cannot obtain original destination of non-TCP connection %T", c)
+		}
+		// IPv4 connections accepted on dual-stack sockets are tracked
+		// by the IPv4 conntrack.
+		level := syscall.SOL_IPV6
+		if local.IP.To4() != nil {
+			level = syscall.SOL_IP
+		}
+		// sockaddr_in6 is large enough for sockaddr_in.
+		var raw syscall.RawSockaddrInet6
+		l := uint32(unsafe.Sizeof(raw))
+		err = getsockopt(int(fd), level, soOriginalDst, unsafe.Pointer(&raw), &l)
+		if err == syscall.ENOENT {
+			return ErrNotRedirected
+		}
+		if err != nil {
+			return fmt.Errorf("cannot obtain SO_ORIGINAL_DST: %s", err)
+		}
+		if addr = rawSockaddrToTCPAddr(&raw); addr == nil {
+			return fmt.Errorf("cannot parse SO_ORIGINAL_DST of family %d", raw.Family)
+		}
+		if addr.IP.To4() == nil {
+			addr.Zone = local.Zone
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
+
+// rawSockaddrToTCPAddr converts sockaddr_in or sockaddr_in6 to TCPAddr.
+func rawSockaddrToTCPAddr(raw *syscall.RawSockaddrInet6) *net.TCPAddr {
+	port := (*[2]byte)(unsafe.Pointer(&raw.Port))
+	addr := &net.TCPAddr{Port: int(port[0])<<8 | int(port[1])}
+	switch raw.Family {
+	case syscall.AF_INET:
+		raw4 := (*syscall.RawSockaddrInet4)(unsafe.Pointer(raw))
+		addr.IP = append(net.IP(nil), raw4.Addr[:]...)
+	case syscall.AF_INET6:
+		addr.IP = append(net.IP(nil), raw.Addr[:]...)
+	default:
+		return nil
+	}
+	return addr
+}
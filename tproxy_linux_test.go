@@ -0,0 +1,123 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func TestOriginalDst(t *testing.T) {
+	ln, err := NewListener("tcp6", "[::]:0", Config{Transparent: true})
+	var ce *MissingCapabilityError
+	if errors.As(err, &ce) {
+		t.Skipf("cannot create transparent listener: %s", err)
+	}
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	// The listener accepts IPv4 connections, so the original destination
+	// is reported as an IPv4-mapped address by getsockname.
+	dst := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: ln.Addr().(*net.TCPAddr).Port}
+	c := acceptDialed(t, ln, dst.String())
+	defer c.Close()
+
+	addr, err := OriginalDst(c)
+	if err != nil {
+		t.Fatalf("cannot obtain original destination: %s", err)
+	}
+	if addr.String() != dst.String() || len(addr.IP) != net.IPv4len {
+		t.Fatalf("unexpected original destination %s. Expecting %s", addr, dst)
+	}
+}
+
+func TestTransparentNonLocalAddr(t *testing.T) {
+	// 192.0.2.0/24 is TEST-NET-1, so the address cannot be local.
+	res, err := NewListenerResult("tcp4", "192.0.2.1:0", Config{Transparent: true})
+	var ce *MissingCapabilityError
+	if errors.As(err, &ce) {
+		t.Skipf("cannot create transparent listener: %s", err)
+	}
+	if err != nil {
+		t.Fatalf("cannot bind to non-local address: %s", err)
+	}
+	defer res.Close()
+	if !res.hasOption("IP_TRANSPARENT") {
+		t.Fatalf("IP_TRANSPARENT is missing in applied options %q", res.AppliedOptions)
+	}
+
+	if _, err = NewListener("tcp4", "192.0.2.1:0", Config{}); err == nil {
+		t.Fatalf("expecting error for non-local address without Transparent")
+	}
+}
+
+func TestOriginalDstNotTransparent(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	c := acceptDialed(t, ln, ln.Addr().String())
+	defer c.Close()
+
+	if _, err = OriginalDst(c); err != ErrNotTransparent {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrNotTransparent)
+	}
+}
+
+// acceptDialed dials addr and returns the connection accepted on ln.
+func acceptDialed(t *testing.T, ln net.Listener, addr string) net.Conn {
+	cc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("cannot dial %s: %s", addr, err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	return c
+}
+
+func TestNetfilterOriginalDstNotRedirected(t *testing.T) {
+	ln, err := NewListener("tcp4", "127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+
+	c := acceptDialed(t, ln, ln.Addr().String())
+	defer c.Close()
+
+	_, err = NetfilterOriginalDst(c)
+	if errors.Is(err, syscall.ENOPROTOOPT) {
+		t.Skipf("conntrack isn't available: %s", err)
+	}
+	if err != ErrNotRedirected {
+		t.Fatalf("unexpected error %v. Expecting %v", err, ErrNotRedirected)
+	}
+}
+
+func TestRawSockaddrToTCPAddr(t *testing.T) {
+	var raw syscall.RawSockaddrInet6
+	raw4 := (*syscall.RawSockaddrInet4)(unsafe.Pointer(&raw))
+	raw4.Family = syscall.AF_INET
+	raw4.Addr = [4]byte{10, 1, 2, 3}
+	*(*[2]byte)(unsafe.Pointer(&raw4.Port)) = [2]byte{0x1f, 0x90}
+	if addr := rawSockaddrToTCPAddr(&raw); addr == nil || addr.String() != "10.1.2.3:8080" {
+		t.Fatalf("unexpected address %v. Expecting 10.1.2.3:8080", addr)
+	}
+
+	raw = syscall.RawSockaddrInet6{Family: syscall.AF_INET6}
+	raw.Addr[15] = 1
+	*(*[2]byte)(unsafe.Pointer(&raw.Port)) = [2]byte{0x01, 0xbb}
+	if addr := rawSockaddrToTCPAddr(&raw); addr == nil || addr.String() != "[::1]:443" {
+		t.Fatalf("unexpected address %v. Expecting [::1]:443", addr)
+	}
+}
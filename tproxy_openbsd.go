@@ -0,0 +1,24 @@
+// +build openbsd
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// enableTransparent enables SO_BINDANY, which is the OpenBSD counterpart
+// of IP_TRANSPARENT used together with pf divert-to rules.
+func enableTransparent(fd int, sa syscall.Sockaddr, tr tracer) (string, error) {
+	err := tr.setsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_BINDANY, "SO_BINDANY", 1)
+	if err == syscall.EPERM {
+		return "", &MissingCapabilityError{
+			Option:     "SO_BINDANY",
+			Capability: "root",
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot enable SO_BINDANY: %s", err)
+	}
+	return "SO_BINDANY", nil
+}
@@ -0,0 +1,29 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// OriginalDst returns the destination the client has dialed for
+// the connection accepted on a transparent (TPROXY) listener.
+//
+// TPROXY exists only on Linux.
+func OriginalDst(c net.Conn) (*net.TCPAddr, error) {
+	return nil, &UnsupportedError{
+		Op:     "OriginalDst",
+		Reason: "TPROXY exists only on Linux",
+	}
+}
+
+// NetfilterOriginalDst returns the destination the client has dialed for
+// the connection redirected with iptables REDIRECT or DNAT.
+//
+// Netfilter exists only on Linux.
+func NetfilterOriginalDst(c net.Conn) (*net.TCPAddr, error) {
+	return nil, &UnsupportedError{
+		Op:     "NetfilterOriginalDst",
+		Reason: "netfilter exists only on Linux",
+	}
+}
@@ -0,0 +1,12 @@
+// +build !linux,!openbsd,!windows,!plan9
+
+package tcplisten
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func enableTransparent(fd int, sa syscall.Sockaddr, tr tracer) (string, error) {
+	return "", fmt.Errorf("cannot enable Transparent: it is supported only on Linux and OpenBSD: %w", ErrUnsupportedOption)
+}
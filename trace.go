@@ -0,0 +1,35 @@
+package tcplisten
+
+// TraceRecord describes a syscall the package has made while setting up
+// a socket.
+type TraceRecord struct {
+	// Call is the name of the syscall, e.g. "setsockopt", "bind"
+	// or "listen".
+	Call string
+
+	// Level is the socket option level, e.g. syscall.IPPROTO_TCP.
+	Level int
+
+	// Option is the socket option name, e.g. "TCP_NODELAY".
+	Option string
+
+	// Value is the option value. Timeouts are in microseconds.
+	// It is the backlog for listen.
+	Value int
+
+	// Addr is the address passed to bind.
+	Addr string
+
+	// Err is the error returned from the syscall, usually syscall.Errno.
+	Err error
+}
+
+// tracer passes trace records to the user-supplied hook.
+// A nil tracer discards records without allocations.
+type tracer func(r TraceRecord)
+
+func (tr tracer) trace(r TraceRecord) {
+	if tr != nil {
+		tr(r)
+	}
+}
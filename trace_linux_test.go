@@ -0,0 +1,64 @@
+package tcplisten
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestConfigTrace(t *testing.T) {
+	var calls []string
+	cfg := Config{
+		ReusePort:   true,
+		DeferAccept: true,
+		Backlog:     64,
+		Trace: func(r TraceRecord) {
+			if r.Err != nil {
+				t.Errorf("unexpected error in %+v", r)
+			}
+			switch r.Call {
+			case "setsockopt", "getsockopt":
+				calls = append(calls, fmt.Sprintf("%s(%d, %s, %d)", r.Call, r.Level, r.Option, r.Value))
+			case "bind":
+				calls = append(calls, "bind("+r.Addr+")")
+			default:
+				calls = append(calls, fmt.Sprintf("%s(%d)", r.Call, r.Value))
+			}
+		},
+	}
+	ln, err := NewListener("tcp4", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	ln.Close()
+
+	expected := []string{
+		fmt.Sprintf("setsockopt(%d, SO_REUSEADDR, 1)", syscall.SOL_SOCKET),
+		fmt.Sprintf("setsockopt(%d, TCP_NODELAY, 1)", syscall.IPPROTO_TCP),
+		fmt.Sprintf("setsockopt(%d, SO_REUSEPORT, 1)", syscall.SOL_SOCKET),
+		fmt.Sprintf("setsockopt(%d, TCP_DEFER_ACCEPT, 1)", syscall.IPPROTO_TCP),
+		fmt.Sprintf("getsockopt(%d, SO_ERROR, 0)", syscall.SOL_SOCKET),
+		"bind(127.0.0.1:0)",
+		"listen(64)",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected calls\n%s\nExpecting\n%s", strings.Join(calls, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestTracerNilAllocs(t *testing.T) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("cannot create socket: %s", err)
+	}
+	defer syscall.Close(fd)
+
+	var tr tracer
+	n := testing.AllocsPerRun(100, func() {
+		tr.setsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_NODELAY, "TCP_NODELAY", 1)
+	})
+	if n != 0 {
+		t.Fatalf("unexpected allocations with nil tracer: %v", n)
+	}
+}
@@ -0,0 +1,20 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"syscall"
+)
+
+// setsockoptInt calls syscall.SetsockoptInt and traces the call.
+func (tr tracer) setsockoptInt(fd, level, opt int, option string, value int) error {
+	err := syscall.SetsockoptInt(fd, level, opt, value)
+	tr.trace(TraceRecord{
+		Call:   "setsockopt",
+		Level:  level,
+		Option: option,
+		Value:  value,
+		Err:    err,
+	})
+	return err
+}
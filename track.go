@@ -0,0 +1,79 @@
+package tcplisten
+
+import (
+	"net"
+	"sync"
+	"syscall"
+)
+
+// ConnSet is a set of connections tracked by the application.
+type ConnSet interface {
+	// Conns returns a snapshot of the tracked connections.
+	Conns() []net.Conn
+}
+
+// TrackedListener tracks connections accepted from the wrapped listener
+// until they are closed.
+//
+// Use TrackConns for creating TrackedListener.
+type TrackedListener struct {
+	net.Listener
+
+	mu sync.Mutex
+	// conns maps the wrappers to the connections returned
+	// from Accept.
+	conns map[*configConn]net.Conn
+}
+
+// TrackConns returns a listener tracking connections accepted from ln.
+func TrackConns(ln net.Listener) *TrackedListener {
+	tl := &TrackedListener{
+		conns: make(map[*configConn]net.Conn),
+	}
+	cl, ok := ln.(*configListener)
+	if ok && cl.tracked == nil {
+		cl = composeListener(cl)
+	} else {
+		cl = &configListener{Listener: ln}
+	}
+	cl.tracked = tl
+	tl.Listener = cl
+	return tl
+}
+
+func (ln *TrackedListener) add(c *configConn, accepted net.Conn) {
+	ln.mu.Lock()
+	ln.conns[c] = accepted
+	ln.mu.Unlock()
+}
+
+func (ln *TrackedListener) remove(c *configConn) {
+	ln.mu.Lock()
+	delete(ln.conns, c)
+	ln.mu.Unlock()
+}
+
+// Conns returns a snapshot of the connections which have been accepted
+// and haven't been closed yet.
+func (ln *TrackedListener) Conns() []net.Conn {
+	ln.mu.Lock()
+	conns := make([]net.Conn, 0, len(ln.conns))
+	for _, c := range ln.conns {
+		conns = append(conns, c)
+	}
+	ln.mu.Unlock()
+	return conns
+}
+
+// Len returns the number of tracked connections.
+func (ln *TrackedListener) Len() int {
+	ln.mu.Lock()
+	n := len(ln.conns)
+	ln.mu.Unlock()
+	return n
+}
+
+// SyscallConn returns the raw connection of the wrapped listener.
+func (ln *TrackedListener) SyscallConn() (syscall.RawConn, error) {
+	return rawConn(ln.Listener)
+}
@@ -0,0 +1,35 @@
+package tcplisten
+
+// TuningReport is a snapshot of the kernel settings limiting listeners,
+// returned by SystemTuning.
+//
+// Values which cannot be read are -1.
+type TuningReport struct {
+	// SoMaxConn is net.core.somaxconn, the limit of Config.Backlog.
+	SoMaxConn int
+
+	// FastOpen is net.ipv4.tcp_fastopen. Bit 1 enables the client side
+	// of TFO and bit 2 enables the server side, which Config.FastOpen
+	// requires.
+	FastOpen int
+
+	// MaxSynBacklog is net.ipv4.tcp_max_syn_backlog, the limit
+	// of the connections waiting for the handshake to complete.
+	MaxSynBacklog int
+
+	// TwReuse is net.ipv4.tcp_tw_reuse. 1 allows reusing TIME_WAIT
+	// sockets for outgoing connections, 2 allows this only for loopback.
+	TwReuse int
+
+	// RmemMax is net.core.rmem_max, the limit of SO_RCVBUF in bytes.
+	RmemMax int
+
+	// WmemMax is net.core.wmem_max, the limit of SO_SNDBUF in bytes.
+	WmemMax int
+}
+
+// FastOpenServerEnabled reports whether net.ipv4.tcp_fastopen enables
+// the server side of TCP Fast Open.
+func (r *TuningReport) FastOpenServerEnabled() bool {
+	return r.FastOpen > 0 && r.FastOpen&2 != 0
+}
@@ -0,0 +1,55 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SystemTuning returns the kernel settings limiting listeners, which
+// may explain why an option has been limited without an error.
+//
+// The sysctls are read from /proc/sys of the current network namespace.
+// Some of them may be unavailable in containers, so all the readable ones
+// are returned along with the error for the first unreadable one.
+func SystemTuning() (TuningReport, error) {
+	var firstErr error
+	read := func(name string) int {
+		n, err := readSysctlInt(name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return -1
+		}
+		return n
+	}
+	r := TuningReport{
+		SoMaxConn:     read("net/core/somaxconn"),
+		FastOpen:      read("net/ipv4/tcp_fastopen"),
+		MaxSynBacklog: read("net/ipv4/tcp_max_syn_backlog"),
+		TwReuse:       read("net/ipv4/tcp_tw_reuse"),
+		RmemMax:       read("net/core/rmem_max"),
+		WmemMax:       read("net/core/wmem_max"),
+	}
+	return r, firstErr
+}
+
+// readSysctlInt reads the integer sysctl with the given path
+// under /proc/sys.
+func readSysctlInt(name string) (int, error) {
+	var buf [32]byte
+	path := "/proc/sys/" + name
+	size, err := readSmallFile(path, buf[:])
+	if err != nil {
+		return 0, fmt.Errorf("cannot read %s: %s", strings.Replace(name, "/", ".", -1), err)
+	}
+	s := strings.TrimSpace(string(buf[:size]))
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q read from %s: %s", s, path, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,26 @@
+package tcplisten
+
+import (
+	"testing"
+)
+
+func TestSystemTuning(t *testing.T) {
+	r, err := SystemTuning()
+	if err != nil {
+		t.Fatalf("cannot read system tuning: %s", err)
+	}
+	if r.SoMaxConn <= 0 || r.MaxSynBacklog <= 0 || r.RmemMax <= 0 || r.WmemMax <= 0 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.FastOpenServerEnabled() != tfoServerEnabled() {
+		t.Fatalf("unexpected FastOpenServerEnabled()=%v for tcp_fastopen=%d", r.FastOpenServerEnabled(), r.FastOpen)
+	}
+
+	n, err := soMaxConn()
+	if err != nil {
+		t.Fatalf("cannot read somaxconn: %s", err)
+	}
+	if n != r.SoMaxConn && r.SoMaxConn <= 1<<16-1 {
+		t.Fatalf("unexpected somaxconn %d. Expecting %d", r.SoMaxConn, n)
+	}
+}
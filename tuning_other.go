@@ -0,0 +1,13 @@
+// +build !linux
+
+package tcplisten
+
+// SystemTuning returns the kernel settings limiting listeners.
+//
+// It is supported only on Linux.
+func SystemTuning() (TuningReport, error) {
+	return TuningReport{}, &UnsupportedError{
+		Op:     "SystemTuning",
+		Reason: "the settings are read from Linux procfs",
+	}
+}
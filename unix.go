@@ -0,0 +1,173 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// listenSockaddr returns the socket address and the family for listening
+// on addr. Unlike getSockaddr, it accepts the unix network too.
+func listenSockaddr(ctx context.Context, network, addr string) (syscall.Sockaddr, int, error) {
+	if network != "unix" {
+		return getSockaddr(ctx, network, addr)
+	}
+	if addr == "" {
+		return nil, -1, errors.New("cannot listen on unix socket: the path is empty")
+	}
+	if addr[0] == '@' && runtime.GOOS != "linux" {
+		return nil, -1, fmt.Errorf("cannot listen on unix socket %q: abstract names are supported only on Linux", addr)
+	}
+	// syscall.SockaddrUnix turns the leading @ into the zero byte
+	// of the abstract namespace.
+	return &syscall.SockaddrUnix{Name: addr}, syscall.AF_UNIX, nil
+}
+
+// socketPath returns the path of the file created by binding to sa,
+// or an empty string if sa isn't a unix socket address or if it is
+// in the abstract namespace.
+func socketPath(sa syscall.Sockaddr) string {
+	sau, ok := sa.(*syscall.SockaddrUnix)
+	if !ok || sau.Name == "" || sau.Name[0] == '@' {
+		return ""
+	}
+	return sau.Name
+}
+
+// checkUnix returns an error if a TCP option is set in cfg.
+func (cfg *Config) checkUnix(addr string) error {
+	var opt string
+	switch {
+	case cfg.ReusePort:
+		opt = "ReusePort"
+	case cfg.ReusePortLB:
+		opt = "ReusePortLB"
+	case cfg.DeferAccept:
+		opt = "DeferAccept"
+	case cfg.FastOpen:
+		opt = "FastOpen"
+	case cfg.NoDelay:
+		opt = "NoDelay"
+	case cfg.QuickACK:
+		opt = "QuickACK"
+	case cfg.V6Only != V6OnlyDefault:
+		opt = "V6Only"
+	case cfg.FlowLabel != FlowLabelDefault:
+		opt = "FlowLabel"
+	case cfg.ServiceClass != ServiceClassDefault:
+		opt = "ServiceClass"
+	case cfg.Transparent:
+		opt = "Transparent"
+	case cfg.KeepAlive:
+		opt = "KeepAlive"
+	case cfg.KeepAliveConfig != nil:
+		opt = "KeepAliveConfig"
+	case cfg.DisableRecvAutotune:
+		opt = "DisableRecvAutotune"
+	case cfg.InitialRTO > 0:
+		opt = "InitialRTO"
+	case cfg.MaxPacingRate > 0:
+		opt = "MaxPacingRate"
+	case cfg.HardwareTimestamping:
+		opt = "HardwareTimestamping"
+	default:
+		return nil
+	}
+	return fmt.Errorf("cannot enable %s on unix socket %q: it is supported only for TCP", opt, addr)
+}
+
+// staleSocketDialTimeout is the timeout of dialing a socket file
+// for checking whether a listener still serves it.
+const staleSocketDialTimeout = 100 * time.Millisecond
+
+// unlinkStaleSocket removes the socket file at path left by a previous
+// listener, e.g. by a crashed process, and reports whether it has been
+// removed.
+//
+// The file is stale only if dialing it fails with ECONNREFUSED, so
+// the socket of a live listener is kept. The file is stat-ed again
+// before unlinking and kept if it has been replaced, so the socket bound
+// by another process meanwhile isn't removed. Files other than sockets
+// are kept too.
+func unlinkStaleSocket(path string, tr tracer) (bool, error) {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot remove stale unix socket %q: %s", path, err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return false, fmt.Errorf("cannot remove stale unix socket %q: it isn't a socket", path)
+	}
+	c, err := net.DialTimeout("unix", path, staleSocketDialTimeout)
+	if err == nil {
+		c.Close()
+		return false, nil
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		// The listener may be alive, but too busy to accept
+		// the connection in time.
+		return false, nil
+	}
+	if cur, err := os.Lstat(path); err != nil || !os.SameFile(fi, cur) {
+		return false, nil
+	}
+	err = syscall.Unlink(path)
+	tr.trace(TraceRecord{Call: "unlink", Addr: path, Err: err})
+	if err == syscall.ENOENT {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot remove stale unix socket %q: %s", path, err)
+	}
+	return true, nil
+}
+
+// setSocketFileMode applies UnixMode and UnixOwner to the socket file
+// at path.
+func (cfg *Config) setSocketFileMode(path string, tr tracer) error {
+	if cfg.UnixMode != 0 {
+		mode := uint32(cfg.UnixMode.Perm())
+		err := syscall.Chmod(path, mode)
+		tr.trace(TraceRecord{Call: "chmod", Addr: path, Value: int(mode), Err: err})
+		if err != nil {
+			return fmt.Errorf("cannot set mode %s on unix socket %q: %s", cfg.UnixMode, path, err)
+		}
+	}
+	if o := cfg.UnixOwner; o != nil {
+		err := syscall.Chown(path, o.UID, o.GID)
+		tr.trace(TraceRecord{Call: "chown", Addr: path, Err: err})
+		if err == syscall.EPERM {
+			return fmt.Errorf("cannot change owner of unix socket %q to %d:%d: %s; the process must own the file and belong to the group, or have root privileges or CAP_CHOWN", path, o.UID, o.GID, err)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot change owner of unix socket %q to %d:%d: %s", path, o.UID, o.GID, err)
+		}
+	}
+	return nil
+}
+
+// removeSocketFile removes the socket file created by binding to sa
+// if the listener cannot be created.
+func removeSocketFile(sa syscall.Sockaddr) {
+	if path := socketPath(sa); path != "" {
+		syscall.Unlink(path)
+	}
+}
+
+// socketProto returns the protocol of the listening socket
+// of the family.
+func socketProto(family int) int {
+	if family == syscall.AF_UNIX {
+		return 0
+	}
+	return syscall.IPPROTO_TCP
+}
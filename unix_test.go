@@ -0,0 +1,196 @@
+// +build !windows,!plan9
+
+package tcplisten
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnixListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	ln, err := NewListener("unix", path, Config{Backlog: 16})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	testUnixRoundTrip(t, ln)
+	ln.Close()
+	if _, err = os.Lstat(path); !os.IsNotExist(err) {
+		t.Fatalf("unexpected error %v. Expecting the socket file to be removed on Close", err)
+	}
+
+	if _, err = NewListener("unix", path, Config{DeferAccept: true}); err == nil || !strings.Contains(err.Error(), "DeferAccept") {
+		t.Fatalf("unexpected error %v. Expecting error about DeferAccept", err)
+	}
+	if _, err = os.Lstat(path); !os.IsNotExist(err) {
+		t.Fatalf("unexpected error %v. Expecting no socket file", err)
+	}
+}
+
+func TestUnixListenerAbstract(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract unix sockets exist only on Linux")
+	}
+	ln, err := NewListener("unix", "@tcplisten-test-"+strconv.Itoa(os.Getpid()), Config{})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	defer ln.Close()
+	testUnixRoundTrip(t, ln)
+}
+
+func TestConfigUnlinkBeforeBind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	if _, err = NewListener("unix", path, Config{}); err == nil || !strings.Contains(err.Error(), "UnlinkBeforeBind") {
+		t.Fatalf("unexpected error %v. Expecting error suggesting UnlinkBeforeBind", err)
+	}
+	ln, err := NewListener("unix", path, Config{UnlinkBeforeBind: true})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	testUnixRoundTrip(t, ln)
+
+	// The socket of a live listener must be kept.
+	if _, err = NewListener("unix", path, Config{UnlinkBeforeBind: true}); err == nil {
+		t.Fatalf("expecting error when the socket is served by a live listener")
+	}
+	// Skip the connection dialed for the check.
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	c.Close()
+	testUnixRoundTrip(t, ln)
+	ln.Close()
+
+	// Regular files must be kept.
+	if err = ioutil.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatalf("cannot create file: %s", err)
+	}
+	if _, err = NewListener("unix", path, Config{UnlinkBeforeBind: true}); err == nil {
+		t.Fatalf("expecting error when the path is a regular file")
+	}
+	if _, err = os.Stat(path); err != nil {
+		t.Fatalf("the regular file has been removed: %s", err)
+	}
+}
+
+func testUnixRoundTrip(t *testing.T, ln net.Listener) {
+	c, err := net.Dial("unix", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot dial %s: %s", ln.Addr(), err)
+	}
+	defer c.Close()
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	defer sc.Close()
+
+	if _, err = c.Write([]byte("ping")); err != nil {
+		t.Fatalf("cannot write: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err = sc.Read(buf); err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("unexpected data %q. Expecting %q", buf, "ping")
+	}
+}
+
+func TestConfigUnlinkStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	cfg := Config{UnlinkStale: true}
+
+	// The socket of a dead listener is removed.
+	dead, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	dead.SetUnlinkOnClose(false)
+	dead.Close()
+
+	var unlinked []string
+	cfg.Trace = func(r TraceRecord) {
+		if r.Call == "unlink" && r.Err == nil {
+			unlinked = append(unlinked, r.Addr)
+		}
+	}
+	ln, err := NewListener("unix", path, cfg)
+	if err != nil {
+		t.Fatalf("cannot create listener over a dead socket: %s", err)
+	}
+	if len(unlinked) != 1 || unlinked[0] != path {
+		t.Fatalf("unexpected unlinked files %q. Expecting %q", unlinked, path)
+	}
+	testUnixRoundTrip(t, ln)
+
+	// The socket of a live listener is kept.
+	unlinked = nil
+	if _, err = NewListener("unix", path, cfg); err == nil || !strings.Contains(err.Error(), "live listener") {
+		t.Fatalf("unexpected error %v. Expecting error about a live listener", err)
+	}
+	if len(unlinked) != 0 {
+		t.Fatalf("the socket of a live listener has been unlinked")
+	}
+	// Skip the connection dialed for the check.
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("cannot accept connection: %s", err)
+	}
+	c.Close()
+	testUnixRoundTrip(t, ln)
+	ln.Close()
+}
+
+func TestConfigUnixMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	ln, err := NewListener("unix", path, Config{
+		UnixMode:  0640,
+		UnixOwner: &UnixOwner{UID: -1, GID: os.Getgid()},
+	})
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatalf("cannot stat socket file: %s", err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Fatalf("unexpected mode %s. Expecting %s", fi.Mode().Perm(), os.FileMode(0640))
+	}
+	testUnixRoundTrip(t, ln)
+	ln.Close()
+
+	if _, err = NewListener("unix", path, Config{UnixMode: os.ModeSetuid | 0600}); err == nil {
+		t.Fatalf("expecting error for UnixMode with non-permission bits")
+	}
+}
+
+func TestConfigUnixOwnerFailure(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root may change the owner to any user")
+	}
+	path := filepath.Join(t.TempDir(), "test.sock")
+	_, err := NewListener("unix", path, Config{UnixOwner: &UnixOwner{UID: 0, GID: -1}})
+	if err == nil || !strings.Contains(err.Error(), "cannot change owner") {
+		t.Fatalf("unexpected error %v. Expecting error about changing the owner", err)
+	}
+	if _, err = os.Lstat(path); !os.IsNotExist(err) {
+		t.Fatalf("the socket file must be removed after failure: %v", err)
+	}
+}
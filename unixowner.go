@@ -0,0 +1,10 @@
+package tcplisten
+
+// UnixOwner is the owner of the socket file of a unix listener.
+type UnixOwner struct {
+	// UID is the user ID of the owner. It is left unchanged if -1.
+	UID int
+
+	// GID is the group ID of the owner. It is left unchanged if -1.
+	GID int
+}
@@ -0,0 +1,48 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestConfigUnmapV4(t *testing.T) {
+	for _, unmap := range []bool{false, true} {
+		ln, err := NewListener("tcp6", "[::]:0", Config{UnmapV4: unmap, V6Only: V6OnlyDisabled})
+		if err != nil {
+			t.Skipf("cannot create dual-stack listener: %s", err)
+		}
+		port := ln.Addr().(*net.TCPAddr).Port
+		c, err := net.Dial("tcp4", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			ln.Close()
+			t.Fatalf("cannot dial: %s", err)
+		}
+		sc, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("cannot accept: %s", err)
+		}
+		ip := sc.RemoteAddr().(*net.TCPAddr).IP
+		if unmap {
+			// Every call must return a copy, which may be modified
+			// by the caller.
+			sc.RemoteAddr().(*net.TCPAddr).Port = 0
+			if sc.RemoteAddr().(*net.TCPAddr).Port == 0 {
+				t.Fatalf("RemoteAddr returned a shared address")
+			}
+		}
+		sc.Close()
+		c.Close()
+		ln.Close()
+
+		expected := net.IPv6len
+		if unmap {
+			expected = net.IPv4len
+		}
+		if len(ip) != expected || !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Fatalf("unexpected remote IP %v of length %d with UnmapV4=%v. Expecting 127.0.0.1 of length %d", ip, len(ip), unmap, expected)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package tcplisten
+
+// V6OnlyMode controls IPV6_V6ONLY on tcp6 listeners.
+type V6OnlyMode int
+
+const (
+	// V6OnlyDefault leaves IPV6_V6ONLY at the platform default.
+	//
+	// The default differs between platforms: Linux accepts IPv4
+	// connections on [::] listeners unless net.ipv6.bindv6only is set,
+	// while Windows and most BSDs don't.
+	V6OnlyDefault V6OnlyMode = iota
+
+	// V6OnlyEnabled restricts the listener to IPv6 connections.
+	V6OnlyEnabled
+
+	// V6OnlyDisabled makes [::] listeners accept IPv4 connections too,
+	// with IPv4-mapped remote addresses.
+	V6OnlyDisabled
+)
+
+// sockoptValue returns the IPV6_V6ONLY value for the mode and false
+// if the option must be left intact.
+func (m V6OnlyMode) sockoptValue() (int, bool) {
+	switch m {
+	case V6OnlyEnabled:
+		return 1, true
+	case V6OnlyDisabled:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,41 @@
+// +build !plan9
+
+package tcplisten
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestConfigV6Only(t *testing.T) {
+	ln, err := NewListener("tcp6", "[::]:0", Config{V6Only: V6OnlyDisabled})
+	if err != nil {
+		t.Skipf("IPv6 is unavailable: %s", err)
+	}
+	defer ln.Close()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			c.Close()
+		}
+	}()
+	c, err := net.Dial("tcp4", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatalf("dual-stack listener must accept IPv4 clients: %s", err)
+	}
+	c.Close()
+
+	ln6, err := NewListener("tcp6", "[::]:0", Config{V6Only: V6OnlyEnabled})
+	if err != nil {
+		t.Fatalf("cannot create IPv6-only listener: %s", err)
+	}
+	defer ln6.Close()
+	port = strconv.Itoa(ln6.Addr().(*net.TCPAddr).Port)
+	if c, err = net.Dial("tcp4", "127.0.0.1:"+port); err == nil {
+		c.Close()
+		t.Fatalf("IPv6-only listener mustn't accept IPv4 clients")
+	}
+}
@@ -0,0 +1,30 @@
+package tcplisten
+
+import (
+	"fmt"
+)
+
+// VsockCIDAny and VsockPortAny bind vsock listeners to any CID
+// and to an ephemeral port respectively.
+const (
+	VsockCIDAny  = 0xFFFFFFFF
+	VsockPortAny = 0xFFFFFFFF
+)
+
+// VsockAddr is the address of a VM socket (AF_VSOCK).
+type VsockAddr struct {
+	// CID is the context ID of the VM or the host.
+	CID uint32
+
+	// Port is the vsock port.
+	Port uint32
+}
+
+// Network returns "vsock".
+func (a *VsockAddr) Network() string {
+	return "vsock"
+}
+
+func (a *VsockAddr) String() string {
+	return fmt.Sprintf("vm(%d):%d", a.CID, a.Port)
+}
@@ -0,0 +1,155 @@
+// +build linux
+
+package tcplisten
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const afVsock = 40
+
+// sockaddrVM is struct sockaddr_vm.
+type sockaddrVM struct {
+	family    uint16
+	reserved1 uint16
+	port      uint32
+	cid       uint32
+	flags     uint8
+	zero      [3]uint8
+}
+
+// NewVsockListener returns a listener for VM sockets (AF_VSOCK)
+// bound to the given context ID and port.
+//
+// Config.Backlog, Config.PostListen and Config.Trace are honored.
+// TCP-specific options are skipped.
+func NewVsockListener(cid, port uint32, cfg Config) (net.Listener, error) {
+	fd, err := newSocketCloexec(afVsock, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, err
+	}
+	laddr, err := cfg.vsockSetup(fd, cid, port)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &vsockListener{
+		f:    os.NewFile(uintptr(fd), fmt.Sprintf("tcplisten-vsock.%s", laddr)),
+		addr: laddr,
+	}, nil
+}
+
+func (cfg *Config) vsockSetup(fd int, cid, port uint32) (*VsockAddr, error) {
+	tr := tracer(cfg.Trace)
+	addr := &VsockAddr{CID: cid, Port: port}
+
+	sa := sockaddrVM{
+		family: afVsock,
+		port:   port,
+		cid:    cid,
+	}
+	err := bind(fd, unsafe.Pointer(&sa), uint32(unsafe.Sizeof(sa)))
+	tr.trace(TraceRecord{Call: "bind", Addr: addr.String(), Err: err})
+	if err != nil {
+		return nil, fmt.Errorf("cannot bind to %s: %s", addr, err)
+	}
+
+	backlog := cfg.Backlog
+	if backlog <= 0 {
+		if backlog, err = soMaxConn(); err != nil {
+			return nil, fmt.Errorf("cannot determine backlog to pass to listen(2): %s", err)
+		}
+	}
+	err = syscall.Listen(fd, backlog)
+	tr.trace(TraceRecord{Call: "listen", Value: backlog, Err: err})
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %s", addr, err)
+	}
+
+	l := uint32(unsafe.Sizeof(sa))
+	if err = getsockname(fd, unsafe.Pointer(&sa), &l); err != nil {
+		return nil, fmt.Errorf("cannot obtain the address of %s: %s", addr, err)
+	}
+	addr = &VsockAddr{CID: sa.cid, Port: sa.port}
+
+	if cfg.PostListen != nil {
+		if err = cfg.PostListen(uintptr(fd)); err != nil {
+			return nil, fmt.Errorf("cannot run PostListen hook on %s: %w", addr, err)
+		}
+	}
+	return addr, nil
+}
+
+// vsockListener accepts VM socket connections.
+//
+// The standard library doesn't know AF_VSOCK, so the listener and
+// the connections are built on top of os.File, which still uses
+// the runtime network poller for non-blocking descriptors.
+type vsockListener struct {
+	f    *os.File
+	addr *VsockAddr
+}
+
+func (ln *vsockListener) Accept() (net.Conn, error) {
+	rc, err := ln.f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		nfd  int
+		sa   sockaddrVM
+		aerr error
+	)
+	err = rc.Read(func(fd uintptr) bool {
+		for {
+			l := uint32(unsafe.Sizeof(sa))
+			nfd, aerr = accept4(int(fd), unsafe.Pointer(&sa), &l, syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC)
+			if aerr != syscall.EINTR {
+				return aerr != syscall.EAGAIN
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aerr != nil {
+		return nil, &net.OpError{Op: "accept", Net: "vsock", Addr: ln.addr, Err: os.NewSyscallError("accept4", aerr)}
+	}
+	remote := &VsockAddr{CID: sa.cid, Port: sa.port}
+	return &vsockConn{
+		File:   os.NewFile(uintptr(nfd), fmt.Sprintf("tcplisten-vsock.%s-%s", ln.addr, remote)),
+		local:  ln.addr,
+		remote: remote,
+	}, nil
+}
+
+func (ln *vsockListener) Close() error {
+	return ln.f.Close()
+}
+
+func (ln *vsockListener) Addr() net.Addr {
+	return ln.addr
+}
+
+func (ln *vsockListener) SyscallConn() (syscall.RawConn, error) {
+	return ln.f.SyscallConn()
+}
+
+// vsockConn is an accepted VM socket connection.
+type vsockConn struct {
+	*os.File
+	local  *VsockAddr
+	remote *VsockAddr
+}
+
+func (c *vsockConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *vsockConn) RemoteAddr() net.Addr {
+	return c.remote
+}
@@ -0,0 +1,35 @@
+package tcplisten
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewVsockListener(t *testing.T) {
+	ln, err := NewVsockListener(VsockCIDAny, VsockPortAny, Config{Backlog: 16})
+	if err != nil {
+		t.Skipf("AF_VSOCK is unavailable: %s", err)
+	}
+	addr, ok := ln.Addr().(*VsockAddr)
+	if !ok || addr.Port == VsockPortAny {
+		t.Fatalf("unexpected listener address %v", ln.Addr())
+	}
+
+	ch := make(chan error, 1)
+	go func() {
+		_, err := ln.Accept()
+		ch <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err = ln.Close(); err != nil {
+		t.Fatalf("cannot close listener: %s", err)
+	}
+	select {
+	case err = <-ch:
+		if err == nil {
+			t.Fatalf("expecting error from Accept on closed listener")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Accept isn't unblocked by Close")
+	}
+}
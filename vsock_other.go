@@ -0,0 +1,17 @@
+// +build !linux
+
+package tcplisten
+
+import (
+	"net"
+)
+
+// NewVsockListener returns a listener for VM sockets (AF_VSOCK).
+//
+// VM sockets are supported only on Linux.
+func NewVsockListener(cid, port uint32, cfg Config) (net.Listener, error) {
+	return nil, &UnsupportedError{
+		Op:     "NewVsockListener",
+		Reason: "AF_VSOCK is supported only on Linux",
+	}
+}
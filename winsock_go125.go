@@ -0,0 +1,7 @@
+// +build windows,go1.25
+
+package tcplisten
+
+// fileListenerSupported is set if net.FileListener turns a Winsock socket
+// into net.Listener.
+const fileListenerSupported = true